@@ -445,3 +445,23 @@ func (ca *RemoteChainOpImpl) TxReceipt(hash string) *RPCResObjCmd {
 func (ca *RemoteChainOpImpl) GroupCheck(addr string) *RPCResObjCmd {
 	return ca.request("groupCheck", addr)
 }
+
+func (ca *RemoteChainOpImpl) GroupForecast() *RPCResObjCmd {
+	return ca.request("groupForecast")
+}
+
+func (ca *RemoteChainOpImpl) ContractABI(addr string) *RPCResObjCmd {
+	return ca.request("contractABI", addr)
+}
+
+func (ca *RemoteChainOpImpl) DecodeLogs(hash string) *RPCResObjCmd {
+	return ca.request("decodeLogs", hash)
+}
+
+// ContractStorage queries the contract data, at the latest state if the height is 0
+func (ca *RemoteChainOpImpl) ContractStorage(addr string, prefix string, startKey string, limit int, height uint64) *RPCResObjCmd {
+	if height == 0 {
+		return ca.request("contractStorage", addr, prefix, startKey, limit)
+	}
+	return ca.request("contractStorage", addr, prefix, startKey, limit, height)
+}
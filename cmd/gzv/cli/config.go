@@ -33,4 +33,5 @@ type minerConfig struct {
 	resetHash         string
 	cors              string
 	privateKey        string
+	metricsAddr       string // Address serving the prometheus metrics, disabled if empty
 }
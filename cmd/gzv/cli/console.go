@@ -404,6 +404,14 @@ func genReceiptCmd() *receiptCmd {
 	return c
 }
 
+func genDecodeLogsCmd() *receiptCmd {
+	c := &receiptCmd{
+		baseCmd: *genBaseCmd("decodelogs", "decode the logs of the transaction by the contract abi"),
+	}
+	c.fs.StringVar(&c.hash, "hash", "", "the hex transaction hash")
+	return c
+}
+
 func (c *receiptCmd) parse(args []string) bool {
 	if err := c.fs.Parse(args); err != nil {
 		output(err.Error())
@@ -823,6 +831,14 @@ func genViewContractCmd() *viewContractCmd {
 	return c
 }
 
+func genContractABICmd() *viewContractCmd {
+	c := &viewContractCmd{
+		baseCmd: *genBaseCmd("contractabi", "view the abi of the contract"),
+	}
+	c.fs.StringVar(&c.addr, "addr", "", "address of the contract")
+	return c
+}
+
 func (c *viewContractCmd) parse(args []string) bool {
 	if err := c.fs.Parse(args); err != nil {
 		output(err.Error())
@@ -839,6 +855,26 @@ func (c *viewContractCmd) parse(args []string) bool {
 	return true
 }
 
+type contractStorageCmd struct {
+	viewContractCmd
+	prefix   string
+	startKey string
+	limit    int
+	height   uint64
+}
+
+func genContractStorageCmd() *contractStorageCmd {
+	c := &contractStorageCmd{
+		viewContractCmd: viewContractCmd{baseCmd: *genBaseCmd("contractstorage", "scan the contract data by the key prefix")},
+	}
+	c.fs.StringVar(&c.addr, "addr", "", "address of the contract")
+	c.fs.StringVar(&c.prefix, "prefix", "", "prefix of the keys")
+	c.fs.StringVar(&c.startKey, "start", "", "key to start from, the nextKey of the previous page")
+	c.fs.IntVar(&c.limit, "limit", 20, "max number of the items returned")
+	c.fs.Uint64Var(&c.height, "height", 0, "the block height, latest if not specified")
+	return c
+}
+
 type importKeyCmd struct {
 	baseCmd
 	key      string
@@ -958,6 +994,7 @@ var cmdBlockHeight = genBaseCmd("blockheight", "the current block height")
 var cmdGroupHeight = genBaseCmd("groupheight", "the current group height")
 var cmdTx = genTxCmd()
 var cmdReceipt = genReceiptCmd()
+var cmdDecodeLogs = genDecodeLogsCmd()
 var cmdBlock = genBlockCmd()
 var cmdSendTx = genSendTxCmd()
 var cmdApplyGuardMiner = genApplyGuardMinerCmd()
@@ -969,10 +1006,13 @@ var cmdChangeGuardNode = genChangeGuardNodeCmd()
 var cmdStakeRefund = genStakeRefundCmd()
 var cmdStakeReduce = genStakeReduceCmd()
 var cmdViewContract = genViewContractCmd()
+var cmdContractABI = genContractABICmd()
+var cmdContractStorage = genContractStorageCmd()
 
 var cmdImportKey = genImportKeyCmd()
 var cmdExportKey = genExportKeyCmd()
 var cmdGroupCheck = genGroupCheckCmd()
+var cmdGroupForecast = genBaseCmd("groupforecast", "forecast whether the group creation of the current era will succeed")
 
 var list = make([]*baseCmd, 0)
 
@@ -995,6 +1035,7 @@ func init() {
 	list = append(list, cmdGroupHeight)
 	list = append(list, &cmdTx.baseCmd)
 	list = append(list, &cmdReceipt.baseCmd)
+	list = append(list, &cmdDecodeLogs.baseCmd)
 	list = append(list, &cmdBlock.baseCmd)
 	list = append(list, &cmdSendTx.baseCmd)
 	list = append(list, &cmdStakeAdd.baseCmd)
@@ -1002,10 +1043,13 @@ func init() {
 	list = append(list, &cmdChangeGuardNode.baseCmd)
 	list = append(list, &cmdStakeRefund.baseCmd)
 	list = append(list, &cmdViewContract.baseCmd)
+	list = append(list, &cmdContractABI.baseCmd)
+	list = append(list, &cmdContractStorage.baseCmd)
 	list = append(list, &cmdStakeReduce.baseCmd)
 	list = append(list, &cmdImportKey.baseCmd)
 	list = append(list, &cmdExportKey.baseCmd)
 	list = append(list, &cmdGroupCheck.baseCmd)
+	list = append(list, cmdGroupForecast)
 	list = append(list, cmdExit)
 }
 
@@ -1264,6 +1308,13 @@ func loop(acm accountOp, chainOp chainOp) {
 					return chainOp.TxReceipt(cmd.hash)
 				})
 			}
+		case cmdDecodeLogs.name:
+			cmd := genDecodeLogsCmd()
+			if cmd.parse(args) {
+				handleCmdForChain(func() *RPCResObjCmd {
+					return chainOp.DecodeLogs(cmd.hash)
+				})
+			}
 		case cmdBlock.name:
 			cmd := genBlockCmd()
 			if cmd.parse(args) {
@@ -1323,6 +1374,20 @@ func loop(acm accountOp, chainOp chainOp) {
 					return chainOp.ViewContract(cmd.addr)
 				})
 			}
+		case cmdContractABI.name:
+			cmd := genContractABICmd()
+			if cmd.parse(args) {
+				handleCmdForChain(func() *RPCResObjCmd {
+					return chainOp.ContractABI(cmd.addr)
+				})
+			}
+		case cmdContractStorage.name:
+			cmd := genContractStorageCmd()
+			if cmd.parse(args) {
+				handleCmdForChain(func() *RPCResObjCmd {
+					return chainOp.ContractStorage(cmd.addr, cmd.prefix, cmd.startKey, cmd.limit, cmd.height)
+				})
+			}
 		case cmdImportKey.name:
 			cmd := genImportKeyCmd()
 			if cmd.parse(args) {
@@ -1344,6 +1409,10 @@ func loop(acm accountOp, chainOp chainOp) {
 					return chainOp.GroupCheck(cmd.addr)
 				})
 			}
+		case cmdGroupForecast.name:
+			handleCmdForChain(func() *RPCResObjCmd {
+				return chainOp.GroupForecast()
+			})
 		default:
 			fmt.Printf("not supported command %v\n", cmdStr)
 			Usage()
@@ -0,0 +1,47 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/darren0718/zvchain/consensus/group"
+)
+
+// simulateGroupSelection loads the miner set from the given json file and prints the selection distribution
+// of the given algorithm
+func simulateGroupSelection(file string, algorithm string, num int, rounds int) error {
+	bs, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("read miner file error:%v", err)
+	}
+	miners := make([]*group.SimulateMiner, 0)
+	if err := json.Unmarshal(bs, &miners); err != nil {
+		return fmt.Errorf("parse miner file error:%v", err)
+	}
+	stats, err := group.SimulateSelection(algorithm, miners, num, rounds)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("algorithm=%v, miners=%v, groupSize=%v, rounds=%v\n", algorithm, len(miners), num, rounds)
+	fmt.Printf("%-68v %-20v %-12v %-12v\n", "address", "stake", "stakeRate", "selectRate")
+	for _, st := range stats {
+		fmt.Printf("%-68v %-20v %-12.6f %-12.6f\n", st.Address, st.Stake, st.StakeRate, st.SelectedRate)
+	}
+	return nil
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/darren0718/zvchain/cmd/gzv/cli/update"
 	"github.com/darren0718/zvchain/log"
 	"github.com/darren0718/zvchain/middleware"
+	"github.com/darren0718/zvchain/middleware/metrics"
 	"github.com/darren0718/zvchain/params"
 	"github.com/sirupsen/logrus"
 	"os"
@@ -47,6 +48,7 @@ import (
 	time2 "github.com/darren0718/zvchain/middleware/time"
 	"github.com/darren0718/zvchain/middleware/types"
 	"github.com/darren0718/zvchain/monitor"
+	"github.com/darren0718/zvchain/tvm"
 )
 
 const (
@@ -54,6 +56,11 @@ const (
 	Section = "gzv"
 )
 
+func init() {
+	// Contracts of the chain run on the TVM
+	core.SetContractEngine(tvm.NewEngine)
+}
+
 type Gzv struct {
 	inited       bool
 	account      Account
@@ -77,6 +84,10 @@ func (gzv *Gzv) miner(cfg *minerConfig) error {
 	if err != nil {
 		return err
 	}
+	err = gzv.startMetrics()
+	if err != nil {
+		return err
+	}
 	ok := mediator.StartMiner()
 
 	fmt.Println("Syncing block and group info from ZV net.Waiting...")
@@ -103,6 +114,22 @@ func (gzv *Gzv) miner(cfg *minerConfig) error {
 	return nil
 }
 
+// startMetrics serves the prometheus metrics if the address set by the flag or the config
+func (gzv *Gzv) startMetrics() error {
+	addr := gzv.config.metricsAddr
+	if addr == "" {
+		addr = common.GlobalConf.GetString(Section, "metrics_addr", "")
+	}
+	if addr == "" {
+		return nil
+	}
+	if err := metrics.Start(addr); err != nil {
+		return fmt.Errorf("start metrics at %v error:%v", addr, err)
+	}
+	log.DefaultLogger.Infof("metrics served at http://%v%v", addr, metrics.Path)
+	return nil
+}
+
 func (gzv *Gzv) runtimeInit() {
 	debug.SetGCPercent(50)
 	debug.SetMaxStack(2 * 1000000000)
@@ -158,6 +185,7 @@ func (gzv *Gzv) Run() {
 	servicePort := mineCmd.Flag("port", "miner report or rpc service port").Short('p').Default("8101").Uint16()
 
 	enableMonitor := mineCmd.Flag("monitor", "enable monitor").Default("false").Bool()
+	metricsAddr := mineCmd.Flag("metrics", "serve the prometheus metrics at the address, e.g. 127.0.0.1:8102, disabled if empty").Default("").String()
 	disableReport := mineCmd.Flag("disablereport", "disable report.").Default("false").Bool()
 	disableNotice := mineCmd.Flag("disableNotice", "disable version upgrade notifications .").Default("false").Bool()
 
@@ -185,6 +213,13 @@ func (gzv *Gzv) Run() {
 
 	clearCmd := app.Command("clear", "Clear the data of blockchain")
 
+	// Group candidate selection simulation
+	groupSimCmd := app.Command("groupsim", "simulate the group candidate selection and report the distribution")
+	groupSimMiners := groupSimCmd.Flag("miners", "json file of the miner set, each with address, stake and drop_rate").Required().String()
+	groupSimAlgo := groupSimCmd.Flag("algo", "selecting algorithm: fts, capped or reputation").Default("fts").String()
+	groupSimNum := groupSimCmd.Flag("num", "number of candidates selected each round").Default("20").Int()
+	groupSimRounds := groupSimCmd.Flag("rounds", "number of simulated rounds").Default("10000").Int()
+
 	command, err := app.Parse(os.Args[1:])
 	if err != nil {
 		kingpin.Fatalf("%s, try --help", err)
@@ -235,6 +270,7 @@ func (gzv *Gzv) Run() {
 			resetHash:         *reset,
 			cors:              *cors,
 			privateKey:        *privKey,
+			metricsAddr:       *metricsAddr,
 		}
 		// Start miner
 		err := gzv.miner(cfg)
@@ -264,6 +300,12 @@ func (gzv *Gzv) Run() {
 		} else {
 			fmt.Println("clear blockchain successfully")
 		}
+	case groupSimCmd.FullCommand():
+		err := simulateGroupSelection(*groupSimMiners, *groupSimAlgo, *groupSimNum, *groupSimRounds)
+		if err != nil {
+			fmt.Println(err.Error())
+		}
+		os.Exit(0)
 	}
 	<-quitChan
 }
@@ -368,17 +410,19 @@ func (gzv *Gzv) fullInit() error {
 	}
 
 	netCfg := network.NetworkConfig{
-		IsSuper:         cfg.super,
-		TestMode:        cfg.testMode,
-		NatAddr:         cfg.natIP,
-		NatPort:         cfg.natPort,
-		SeedAddr:        cfg.seedIP,
-		NodeIDHex:       id,
-		ChainID:         cfg.chainID,
-		ProtocolVersion: common.ProtocolVersion,
-		SeedIDs:         genesisMembers,
-		PK:              gzv.account.Pk,
-		SK:              gzv.account.Sk,
+		IsSuper:            cfg.super,
+		TestMode:           cfg.testMode,
+		NatAddr:            cfg.natIP,
+		NatPort:            cfg.natPort,
+		SeedAddr:           cfg.seedIP,
+		NodeIDHex:          id,
+		ChainID:            cfg.chainID,
+		ProtocolVersion:    common.ProtocolVersion,
+		MinProtocolVersion: common.MinProtocolVersion,
+		Capabilities:       network.CapHeaderSync,
+		SeedIDs:            genesisMembers,
+		PK:                 gzv.account.Pk,
+		SK:                 gzv.account.Sk,
 	}
 
 	err = network.Init(&common.GlobalConf, chandler.MessageHandler, netCfg)
@@ -62,6 +62,7 @@ func (gzv *Gzv) initRpcInstances() error {
 	}
 	if level >= rpcLevelDev {
 		gzv.addInstance(&RpcDevImpl{rpcBaseImpl: base})
+		gzv.addInstance(&RpcAdminImpl{rpcBaseImpl: base})
 	}
 	return nil
 }
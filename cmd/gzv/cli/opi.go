@@ -152,4 +152,12 @@ type chainOp interface {
 	TxReceipt(hash string) *RPCResObjCmd
 
 	GroupCheck(addr string) *RPCResObjCmd
+
+	GroupForecast() *RPCResObjCmd
+
+	ContractABI(addr string) *RPCResObjCmd
+
+	DecodeLogs(hash string) *RPCResObjCmd
+
+	ContractStorage(addr string, prefix string, startKey string, limit int, height uint64) *RPCResObjCmd
 }
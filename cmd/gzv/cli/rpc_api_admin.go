@@ -0,0 +1,153 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/darren0718/zvchain/network"
+)
+
+const adminTimeFormat = "2006-01-02 15:04:05"
+
+// RpcAdminImpl provides rpc service for the node operator to manage the local node
+type RpcAdminImpl struct {
+	*rpcBaseImpl
+}
+
+func (api *RpcAdminImpl) Namespace() string {
+	return "Admin"
+}
+
+func (api *RpcAdminImpl) Version() string {
+	return "1"
+}
+
+// BanPeer bans the peer for the given minutes. The duration grows with the times the peer banned if minutes is 0
+func (api *RpcAdminImpl) BanPeer(id string, minutes uint64, reason string) (bool, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return false, fmt.Errorf("empty peer id")
+	}
+	if reason == "" {
+		reason = "banned by admin"
+	}
+	if err := network.BanPeer(id, time.Duration(minutes)*time.Minute, reason); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UnbanPeer removes the peer from the ban list
+func (api *RpcAdminImpl) UnbanPeer(id string) (bool, error) {
+	if err := network.UnbanPeer(strings.TrimSpace(id)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// BannedPeers returns the peers banned currently
+func (api *RpcAdminImpl) BannedPeers() ([]*BannedPeerInfo, error) {
+	entries := network.BannedPeers()
+	ret := make([]*BannedPeerInfo, 0, len(entries))
+	for _, e := range entries {
+		ret = append(ret, &BannedPeerInfo{
+			ID:      e.ID,
+			Reason:  e.Reason,
+			Count:   e.Count,
+			LastBan: e.LastBan.Format(adminTimeFormat),
+			Until:   e.Until.Format(adminTimeFormat),
+		})
+	}
+	return ret, nil
+}
+
+// PeerScore returns the reputation score of the peer
+func (api *RpcAdminImpl) PeerScore(id string) (*PeerScoreInfo, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, fmt.Errorf("empty peer id")
+	}
+	return &PeerScoreInfo{
+		ID:     id,
+		Score:  network.PeerScore(id),
+		Banned: network.IsPeerBanned(id),
+	}, nil
+}
+
+// AddPeer connects to the node and keeps it connected as a static node
+func (api *RpcAdminImpl) AddPeer(id string, ip string, port int) (bool, error) {
+	if err := network.AddPeer(id, strings.TrimSpace(ip), port); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemovePeer disconnects the peer and removes it from the static nodes
+func (api *RpcAdminImpl) RemovePeer(id string) (bool, error) {
+	if err := network.RemovePeer(id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Peers returns the status of all the peers
+func (api *RpcAdminImpl) Peers() ([]*AdminPeerInfo, error) {
+	peers := network.Peers()
+	ret := make([]*AdminPeerInfo, 0, len(peers))
+	for _, p := range peers {
+		ret = append(ret, &AdminPeerInfo{
+			ID:              p.ID,
+			IP:              p.IP,
+			Port:            p.Port,
+			Session:         p.Session,
+			Source:          p.Source.String(),
+			Authed:          p.Authed,
+			ChainID:         p.ChainID,
+			BytesSend:       p.BytesSend,
+			BytesReceived:   p.BytesReceived,
+			DisconnectCount: p.DisconnectCount,
+			SessionAge:      uint64(p.SessionAge.Seconds()),
+			ProtocolVersion: p.ProtocolVersion,
+			Capabilities:    p.Capabilities,
+		})
+	}
+	return ret, nil
+}
+
+// NodeInfo returns the network status of the local node
+func (api *RpcAdminImpl) NodeInfo() (*AdminNodeInfo, error) {
+	info := network.GetNodeInfo()
+	if info == nil {
+		return nil, fmt.Errorf("network not initialized")
+	}
+	return &AdminNodeInfo{
+		ID:                 info.ID,
+		IP:                 info.IP,
+		Port:               info.Port,
+		NetID:              info.NetID,
+		ChainID:            info.ChainID,
+		ProtocolVersion:    info.ProtocolVersion,
+		MinProtocolVersion: info.MinProtocolVersion,
+		Capabilities:       info.Capabilities,
+		Transport:          info.Transport,
+		PeerCount:          info.PeerCount,
+		KadSize:            info.KadSize,
+		StaticNodes:        info.StaticNodes,
+	}, nil
+}
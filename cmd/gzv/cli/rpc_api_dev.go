@@ -26,11 +26,15 @@ import (
 	"github.com/darren0718/zvchain/consensus/groupsig"
 	"github.com/darren0718/zvchain/consensus/mediator"
 	"github.com/darren0718/zvchain/core"
+	"github.com/darren0718/zvchain/middleware/time"
 	"github.com/darren0718/zvchain/middleware/types"
 	"github.com/darren0718/zvchain/network"
+	"github.com/darren0718/zvchain/tvm"
 	"github.com/pmylund/sortutil"
 )
 
+const profileGasLimit = 500000 // Max gas limit of the transactions
+
 // RpcDevImpl provides api functions for those develop chain features.
 // It is mainly for debug or test use
 type RpcDevImpl struct {
@@ -414,6 +418,19 @@ func (api *RpcDevImpl) BlockDetail(h string) (*BlockDetail, error) {
 	return bd, nil
 }
 
+// BlamedMembers returns the members which sent bad signature pieces for the block, seen by the local node
+func (api *RpcDevImpl) BlamedMembers(h string) ([]string, error) {
+	if !validateHash(strings.TrimSpace(h)) {
+		return nil, fmt.Errorf("wrong param format")
+	}
+	ids := mediator.Proc.GetBlamedMembers(common.HexToHash(h))
+	members := make([]string, 0, len(ids))
+	for _, id := range ids {
+		members = append(members, id.GetAddrString())
+	}
+	return members, nil
+}
+
 func (api *RpcDevImpl) BlockReceipts(h string) (*BlockReceipt, error) {
 	if !validateHash(strings.TrimSpace(h)) {
 		return nil, fmt.Errorf("wrong param format")
@@ -436,6 +453,90 @@ func (api *RpcDevImpl) BlockReceipts(h string) (*BlockReceipt, error) {
 	return br, nil
 }
 
+// BlockReceiptDetails returns the receipts of the block with the fee split between the proposer,
+// the verifiers and the burned part
+func (api *RpcDevImpl) BlockReceiptDetails(h string) (*BlockReceiptDetail, error) {
+	if !validateHash(strings.TrimSpace(h)) {
+		return nil, fmt.Errorf("wrong param format")
+	}
+	chain := core.BlockChainImpl
+	b := chain.QueryBlockByHash(common.HexToHash(h))
+	if b == nil {
+		return nil, fmt.Errorf("block not found")
+	}
+
+	evictedReceipts := make([]*Receipt, 0)
+	receipts := make([]*Receipt, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		wrapper := chain.GetTransactionPool().GetReceipt(tx.GenHash())
+		if wrapper != nil {
+			receipts[i] = convertReceipt(wrapper, tx)
+		}
+	}
+	br := &BlockReceiptDetail{EvictedReceipts: evictedReceipts, Receipts: receipts}
+	return br, nil
+}
+
+// ProfileContractCall executes the contract call on the latest state without saving it, and
+// returns the gas attributed to the bridge operations. The call runs in the block next to the
+// top one, as the transaction sent would. The gas limit is the max of the transactions if not given
+func (api *RpcDevImpl) ProfileContractCall(source string, contractAddr string, abiJSON string, value uint64, gasLimit *uint64) (*ContractProfile, error) {
+	if !common.ValidateAddress(strings.TrimSpace(source)) || !common.ValidateAddress(strings.TrimSpace(contractAddr)) {
+		return nil, fmt.Errorf("wrong address format")
+	}
+	limit := uint64(profileGasLimit)
+	if gasLimit != nil && *gasLimit > 0 && *gasLimit < limit {
+		limit = *gasLimit
+	}
+	db, err := core.BlockChainImpl.LatestAccountDB()
+	if err != nil || db == nil {
+		return nil, fmt.Errorf("get status failed")
+	}
+	sender := common.StringToAddress(strings.TrimSpace(source))
+	target := common.StringToAddress(strings.TrimSpace(contractAddr))
+	raw := &types.RawTransaction{
+		Source:   &sender,
+		Target:   &target,
+		Value:    types.NewBigInt(value),
+		GasLimit: types.NewBigInt(limit),
+		GasPrice: types.NewBigInt(0),
+		Data:     []byte(abiJSON),
+		Type:     types.TransactionTypeContractCall,
+	}
+
+	top := core.BlockChainImpl.QueryTopBlock()
+	header := &types.BlockHeader{
+		Height:  top.Height + 1,
+		PreHash: top.Hash,
+		CurTime: time.TSInstance.Now(),
+	}
+	controller := tvm.NewController(db, core.BlockChainImpl, header, types.NewTransaction(raw, raw.GenHash()), 0, core.MinerManagerImpl)
+	contract := controller.LoadContract(target)
+	if contract.Code == "" {
+		return nil, fmt.Errorf("no code at the given address %v", target.AddrPrefixString())
+	}
+	amount := new(big.Int).SetUint64(value)
+	if !db.CanTransfer(sender, amount) {
+		return nil, fmt.Errorf("balance not enough")
+	}
+	db.Transfer(sender, target, amount)
+
+	controller.Profile = tvm.NewGasProfile()
+	result, logs, txErr := controller.ExecuteAbiEval(&sender, contract, abiJSON)
+	profile := &ContractProfile{
+		GasUsed: limit - controller.GetGasLeft(),
+		Logs:    logs,
+		Profile: controller.Profile,
+	}
+	if result != nil {
+		profile.Result = result.Content
+	}
+	if txErr != nil {
+		profile.Error = txErr.Message
+	}
+	return profile, nil
+}
+
 // MonitorBlocks monitoring platform calls block sync
 func (api *RpcDevImpl) MonitorBlocks(begin, end uint64) ([]*BlockDetail, error) {
 	if end < begin {
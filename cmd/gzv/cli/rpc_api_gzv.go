@@ -16,9 +16,10 @@
 package cli
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/consensus/group"
+	"github.com/darren0718/zvchain/consensus/groupsig"
 	"github.com/darren0718/zvchain/core"
 	"github.com/darren0718/zvchain/middleware/types"
 	"github.com/darren0718/zvchain/tvm"
@@ -42,6 +43,7 @@ type groupInfoReader interface {
 
 type groupRoutineChecker interface {
 	CurrentEraCheck(address common.Address) (selected bool, seed common.Hash, seedHeight uint64, stage int)
+	Forecast() (*group.CreateForecast, error)
 }
 
 type blockReader interface {
@@ -365,6 +367,35 @@ func (api *RpcGzvImpl) TxReceipt(h string) (*ExecutedTransaction, error) {
 	return nil, nil
 }
 
+// DecodeLogs decodes the logs of the transaction into the named fields by the abi of the contracts
+func (api *RpcGzvImpl) DecodeLogs(h string) ([]*tvm.DecodedLog, error) {
+	h = strings.TrimSpace(h)
+	if !validateHash(h) {
+		return nil, fmt.Errorf("wrong hash format")
+	}
+	rc := core.BlockChainImpl.GetTransactionPool().GetReceipt(common.HexToHash(h))
+	if rc == nil {
+		return nil, nil
+	}
+	accountDb, err := core.BlockChainImpl.LatestAccountDB()
+	if err != nil {
+		return nil, fmt.Errorf("get status failed")
+	}
+	abis := make(map[common.Address]*tvm.ContractABI)
+	logs := make([]*tvm.DecodedLog, 0, len(rc.Logs))
+	for _, log := range rc.Logs {
+		abi, ok := abis[log.Address]
+		if !ok {
+			if abi, err = contractABI(accountDb, log.Address); err != nil {
+				return nil, err
+			}
+			abis[log.Address] = abi
+		}
+		logs = append(logs, abi.DecodeLog(log))
+	}
+	return logs, nil
+}
+
 // ViewAccount is used for querying account information
 func (api *RpcGzvImpl) ViewAccount(hash string) (*ExplorerAccount, error) {
 	hash = strings.TrimSpace(hash)
@@ -393,16 +424,17 @@ func (api *RpcGzvImpl) ViewAccount(hash string) (*ExplorerAccount, error) {
 		account.Type = 1
 		account.StateData = make(map[string]interface{})
 
-		contract := tvm.Contract{}
-		err = json.Unmarshal([]byte(account.Code), &contract)
+		abi, err := contractABI(accountDb, address)
 		if err != nil {
-			return nil, fmt.Errorf("UnMarshall contract fail!" + err.Error())
+			return nil, err
 		}
-		abi := parseABI(contract.Code)
-		account.ABI = abi
+		account.ABI = abi.Functions
 
 		iter := accountDb.DataIterator(common.StringToAddress(hash), []byte{})
 		for iter.Next() {
+			if tvm.IsReservedKey(iter.Key) {
+				continue
+			}
 			k := string(iter.Key[:])
 			v := tvm.VmDataConvert(iter.Value[:])
 			account.StateData[k] = v
@@ -412,6 +444,19 @@ func (api *RpcGzvImpl) ViewAccount(hash string) (*ExplorerAccount, error) {
 	return account, nil
 }
 
+// ContractABI returns the abi of the contract, including the public functions and the events
+func (api *RpcGzvImpl) ContractABI(addr string) (*tvm.ContractABI, error) {
+	addr = strings.TrimSpace(addr)
+	if !common.ValidateAddress(addr) {
+		return nil, fmt.Errorf("wrong address format")
+	}
+	accountDb, err := core.BlockChainImpl.LatestAccountDB()
+	if err != nil {
+		return nil, fmt.Errorf("get status failed")
+	}
+	return contractABI(accountDb, common.StringToAddress(addr))
+}
+
 func (api *RpcGzvImpl) QueryAccountData(addr string, key string, count int) (interface{}, error) {
 	addr = strings.TrimSpace(addr)
 	// input check
@@ -420,11 +465,10 @@ func (api *RpcGzvImpl) QueryAccountData(addr string, key string, count int) (int
 	}
 	address := common.StringToAddress(addr)
 
-	const MaxCountQuery = 100
 	if count <= 0 {
 		count = 0
-	} else if count > MaxCountQuery {
-		count = MaxCountQuery
+	} else if count > maxStorageQueryLimit {
+		count = maxStorageQueryLimit
 	}
 
 	chain := core.BlockChainImpl
@@ -434,6 +478,9 @@ func (api *RpcGzvImpl) QueryAccountData(addr string, key string, count int) (int
 	}
 
 	var resultData interface{}
+	if tvm.IsReservedKey([]byte(key)) {
+		return nil, fmt.Errorf("reserved key")
+	}
 	if count == 0 {
 		value := state.GetData(address, []byte(key))
 		if value != nil {
@@ -447,7 +494,7 @@ func (api *RpcGzvImpl) QueryAccountData(addr string, key string, count int) (int
 			tmp := make([]map[string]interface{}, 0)
 			for iter.Next() {
 				k := string(iter.Key[:])
-				if !strings.HasPrefix(k, key) {
+				if !strings.HasPrefix(k, key) || tvm.IsReservedKey(iter.Key) {
 					continue
 				}
 				v := tvm.VmDataConvert(iter.Value[:])
@@ -469,6 +516,33 @@ func (api *RpcGzvImpl) QueryAccountData(addr string, key string, count int) (int
 	}
 }
 
+// ContractStorage returns at most limit data items of the contract with the prefix, starting
+// from the start key, empty for the first page. The nextKey of the result is the start key of the next page. The state
+// at the height is read if given, otherwise the latest
+func (api *RpcGzvImpl) ContractStorage(addr string, prefix string, startKey string, limit int, height *uint64) (*ContractStorage, error) {
+	addr = strings.TrimSpace(addr)
+	if !common.ValidateAddress(addr) {
+		return nil, fmt.Errorf("wrong address format")
+	}
+	if startKey != "" && !strings.HasPrefix(startKey, prefix) {
+		return nil, fmt.Errorf("start key should have the prefix")
+	}
+	if limit <= 0 || limit > maxStorageQueryLimit {
+		limit = maxStorageQueryLimit
+	}
+	var db types.AccountDB
+	var err error
+	if height == nil {
+		db, err = core.BlockChainImpl.LatestAccountDB()
+	} else {
+		db, err = core.BlockChainImpl.AccountDBAt(*height)
+	}
+	if err != nil || db == nil {
+		return nil, fmt.Errorf("get status failed")
+	}
+	return contractStorage(db, common.StringToAddress(addr), prefix, startKey, limit), nil
+}
+
 func (api *RpcGzvImpl) GroupCheck(addr string) (*GroupCheckInfo, error) {
 	addr = strings.TrimSpace(addr)
 	if !common.ValidateAddress(addr) {
@@ -511,6 +585,44 @@ func (api *RpcGzvImpl) GroupCheck(addr string) (*GroupCheckInfo, error) {
 	return &GroupCheckInfo{JoinedGroups: jgs, CurrentGroupRoutine: currentInfo}, nil
 }
 
+// GroupForecast reports whether the group creation of the current era will succeed based on the packets received so far
+func (api *RpcGzvImpl) GroupForecast() (*GroupForecastInfo, error) {
+	fc, err := api.routineChecker.Forecast()
+	if err != nil {
+		return nil, err
+	}
+	addrs := func(ids []groupsig.ID) []string {
+		ret := make([]string, 0, len(ids))
+		for _, id := range ids {
+			ret = append(ret, id.GetAddrString())
+		}
+		return ret
+	}
+	return &GroupForecastInfo{
+		Height:              fc.Height,
+		SeedHeight:          fc.SeedHeight,
+		GroupSeed:           fc.Seed,
+		Round:               fc.Round,
+		CandidateCount:      fc.CandidateCount,
+		CandidateRequired:   fc.CandidateRequired,
+		CandidateEnough:     fc.CandidateEnough,
+		PieceReceived:       fc.PieceReceived,
+		PieceRequired:       fc.PieceRequired,
+		PieceEnough:         fc.PieceEnough,
+		MpkReceived:         fc.MpkReceived,
+		MpkRequired:         fc.MpkRequired,
+		MpkEnough:           fc.MpkEnough,
+		OriginPieceRequired: fc.OriginPieceRequired,
+		OriginPieceReceived: fc.OriginPieceReceived,
+		MissingPiece:        addrs(fc.MissingPiece),
+		MissingMpk:          addrs(fc.MissingMpk),
+		MissingOriginPiece:  addrs(fc.MissingOriginPiece),
+		FreezeList:          addrs(fc.FreezeList),
+		Projection:          fc.Projection,
+		Reason:              fc.Reason,
+	}, nil
+}
+
 func (api *RpcGzvImpl) CheckPointAt(h uint64) (*types.BlockHeader, error) {
 	cp := api.br.CheckPointAt(h)
 	return cp, nil
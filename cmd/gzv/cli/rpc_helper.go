@@ -16,6 +16,7 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/darren0718/zvchain/consensus/logical"
 	"github.com/darren0718/zvchain/log"
@@ -60,22 +61,46 @@ func convertTransaction(tx *types.Transaction) *Transaction {
 }
 
 func convertExecutedTransaction(executed *types.ExecutedTransaction) *ExecutedTransaction {
-	rec := &Receipt{
-		Status:            int(executed.Receipt.Status),
-		CumulativeGasUsed: executed.Receipt.CumulativeGasUsed,
-		Logs:              executed.Receipt.Logs,
-		TxHash:            executed.Receipt.TxHash,
-		ContractAddress:   executed.Receipt.ContractAddress,
-		Height:            executed.Receipt.Height,
-		TxIndex:           executed.Receipt.TxIndex,
-	}
 	return &ExecutedTransaction{
-		Receipt:     rec,
+		Receipt:     convertReceipt(executed.Receipt, executed.Transaction.RawTransaction),
 		Transaction: convertTransaction(executed.Transaction),
 	}
 
 }
 
+// convertReceipt converts the receipt with the fee split between the proposer and the verifiers.
+// The receipts before zip009 don't record the fee, which is derived from the gas used and the
+// gas price of the transaction
+func convertReceipt(receipt *types.Receipt, tx *types.RawTransaction) *Receipt {
+	rec := &Receipt{
+		Status:            int(receipt.Status),
+		CumulativeGasUsed: receipt.CumulativeGasUsed,
+		Logs:              receipt.Logs,
+		TxHash:            receipt.TxHash,
+		ContractAddress:   receipt.ContractAddress,
+		Height:            receipt.Height,
+		TxIndex:           receipt.TxIndex,
+		GasUsed:           receipt.GasUsed,
+		EffectiveGasPrice: receipt.GasPrice,
+		Fee:               receipt.Fee,
+	}
+	if receipt.Bloom != (types.Bloom{}) {
+		rec.Bloom = common.ToHex(receipt.Bloom.Bytes())
+	}
+	if rec.GasUsed == 0 && tx != nil && tx.GasPrice != nil {
+		rec.GasUsed = receipt.CumulativeGasUsed
+		rec.EffectiveGasPrice = tx.GasPrice.Uint64()
+		rec.Fee = rec.GasUsed * rec.EffectiveGasPrice
+	}
+	if rec.Fee > 0 {
+		share := core.BlockChainImpl.GetRewardManager().CalculateCastRewardShare(rec.Height, rec.Fee)
+		rec.FeeForProposer = share.FeeForProposer
+		rec.FeeForVerifier = share.FeeForVerifier
+		rec.FeeBurned = rec.Fee - share.FeeForProposer - share.FeeForVerifier
+	}
+	return rec
+}
+
 func convertBlockHeader(b *types.Block) *Block {
 	bh := b.Header
 	block := &Block{
@@ -171,49 +196,57 @@ func convertGroup(g types.GroupI) *Group {
 
 }
 
-func parseABI(code string) []tvm.ABIVerify {
-
-	ABIs := make([]tvm.ABIVerify, 0)
-
-	stringSlice := strings.Split(code, "\n")
-	for k, targetString := range stringSlice {
-		targetString = strings.TrimSpace(targetString)
-		if strings.HasPrefix(targetString, "@register.public") {
-			params := strings.TrimPrefix(targetString, "@register.public")
-			params = params[1 : len(params)-1]
-			args := strings.Split(params, ",")
-			argsNew := make([]string, 0)
-			for _, arg := range args {
-				arg = strings.TrimSpace(arg)
-				if len(arg) > 0 {
-					argsNew = append(argsNew, arg)
-				}
-			}
-
-			funcName := ""
-			if k+1 < len(stringSlice) {
-				funcLine := stringSlice[k+1]
-				funcLine = strings.TrimSpace(funcLine)
-				if strings.HasPrefix(funcLine, "def") {
-					funcLine = strings.TrimPrefix(funcLine, "def")
-					funcLine = strings.TrimSpace(funcLine)
-
-					for m, v := range funcLine {
-						if v == '(' {
-							funcName = funcLine[:m]
-							funcName = strings.TrimSpace(funcName)
-						}
-					}
-					abi := tvm.ABIVerify{
-						FuncName: funcName,
-						Args:     argsNew,
-					}
-					ABIs = append(ABIs, abi)
-				}
-			}
+// contractABI returns the abi stored in the contract account. The abi of the contracts
+// deployed before stored is extracted from the code
+func contractABI(db types.AccountDB, addr common.Address) (*tvm.ContractABI, error) {
+	if abi := tvm.LoadABI(db, addr); abi != nil {
+		return abi, nil
+	}
+	code := db.GetCode(addr)
+	if len(code) == 0 {
+		return nil, fmt.Errorf("no code at the given address %v", addr.AddrPrefixString())
+	}
+	contract := tvm.Contract{}
+	if err := json.Unmarshal(code, &contract); err != nil {
+		return nil, fmt.Errorf("UnMarshall contract fail!%v", err)
+	}
+	return tvm.ExtractABI(contract.Code), nil
+}
+
+// maxStorageQueryLimit is the max number of the contract data items returned by one query
+const maxStorageQueryLimit = 100
+
+// contractStorage returns at most limit data items of the contract with the prefix, starting
+// from the start key, which is the next key of the previous page. The keys with the prefix
+// are contiguous in the trie, so the scan stops at the first key out of the prefix. The trie
+// yields the longer keys before the key they extend, which are skipped when resuming from the
+// start key as returned by the previous page
+func contractStorage(db types.AccountDB, addr common.Address, prefix string, startKey string, limit int) *ContractStorage {
+	ret := &ContractStorage{Items: make([]*StorageItem, 0)}
+	resume := startKey != "" && strings.HasPrefix(startKey, prefix)
+	start := prefix
+	if resume {
+		start = startKey
+	}
+	iter := db.DataIterator(addr, []byte(start))
+	if iter == nil {
+		return ret
+	}
+	for iter.Next() {
+		k := string(iter.Key)
+		if !strings.HasPrefix(k, prefix) {
+			break
+		}
+		if tvm.IsReservedKey(iter.Key) || (resume && k != startKey && strings.HasPrefix(k, startKey)) {
+			continue
+		}
+		if len(ret.Items) >= limit {
+			ret.NextKey = k
+			break
 		}
+		ret.Items = append(ret.Items, &StorageItem{Key: k, Value: tvm.VmDataConvert(iter.Value)})
 	}
-	return ABIs
+	return ret
 }
 
 func getMorts(p logical.Processor) (string, []MortGage) {
@@ -23,7 +23,11 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/darren0718/zvchain/common"
 	"github.com/darren0718/zvchain/core"
+	"github.com/darren0718/zvchain/storage/account"
+	"github.com/darren0718/zvchain/storage/tasdb"
+	"github.com/darren0718/zvchain/tvm"
 )
 
 const code = `
@@ -195,10 +199,56 @@ func TestUnmarhsalTxRawData(t *testing.T) {
 }
 
 func TestParseABI(t *testing.T) {
-	abi := parseABI(code)
+	abi := tvm.ExtractABI(code).Functions
 	fmt.Println(abi)
 	for _, v := range abi {
 		fmt.Println(v.FuncName)
 		fmt.Println(len(v.Args))
 	}
 }
+
+func TestContractStorage_Pagination(t *testing.T) {
+	db, _ := tasdb.NewMemDatabase()
+	database := account.NewDatabase(db)
+	state, _ := account.NewAccountDB(common.Hash{}, database)
+	addr := common.BytesToAddress([]byte("contract"))
+	for i := 0; i < 25; i++ {
+		state.SetData(addr, []byte(fmt.Sprintf("balanceOf@%d", i)), []byte(fmt.Sprintf("s%d", i)))
+	}
+	state.SetData(addr, []byte("balanceOf@"), []byte("s"))
+	state.SetData(addr, []byte("name"), []byte("sToken"))
+	if err := tvm.StoreABI(state, addr, &tvm.ContractABI{}); err != nil {
+		t.Fatal(err)
+	}
+	root, _ := state.Commit(false)
+	database.TrieDB().Commit(root, false)
+	state, _ = account.NewAccountDB(root, database)
+
+	// The keys extending the others are yielded first, which mustn't repeat across the pages
+	seen := make(map[string]bool)
+	startKey := ""
+	for {
+		ret := contractStorage(state, addr, "balanceOf@", startKey, 4)
+		if len(ret.Items) > 4 {
+			t.Fatalf("page exceeds the limit: %v", len(ret.Items))
+		}
+		for _, item := range ret.Items {
+			if seen[item.Key] {
+				t.Fatalf("key %v returned twice", item.Key)
+			}
+			seen[item.Key] = true
+		}
+		if ret.NextKey == "" {
+			break
+		}
+		startKey = ret.NextKey
+	}
+	if len(seen) != 26 {
+		t.Fatalf("expect 26 keys, got %v", len(seen))
+	}
+
+	all := contractStorage(state, addr, "", "", maxStorageQueryLimit)
+	if len(all.Items) != 27 {
+		t.Fatalf("expect 27 keys without the reserved, got %v", len(all.Items))
+	}
+}
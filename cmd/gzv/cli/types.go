@@ -222,11 +222,26 @@ type BlockDetail struct {
 	PreTotalQN    uint64                `json:"pre_total_qn"`
 }
 
+// ContractProfile is the result of the contract call profiled on the latest state
+type ContractProfile struct {
+	GasUsed uint64          `json:"gasUsed"`
+	Result  string          `json:"result"`
+	Error   string          `json:"error,omitempty"`
+	Logs    []*types.Log    `json:"logs"`
+	Profile *tvm.GasProfile `json:"profile"`
+}
+
 type BlockReceipt struct {
 	Receipts        []*types.Receipt `json:"receipts"`
 	EvictedReceipts []*types.Receipt `json:"evictedReceipts"`
 }
 
+// BlockReceiptDetail is the receipts of the block with the fee split
+type BlockReceiptDetail struct {
+	Receipts        []*Receipt `json:"receipts"`
+	EvictedReceipts []*Receipt `json:"evictedReceipts"`
+}
+
 type ExplorerBlockDetail struct {
 	BlockDetail
 	Receipts        []*types.Receipt `json:"receipts"`
@@ -273,11 +288,19 @@ type Receipt struct {
 	Status            int          `json:"status"`
 	CumulativeGasUsed uint64       `json:"cumulativeGasUsed"`
 	Logs              []*types.Log `json:"logs"`
+	Bloom             string       `json:"bloom,omitempty"`
 
 	TxHash          common.Hash    `json:"transactionHash" gencodec:"required"`
 	ContractAddress common.Address `json:"contractAddress"`
 	Height          uint64         `json:"height"`
 	TxIndex         uint16         `json:"tx_index"`
+
+	GasUsed           uint64 `json:"gasUsed"`
+	EffectiveGasPrice uint64 `json:"effectiveGasPrice"`
+	Fee               uint64 `json:"fee"`
+	FeeForProposer    uint64 `json:"feeForProposer"`
+	FeeForVerifier    uint64 `json:"feeForVerifier"`
+	FeeBurned         uint64 `json:"feeBurned"`
 }
 
 type ExecutedTransaction struct {
@@ -314,6 +337,19 @@ type ExplorerAccount struct {
 	StateData map[string]interface{} `json:"state_data"`
 }
 
+// StorageItem is a data item of the contract, the value decoded
+type StorageItem struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// ContractStorage is a page of the contract data in the key order. The scan continues
+// from the next key, which is empty if no more
+type ContractStorage struct {
+	Items   []*StorageItem `json:"items"`
+	NextKey string         `json:"nextKey"`
+}
+
 type ExploreBlockReward struct {
 	ProposalID           string            `json:"proposal_id"`
 	ProposalReward       uint64            `json:"proposal_reward"`
@@ -344,3 +380,72 @@ type GroupCheckInfo struct {
 	JoinedGroups        []*JoinedGroupInfo   `json:"joined_living_groups"`
 	CurrentGroupRoutine *CurrentEraGroupInfo `json:"current_group_routine"`
 }
+
+type GroupForecastInfo struct {
+	Height              uint64      `json:"height"`
+	SeedHeight          uint64      `json:"seed_height"`
+	GroupSeed           common.Hash `json:"seed"`
+	Round               string      `json:"round"`
+	CandidateCount      int         `json:"candidate_count"`
+	CandidateRequired   int         `json:"candidate_required"`
+	CandidateEnough     bool        `json:"candidate_enough"`
+	PieceReceived       int         `json:"piece_received"`
+	PieceRequired       int         `json:"piece_required"`
+	PieceEnough         bool        `json:"piece_enough"`
+	MpkReceived         int         `json:"mpk_received"`
+	MpkRequired         int         `json:"mpk_required"`
+	MpkEnough           bool        `json:"mpk_enough"`
+	OriginPieceRequired bool        `json:"origin_piece_required"`
+	OriginPieceReceived int         `json:"origin_piece_received"`
+	MissingPiece        []string    `json:"missing_piece"`
+	MissingMpk          []string    `json:"missing_mpk"`
+	MissingOriginPiece  []string    `json:"missing_origin_piece"`
+	FreezeList          []string    `json:"freeze_list"`
+	Projection          string      `json:"projection"`
+	Reason              string      `json:"reason"`
+}
+
+type BannedPeerInfo struct {
+	ID      string `json:"id"`
+	Reason  string `json:"reason"`
+	Count   int    `json:"count"`
+	LastBan string `json:"last_ban"`
+	Until   string `json:"until"`
+}
+
+type PeerScoreInfo struct {
+	ID     string `json:"id"`
+	Score  int    `json:"score"`
+	Banned bool   `json:"banned"`
+}
+
+type AdminPeerInfo struct {
+	ID              string   `json:"id"`
+	IP              string   `json:"ip"`
+	Port            int      `json:"port"`
+	Session         uint32   `json:"session"`
+	Source          string   `json:"source"`
+	Authed          bool     `json:"authed"`
+	ChainID         uint16   `json:"chain_id"`
+	BytesSend       int      `json:"bytes_send"`
+	BytesReceived   int      `json:"bytes_received"`
+	DisconnectCount int      `json:"disconnect_count"`
+	SessionAge      uint64   `json:"session_age"` // In seconds
+	ProtocolVersion uint16   `json:"protocol_version"`
+	Capabilities    []string `json:"capabilities"`
+}
+
+type AdminNodeInfo struct {
+	ID                 string   `json:"id"`
+	IP                 string   `json:"ip"`
+	Port               int      `json:"port"`
+	NetID              uint64   `json:"net_id"`
+	ChainID            uint16   `json:"chain_id"`
+	ProtocolVersion    uint16   `json:"protocol_version"`
+	MinProtocolVersion uint16   `json:"min_protocol_version"`
+	Capabilities       []string `json:"capabilities"`
+	Transport          string   `json:"transport"`
+	PeerCount          int      `json:"peer_count"`
+	KadSize            int      `json:"kad_size"`
+	StaticNodes        []string `json:"static_nodes"`
+}
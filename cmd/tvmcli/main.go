@@ -21,19 +21,47 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/darren0718/zvchain/tvm"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 var (
 	app = kingpin.New("chat", "A command-line chat application.")
 
+	sender          = app.Flag("sender", "sender of the transactions, index of the default accounts or address.").Default("0").String()
+	profile         = app.Flag("profile", "attribute the gas of the transactions to the bridge operations.").Bool()
+	maxStorageBytes = app.Flag("max-storage-bytes", "limit of the storage bytes written by each call, instead of the chain's.").Uint64()
+	maxEvents       = app.Flag("max-events", "limit of the events emitted by each call, instead of the chain's.").Uint64()
+
 	deployContract = app.Command("deploy", "deploy contract.")
 	contractName   = deployContract.Arg("name", "").Required().String()
 	contractPath   = deployContract.Arg("path", "").Required().String()
+	deployValue    = deployContract.Flag("value", "value transferred to the contract.").Default("0").Uint64()
 
 	callContract    = app.Command("call", "call contract.")
 	contractAddress = callContract.Arg("contractAddress", "contract address.").Required().String()
 	contractAbi     = callContract.Arg("abiPath", "").Required().String()
+	callValue       = callContract.Flag("value", "value transferred to the contract.").Default("0").Uint64()
+
+	transfer      = app.Command("transfer", "transfer value from the sender.")
+	transferTo    = transfer.Arg("to", "index of the default accounts or address.").Required().String()
+	transferValue = transfer.Arg("value", "").Required().Uint64()
+
+	accounts = app.Command("accounts", "list the default accounts.")
+
+	block         = app.Command("block", "show or set the top block, the transactions are executed in the next blocks.")
+	blockHeight   = block.Flag("height", "height of the top block.").Uint64()
+	blockTime     = block.Flag("time", "unix seconds of the top block.").Int64()
+	blockInterval = block.Flag("interval", "seconds between the blocks.").Int64()
+
+	snapshot     = app.Command("snapshot", "save the state and the block under the name.")
+	snapshotName = snapshot.Arg("name", "").Required().String()
+
+	revert     = app.Command("revert", "revert the state and the block to the snapshot.")
+	revertName = revert.Arg("name", "").Required().String()
+
+	testScenario  = app.Command("test", "run the json or yaml scenarios of the contracts on a fresh sandbox.")
+	scenarioPaths = testScenario.Arg("paths", "").Required().Strings()
 
 	exportAbi             = app.Command("export", "export abi.")
 	exportAbiContractName = exportAbi.Arg("name", "").Required().String()
@@ -47,9 +75,35 @@ var (
 
 func main() {
 
+	command := kingpin.MustParse(app.Parse(os.Args[1:]))
+	if command == testScenario.FullCommand() {
+		failed := 0
+		for _, path := range *scenarioPaths {
+			n, err := RunScenario(path, os.Stdout)
+			if err != nil {
+				fmt.Println(err)
+				n = 1
+			}
+			failed += n
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
 	tvmCli := NewTvmCli()
+	defer tvmCli.DeleteTvmCli()
+	if err := tvmCli.SetSender(*sender); err != nil {
+		fmt.Println(err)
+		return
+	}
+	tvmCli.SetProfile(*profile)
+	if *maxStorageBytes > 0 || *maxEvents > 0 {
+		tvmCli.SetLimits(&tvm.CallLimits{MaxStorageBytes: *maxStorageBytes, MaxEvents: *maxEvents})
+	}
 
-	switch kingpin.MustParse(app.Parse(os.Args[1:])) {
+	switch command {
 
 	// deploy Token ./cli/erc20.py
 	case deployContract.FullCommand():
@@ -58,6 +112,7 @@ func main() {
 			fmt.Println("read the ", *contractPath, " file failed ", err)
 			return
 		}
+		tvmCli.SetValue(*deployValue)
 		tvmCli.Deploy(*contractName, string(f))
 
 	// call ./cli/call_Token_abi.json
@@ -67,8 +122,28 @@ func main() {
 			fmt.Println("read the ", *contractAbi, " file failed ", err)
 			return
 		}
+		tvmCli.SetValue(*callValue)
 		tvmCli.Call(*contractAddress, string(f))
 
+	case transfer.FullCommand():
+		if err := tvmCli.Transfer(*transferTo, *transferValue); err != nil {
+			fmt.Println(err)
+		}
+
+	case accounts.FullCommand():
+		tvmCli.Accounts()
+
+	case block.FullCommand():
+		tvmCli.SetBlock(*blockHeight, *blockTime, *blockInterval)
+
+	case snapshot.FullCommand():
+		tvmCli.SaveSnapshot(*snapshotName)
+
+	case revert.FullCommand():
+		if err := tvmCli.RevertSnapshot(*revertName); err != nil {
+			fmt.Println(err)
+		}
+
 	// export ./cli/erc20.py
 	case exportAbi.FullCommand():
 		f, err := ioutil.ReadFile(filepath.Dir(os.Args[0]) + "/" + *exportAbiContractPath) //读取文件
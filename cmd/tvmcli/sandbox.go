@@ -0,0 +1,322 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/middleware/time"
+	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/darren0718/zvchain/storage/account"
+	"github.com/darren0718/zvchain/tvm"
+)
+
+const defaultBlockInterval = 3 // Seconds between the sandbox blocks
+
+// Transaction is the transaction executed in the sandbox
+type Transaction struct {
+	opType int8
+	source *common.Address
+	target *common.Address
+	value  uint64
+	nonce  uint64
+	data   []byte
+	hash   common.Hash
+}
+
+func (tx *Transaction) OpType() int8              { return tx.opType }
+func (tx *Transaction) GetGasLimit() uint64       { return TransactionGasLimitMax }
+func (tx *Transaction) GetValue() uint64          { return tx.value }
+func (tx *Transaction) Amount() *big.Int          { return new(big.Int).SetUint64(tx.value) }
+func (tx *Transaction) Operator() *common.Address { return tx.source }
+func (tx *Transaction) OpTarget() *common.Address { return tx.target }
+func (tx *Transaction) Payload() []byte           { return tx.data }
+func (tx *Transaction) GetExtraData() []byte      { return nil }
+func (tx *Transaction) GetNonce() uint64          { return tx.nonce }
+func (tx *Transaction) GetHash() common.Hash      { return tx.hash }
+func (tx *Transaction) GetGasLimitOriginal() *big.Int {
+	return new(big.Int).SetUint64(TransactionGasLimitMax)
+}
+
+// SandboxChain is the chain of the sandbox, which only keeps the position of the top block.
+// The headers below the top are generated on demand, one block per interval
+type SandboxChain struct {
+	height   uint64
+	time     int64 // Unix seconds of the top block
+	interval int64
+	heights  map[common.Hash]uint64
+}
+
+// NewSandboxChain creates the chain with the top block at the height and time
+func NewSandboxChain(height uint64, unixTime int64, interval int64) *SandboxChain {
+	if interval <= 0 {
+		interval = defaultBlockInterval
+	}
+	return &SandboxChain{height: height, time: unixTime, interval: interval, heights: make(map[common.Hash]uint64)}
+}
+
+func sandboxBlockHash(height uint64) common.Hash {
+	return common.BytesToHash(common.Sha256(common.BytesCombine([]byte("sandbox"), common.Uint64ToByte(height))))
+}
+
+func (c *SandboxChain) header(height uint64) *types.BlockHeader {
+	if height > c.height {
+		return nil
+	}
+	bh := &types.BlockHeader{
+		Height:  height,
+		Hash:    sandboxBlockHash(height),
+		CurTime: time.Int64MilliSecondsToTimeStamp((c.time - int64(c.height-height)*c.interval) * 1000),
+	}
+	if height > 0 {
+		bh.PreHash = sandboxBlockHash(height - 1)
+	}
+	c.heights[bh.Hash] = height
+	return bh
+}
+
+// next advances the chain by one block and returns the new top
+func (c *SandboxChain) next() *types.BlockHeader {
+	c.height++
+	c.time += c.interval
+	return c.header(c.height)
+}
+
+func (c *SandboxChain) Height() uint64 {
+	return c.height
+}
+
+func (c *SandboxChain) QueryTopBlock() *types.BlockHeader {
+	return c.header(c.height)
+}
+
+func (c *SandboxChain) QueryBlockHeaderByHash(hash common.Hash) *types.BlockHeader {
+	if height, ok := c.heights[hash]; ok {
+		return c.header(height)
+	}
+	return nil
+}
+
+func (c *SandboxChain) QueryBlockHeaderByHeight(height uint64) *types.BlockHeader {
+	return c.header(height)
+}
+
+func (c *SandboxChain) HasBlock(hash common.Hash) bool {
+	_, ok := c.heights[hash]
+	return ok
+}
+
+func (c *SandboxChain) HasHeight(height uint64) bool {
+	return height <= c.height
+}
+
+// ExecResult is the result of the transaction executed in the sandbox
+type ExecResult struct {
+	Height   uint64
+	Contract common.Address // The contract deployed or called
+	Result   *tvm.ExecuteResult
+	Logs     []*types.Log
+	GasUsed  uint64
+	Profile  *tvm.GasProfile // Set if the sandbox is profiling
+	Err      error
+}
+
+type sandboxSnapshot struct {
+	revision int
+	height   uint64
+	time     int64
+}
+
+// Sandbox executes the transactions on the local state, each of which in a new block.
+// The failed transactions are reverted like on the chain
+type Sandbox struct {
+	state     *account.AccountDB
+	chain     *SandboxChain
+	txCount   uint64
+	snapshots []sandboxSnapshot
+
+	Profile bool            // Attributes the gas of the transactions to the bridge operations
+	Limits  *tvm.CallLimits // Limits of the calls instead of the chain's if set
+}
+
+// NewSandbox creates the sandbox on the state and the chain
+func NewSandbox(state *account.AccountDB, chain *SandboxChain) *Sandbox {
+	return &Sandbox{state: state, chain: chain}
+}
+
+// newController creates the controller of the transaction with the profile and the limits of the sandbox
+func (s *Sandbox) newController(bh *types.BlockHeader, tx *Transaction, ret *ExecResult) *tvm.Controller {
+	controller := tvm.NewController(s.state, s.chain, bh, tx, 0, nil)
+	if s.Profile {
+		controller.Profile = tvm.NewGasProfile()
+		ret.Profile = controller.Profile
+	}
+	if s.Limits != nil {
+		controller.Limits = s.Limits
+	}
+	return controller
+}
+
+func (s *Sandbox) newTransaction(opType int8, source common.Address, target *common.Address, value uint64, data []byte) *Transaction {
+	s.txCount++
+	return &Transaction{
+		opType: opType,
+		source: &source,
+		target: target,
+		value:  value,
+		nonce:  s.state.GetNonce(source),
+		data:   data,
+		hash:   common.BytesToHash(common.Sha256(common.BytesCombine(sandboxBlockHash(s.chain.height).Bytes(), common.Uint64ToByte(s.txCount)))),
+	}
+}
+
+func (s *Sandbox) transfer(source, target common.Address, value uint64) error {
+	if value == 0 {
+		return nil
+	}
+	amount := new(big.Int).SetUint64(value)
+	if !s.state.CanTransfer(source, amount) {
+		return fmt.Errorf("balance not enough, address is %v", source.AddrPrefixString())
+	}
+	s.state.Transfer(source, target, amount)
+	return nil
+}
+
+// finish reverts the transaction to the snapshot if failed
+func (s *Sandbox) finish(ret *ExecResult, snapshot int, controller *tvm.Controller, txErr *types.TransactionError) *ExecResult {
+	ret.GasUsed = TransactionGasLimitMax - controller.GetGasLeft()
+	if txErr != nil {
+		ret.Err = fmt.Errorf(txErr.Message)
+	}
+	if ret.Err != nil {
+		s.state.RevertToSnapshot(snapshot)
+	}
+	return ret
+}
+
+// Deploy deploys the contract by the sender, with the value transferred to the contract
+func (s *Sandbox) Deploy(sender common.Address, name string, code string, value uint64) *ExecResult {
+	bh := s.chain.next()
+	nonce := s.state.GetNonce(sender)
+	contractAddress := common.BytesToAddress(common.Sha256(common.BytesCombine(sender[:], common.Uint64ToByte(nonce))))
+	ret := &ExecResult{Height: bh.Height, Contract: contractAddress}
+
+	contract := &tvm.Contract{ContractName: name, Code: code}
+	jsonBytes, err := json.Marshal(contract)
+	if err != nil {
+		ret.Err = err
+		return ret
+	}
+	tx := s.newTransaction(types.TransactionTypeContractCreate, sender, nil, value, jsonBytes)
+	s.state.SetNonce(sender, nonce+1)
+	if s.state.GetCodeHash(contractAddress) != (common.Hash{}) {
+		ret.Err = fmt.Errorf("contract address conflict")
+		return ret
+	}
+	snapshot := s.state.Snapshot()
+	s.state.CreateAccount(contractAddress)
+	s.state.SetCode(contractAddress, jsonBytes)
+	s.state.SetNonce(contractAddress, 1)
+	contract.ContractAddress = &contractAddress
+
+	controller := s.newController(bh, tx, ret)
+	if ret.Err = s.transfer(sender, contractAddress, value); ret.Err != nil {
+		return s.finish(ret, snapshot, controller, nil)
+	}
+	result, logs, txErr := controller.Deploy(contract)
+	ret.Result, ret.Logs = result, logs
+	return s.finish(ret, snapshot, controller, txErr)
+}
+
+// Call calls the contract by the sender, with the value transferred to the contract
+func (s *Sandbox) Call(sender common.Address, contractAddress common.Address, abiJSON string, value uint64) *ExecResult {
+	bh := s.chain.next()
+	tx := s.newTransaction(types.TransactionTypeContractCall, sender, &contractAddress, value, []byte(abiJSON))
+	ret := &ExecResult{Height: bh.Height, Contract: contractAddress}
+	controller := s.newController(bh, tx, ret)
+	if len(s.state.GetCode(contractAddress)) == 0 {
+		ret.Err = fmt.Errorf("contract %v not found", contractAddress.AddrPrefixString())
+		return ret
+	}
+
+	snapshot := s.state.Snapshot()
+	if ret.Err = s.transfer(sender, contractAddress, value); ret.Err != nil {
+		return s.finish(ret, snapshot, controller, nil)
+	}
+	contract := controller.LoadContract(contractAddress)
+	result, logs, txErr := controller.ExecuteAbiEval(&sender, contract, abiJSON)
+	ret.Result, ret.Logs = result, logs
+	if txErr == nil && result == nil {
+		ret.Err = fmt.Errorf("execute abi error")
+	}
+	return s.finish(ret, snapshot, controller, txErr)
+}
+
+// Transfer transfers the value between the accounts
+func (s *Sandbox) Transfer(source common.Address, target common.Address, value uint64) *ExecResult {
+	bh := s.chain.next()
+	ret := &ExecResult{Height: bh.Height, Contract: target}
+	ret.Err = s.transfer(source, target, value)
+	return ret
+}
+
+// Snapshot takes the snapshot of the state and the chain position, which is valid until
+// committed
+func (s *Sandbox) Snapshot() int {
+	s.snapshots = append(s.snapshots, sandboxSnapshot{revision: s.state.Snapshot(), height: s.chain.height, time: s.chain.time})
+	return len(s.snapshots) - 1
+}
+
+// Revert reverts the state and the chain position to the snapshot, the snapshots taken
+// after it are discarded
+func (s *Sandbox) Revert(id int) error {
+	if id < 0 || id >= len(s.snapshots) {
+		return fmt.Errorf("snapshot %v not found", id)
+	}
+	snapshot := s.snapshots[id]
+	s.state.RevertToSnapshot(snapshot.revision)
+	s.chain.height, s.chain.time = snapshot.height, snapshot.time
+	s.snapshots = s.snapshots[:id]
+	return nil
+}
+
+// Commit writes the state to the database and returns the root, the snapshots are discarded
+func (s *Sandbox) Commit() (common.Hash, error) {
+	s.snapshots = nil
+	root, err := s.state.Commit(false)
+	if err != nil {
+		return root, err
+	}
+	return root, s.state.Database().TrieDB().Commit(root, false)
+}
+
+// Balance returns the balance of the account
+func (s *Sandbox) Balance(addr common.Address) *big.Int {
+	return s.state.GetBalance(addr)
+}
+
+// Data returns the value of the contract data converted, nil if not found
+func (s *Sandbox) Data(addr common.Address, key string) interface{} {
+	return tvm.VmDataConvert(s.state.GetData(addr, []byte(key)))
+}
+
+// Height returns the height and the unix time of the top block
+func (s *Sandbox) Height() (uint64, int64) {
+	return s.chain.height, s.chain.time
+}
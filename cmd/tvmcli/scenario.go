@@ -0,0 +1,408 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/storage/account"
+	"github.com/darren0718/zvchain/storage/tasdb"
+	"github.com/darren0718/zvchain/tvm"
+	"gopkg.in/yaml.v2"
+)
+
+// Scenario is the contract test run on a fresh sandbox, with the default accounts funded.
+// The accounts are referred by the index of the default accounts or the address, and the
+// contracts by the alias given at deploy or the address
+type Scenario struct {
+	Name     string          `json:"name"`
+	Balance  uint64          `json:"balance"`  // Initial balance of the default accounts, 200 if not set
+	Height   uint64          `json:"height"`   // Height of the top block before the first step
+	Time     int64           `json:"time"`     // Unix seconds of the top block before the first step
+	Interval int64           `json:"interval"` // Seconds between the blocks
+	Steps    []*ScenarioStep `json:"steps"`
+}
+
+// ScenarioStep is one action of the scenario, checked against the expectations after run.
+// The actions are deploy, call, transfer, snapshot, revert, block and check
+type ScenarioStep struct {
+	Name     string          `json:"name"`
+	Action   string          `json:"action"`
+	Sender   string          `json:"sender"` // The first default account if not set
+	Value    uint64          `json:"value"`
+	Contract string          `json:"contract"` // Name of the contract deployed, or the contract called and checked
+	File     string          `json:"file"`     // Source of the contract deployed, relative to the scenario
+	As       string          `json:"as"`       // Alias of the contract deployed
+	Func     string          `json:"func"`
+	Args     []interface{}   `json:"args"`
+	To       string          `json:"to"`       // Target of the transfer
+	Snapshot string          `json:"snapshot"` // Name of the snapshot taken or reverted to
+	Height   uint64          `json:"height"`   // New height of the top block
+	Time     int64           `json:"time"`     // New unix seconds of the top block
+	Expect   *ScenarioExpect `json:"expect"`
+}
+
+// ScenarioExpect is the expected outcome of the step. The transaction is expected to
+// succeed unless Fail or Error set
+type ScenarioExpect struct {
+	Fail     bool                       `json:"fail"`
+	Error    string                     `json:"error"`  // Substring of the error expected
+	Result   json.RawMessage            `json:"result"` // Compared with the returned content, as is if a string
+	Events   []string                   `json:"events"` // Names of the events emitted in order
+	Storage  map[string]json.RawMessage `json:"storage"`
+	Balances map[string]uint64          `json:"balances"`
+}
+
+// LoadScenario loads the scenario from the json or yaml file
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".yaml" || ext == ".yml" {
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, err
+		}
+	}
+	scenario := &Scenario{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	decoder.UseNumber()
+	if err := decoder.Decode(scenario); err != nil {
+		return nil, fmt.Errorf("decode scenario %v error: %v", path, err)
+	}
+	return scenario, nil
+}
+
+func yamlToJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	v, err := jsonValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// jsonValue converts the yaml maps with the interface keys into the json objects
+func jsonValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			value, err := jsonValue(value)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(k)] = value
+		}
+		return m, nil
+	case []interface{}:
+		for i := range v {
+			value, err := jsonValue(v[i])
+			if err != nil {
+				return nil, err
+			}
+			v[i] = value
+		}
+	}
+	return v, nil
+}
+
+// canonicalJSON re-encodes the json with the sorted keys and the numbers kept as is
+func canonicalJSON(data []byte) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// ScenarioRunner runs the steps of the scenario on the sandbox
+type ScenarioRunner struct {
+	sandbox   *Sandbox
+	dir       string
+	contracts map[string]common.Address
+	snapshots map[string]int
+	out       io.Writer
+}
+
+// NewScenarioRunner creates the runner on a fresh in-memory sandbox. The contract sources
+// are relative to the dir
+func NewScenarioRunner(scenario *Scenario, dir string, out io.Writer) (*ScenarioRunner, error) {
+	db, err := tasdb.NewMemDatabase()
+	if err != nil {
+		return nil, err
+	}
+	state, err := account.NewAccountDB(common.Hash{}, account.NewDatabase(db))
+	if err != nil {
+		return nil, err
+	}
+	balance := scenario.Balance
+	if balance == 0 {
+		balance = 200
+	}
+	for _, addr := range DefaultAccounts {
+		state.SetBalance(common.StringToAddress(addr), new(big.Int).SetUint64(balance))
+	}
+	return &ScenarioRunner{
+		sandbox:   NewSandbox(state, NewSandboxChain(scenario.Height, scenario.Time, scenario.Interval)),
+		dir:       dir,
+		contracts: make(map[string]common.Address),
+		snapshots: make(map[string]int),
+		out:       out,
+	}, nil
+}
+
+// Run runs all the steps and returns the number of the steps failed
+func (r *ScenarioRunner) Run(scenario *Scenario) int {
+	failed := 0
+	for i, step := range scenario.Steps {
+		name := step.Name
+		if name == "" {
+			name = step.Action
+		}
+		if err := r.runStep(step); err != nil {
+			failed++
+			fmt.Fprintf(r.out, "FAIL %d %s: %v\n", i+1, name, err)
+		} else {
+			fmt.Fprintf(r.out, "ok   %d %s\n", i+1, name)
+		}
+	}
+	fmt.Fprintf(r.out, "%s: %d steps, %d failed\n", scenario.Name, len(scenario.Steps), failed)
+	return failed
+}
+
+// account resolves the account of the index of the default accounts, the alias of the
+// contract or the address
+func (r *ScenarioRunner) account(ref string) (common.Address, error) {
+	if ref == "" {
+		ref = "0"
+	}
+	if addr, ok := r.contracts[ref]; ok {
+		return addr, nil
+	}
+	if i, err := strconv.Atoi(ref); err == nil {
+		if i < 0 || i >= len(DefaultAccounts) {
+			return common.Address{}, fmt.Errorf("account index %v out of range", i)
+		}
+		return common.StringToAddress(DefaultAccounts[i]), nil
+	}
+	if !common.ValidateAddress(ref) {
+		return common.Address{}, fmt.Errorf("invalid account %v", ref)
+	}
+	return common.StringToAddress(ref), nil
+}
+
+func (r *ScenarioRunner) runStep(step *ScenarioStep) error {
+	var ret *ExecResult
+	switch step.Action {
+	case "deploy":
+		sender, err := r.account(step.Sender)
+		if err != nil {
+			return err
+		}
+		code, err := ioutil.ReadFile(filepath.Join(r.dir, step.File))
+		if err != nil {
+			return err
+		}
+		ret = r.sandbox.Deploy(sender, step.Contract, string(code), step.Value)
+		if ret.Err == nil && step.As != "" {
+			r.contracts[step.As] = ret.Contract
+		}
+	case "call":
+		sender, err := r.account(step.Sender)
+		if err != nil {
+			return err
+		}
+		contract, err := r.account(step.Contract)
+		if err != nil {
+			return err
+		}
+		args := step.Args
+		if args == nil {
+			args = make([]interface{}, 0)
+		}
+		abiJSON, err := json.Marshal(tvm.ABI{FuncName: step.Func, Args: args})
+		if err != nil {
+			return err
+		}
+		ret = r.sandbox.Call(sender, contract, string(abiJSON), step.Value)
+	case "transfer":
+		sender, err := r.account(step.Sender)
+		if err != nil {
+			return err
+		}
+		to, err := r.account(step.To)
+		if err != nil {
+			return err
+		}
+		ret = r.sandbox.Transfer(sender, to, step.Value)
+	case "snapshot":
+		r.snapshots[step.Snapshot] = r.sandbox.Snapshot()
+	case "revert":
+		id, ok := r.snapshots[step.Snapshot]
+		if !ok {
+			return fmt.Errorf("snapshot %v not found", step.Snapshot)
+		}
+		if err := r.sandbox.Revert(id); err != nil {
+			return err
+		}
+		for name, sid := range r.snapshots {
+			if sid >= id {
+				delete(r.snapshots, name)
+			}
+		}
+	case "block":
+		if step.Height > 0 {
+			r.sandbox.chain.height = step.Height
+		}
+		if step.Time > 0 {
+			r.sandbox.chain.time = step.Time
+		}
+	case "check":
+	default:
+		return fmt.Errorf("unknown action %v", step.Action)
+	}
+	if ret != nil {
+		printResult(r.out, r.sandbox, ret)
+	}
+	return r.check(step, ret)
+}
+
+func (r *ScenarioRunner) check(step *ScenarioStep, ret *ExecResult) error {
+	expect := step.Expect
+	if expect == nil {
+		expect = &ScenarioExpect{}
+	}
+	if ret != nil {
+		if err := checkExecResult(expect, ret); err != nil {
+			return err
+		}
+	}
+	if len(expect.Storage) > 0 {
+		contract, err := r.account(step.Contract)
+		if err != nil {
+			return err
+		}
+		for key, want := range expect.Storage {
+			if err := checkStorage(key, want, r.sandbox.Data(contract, key)); err != nil {
+				return err
+			}
+		}
+	}
+	for ref, want := range expect.Balances {
+		addr, err := r.account(ref)
+		if err != nil {
+			return err
+		}
+		if got := r.sandbox.Balance(addr); got.Cmp(new(big.Int).SetUint64(want)) != 0 {
+			return fmt.Errorf("balance of %v: expected %v, got %v", ref, want, got)
+		}
+	}
+	return nil
+}
+
+func checkExecResult(expect *ScenarioExpect, ret *ExecResult) error {
+	if ret.Err != nil {
+		if !expect.Fail && expect.Error == "" {
+			return fmt.Errorf("unexpected error: %v", ret.Err)
+		}
+		if !strings.Contains(ret.Err.Error(), expect.Error) {
+			return fmt.Errorf("expected error containing %q, got %v", expect.Error, ret.Err)
+		}
+		return nil
+	}
+	if expect.Fail || expect.Error != "" {
+		return fmt.Errorf("expected failure, but succeeded")
+	}
+	if len(expect.Result) > 0 {
+		var want string
+		if err := json.Unmarshal(expect.Result, &want); err != nil {
+			if want, err = canonicalJSON(expect.Result); err != nil {
+				return err
+			}
+		}
+		got := ""
+		if ret.Result != nil {
+			got = ret.Result.Content
+		}
+		if got != want {
+			return fmt.Errorf("result: expected %v, got %v", want, got)
+		}
+	}
+	if expect.Events != nil {
+		if len(ret.Logs) != len(expect.Events) {
+			return fmt.Errorf("expected %v events, got %v", len(expect.Events), len(ret.Logs))
+		}
+		for i, name := range expect.Events {
+			if ret.Logs[i].Topic != tvm.EventTopic(name) {
+				return fmt.Errorf("event %v: expected %v", i, name)
+			}
+		}
+	}
+	return nil
+}
+
+func checkStorage(key string, want json.RawMessage, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	got, err := canonicalJSON(data)
+	if err != nil {
+		return err
+	}
+	expected, err := canonicalJSON(want)
+	if err != nil {
+		return err
+	}
+	if got != expected {
+		return fmt.Errorf("storage %v: expected %v, got %v", key, expected, got)
+	}
+	return nil
+}
+
+// RunScenario runs the scenario file and returns the number of the steps failed
+func RunScenario(path string, out io.Writer) (int, error) {
+	scenario, err := LoadScenario(path)
+	if err != nil {
+		return 0, err
+	}
+	if scenario.Name == "" {
+		scenario.Name = filepath.Base(path)
+	}
+	runner, err := NewScenarioRunner(scenario, filepath.Dir(path), out)
+	if err != nil {
+		return 0, err
+	}
+	return runner.Run(scenario), nil
+}
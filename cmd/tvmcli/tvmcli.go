@@ -17,12 +17,13 @@ package main
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
-	"github.com/darren0718/zvchain/middleware/types"
+	"io"
 	"math/big"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/darren0718/zvchain/common"
@@ -35,46 +36,6 @@ const (
 	TransactionGasLimitMax = 500000
 )
 
-type Transaction struct {
-	types.TxMessage
-}
-
-func (Transaction) GetGasLimit() uint64 { return TransactionGasLimitMax }
-func (Transaction) GetValue() uint64    { return 0 }
-func (Transaction) Operator() *common.Address {
-	address := common.StringToAddress("zvc2f067dba80c53cfdd956f86a61dd3aaf5abbba5609572636719f054247d8103")
-	return &address
-}
-func (Transaction) OpTarget() *common.Address {
-	address := common.StringToAddress("zvc2f067dba80c53cfdd956f86a61dd3aaf5abbba5609572636719f054247d8103")
-	return &address
-}
-func (Transaction) Payload() []byte      { return nil }
-func (Transaction) GetHash() common.Hash { return common.Hash{} }
-
-type FakeChainReader struct {
-}
-
-func (FakeChainReader) Height() uint64 {
-	return 0
-}
-
-func (FakeChainReader) QueryTopBlock() *types.BlockHeader {
-	return &types.BlockHeader{}
-}
-func (FakeChainReader) QueryBlockHeaderByHash(hash common.Hash) *types.BlockHeader {
-	return &types.BlockHeader{}
-}
-func (FakeChainReader) QueryBlockHeaderByHeight(height uint64) *types.BlockHeader {
-	return &types.BlockHeader{}
-}
-func (FakeChainReader) HasBlock(hash common.Hash) bool {
-	return true
-}
-func (FakeChainReader) HasHeight(height uint64) bool {
-	return true
-}
-
 func Exists(path string) bool {
 	_, err := os.Stat(path) //os.Stat获取文件信息
 	if err != nil {
@@ -87,21 +48,27 @@ func Exists(path string) bool {
 }
 
 var (
-	DefaultAccounts = [...]string{"0x6c63b15aac9b94927681f5fb1a7343888dece14e3160b3633baa9e0d540228cd",
-		"0x3eed3f4a15d238dc2ab658dcaa069a7d072437c9c86e1605ce74cd9f4730bbf2",
-		"0x36ae29871aed1bc21e708c4e2f5ff7c03218f5ffcd3eeae31d94a2985143abd7",
-		"0xf798010011a0f17510ce4fdea9b3e7b458392b4bb8205ead3eb818609e93746c",
-		"0xcd54640ff11b6ffe601566008872c87a4f3ec01a2890404b6ce30905ee3b2137"}
+	DefaultAccounts = [...]string{"zv6c63b15aac9b94927681f5fb1a7343888dece14e3160b3633baa9e0d540228cd",
+		"zv3eed3f4a15d238dc2ab658dcaa069a7d072437c9c86e1605ce74cd9f4730bbf2",
+		"zv36ae29871aed1bc21e708c4e2f5ff7c03218f5ffcd3eeae31d94a2985143abd7",
+		"zvf798010011a0f17510ce4fdea9b3e7b458392b4bb8205ead3eb818609e93746c",
+		"zvcd54640ff11b6ffe601566008872c87a4f3ec01a2890404b6ce30905ee3b2137"}
 )
 
 type TvmCli struct {
 	settings common.ConfManager
 	db       *tasdb.LDBDatabase
 	database account.AccountDatabase
+
+	sender  common.Address  // Sender of the transactions, the first default account by default
+	value   uint64          // Value transferred to the contract by the transactions
+	profile bool            // Prints the gas profile of the transactions
+	limits  *tvm.CallLimits // Limits of the calls instead of the chain's if set
 }
 
 func NewTvmCli() *TvmCli {
 	tvmCli := new(TvmCli)
+	tvmCli.sender = common.StringToAddress(DefaultAccounts[0])
 	tvmCli.init()
 	return tvmCli
 }
@@ -123,14 +90,6 @@ func (t *TvmCli) init() {
 
 	if Exists(currentPath + "/settings.ini") {
 		t.settings = common.NewConfINIManager(currentPath + "/settings.ini")
-		//stateHash := settings.GetString("root", "StateHash", "")
-		//state, error := account.NewAccountDB(common.HexToHash(stateHash), database)
-		//if error != nil {
-		//	fmt.Println(error)
-		//	return
-		//}
-		//fmt.Println(stateHash)
-		//fmt.Println(state.GetBalance(common.StringToAddress(defaultAccounts[0])))
 	} else {
 		t.settings = common.NewConfINIManager(currentPath + "/settings.ini")
 		state, _ := account.NewAccountDB(common.Hash{}, t.database)
@@ -150,89 +109,211 @@ func (t *TvmCli) init() {
 	}
 }
 
-func (t *TvmCli) Deploy(contractName string, contractCode string) (string, error) {
-	stateHash := t.settings.GetString("root", "StateHash", "")
-	state, _ := account.NewAccountDB(common.HexToHash(stateHash), t.database)
-	transaction := Transaction{}
-	controller := tvm.NewController(state, FakeChainReader{}, &types.BlockHeader{}, transaction, 0, nil)
-
-	nonce := state.GetNonce(*transaction.Operator())
-	contractAddress := common.BytesToAddress(common.Sha256(common.BytesCombine(transaction.Operator()[:], common.Uint64ToByte(nonce))))
-	fmt.Println("contractAddress: ", contractAddress.AddrPrefixString())
-	state.SetNonce(*transaction.Operator(), nonce+1)
+// parseAccount parses the index of the default accounts or the address
+func parseAccount(ref string) (common.Address, error) {
+	if i, err := strconv.Atoi(ref); err == nil {
+		if i < 0 || i >= len(DefaultAccounts) {
+			return common.Address{}, fmt.Errorf("account index %v out of range", i)
+		}
+		return common.StringToAddress(DefaultAccounts[i]), nil
+	}
+	if !common.ValidateAddress(ref) {
+		return common.Address{}, fmt.Errorf("invalid account %v", ref)
+	}
+	return common.StringToAddress(ref), nil
+}
 
-	contract := tvm.Contract{
-		ContractName: contractName,
-		Code:         contractCode,
-		//ContractAddress: &contractAddress,
+// SetSender sets the sender by the index of the default accounts or the address
+func (t *TvmCli) SetSender(ref string) error {
+	sender, err := parseAccount(ref)
+	if err != nil {
+		return err
 	}
+	t.sender = sender
+	return nil
+}
 
-	jsonBytes, errMarsh := json.Marshal(contract)
-	if errMarsh != nil {
-		fmt.Println(errMarsh)
-		return "", errMarsh
+// SetValue sets the value transferred to the contract by the transactions
+func (t *TvmCli) SetValue(value uint64) {
+	t.value = value
+}
+
+// SetProfile sets whether to profile the gas of the transactions
+func (t *TvmCli) SetProfile(profile bool) {
+	t.profile = profile
+}
+
+// SetLimits sets the limits of the calls instead of the chain's
+func (t *TvmCli) SetLimits(limits *tvm.CallLimits) {
+	t.limits = limits
+}
+
+func (t *TvmCli) getUint64(section string, key string, defaultValue uint64) uint64 {
+	v, err := strconv.ParseUint(t.settings.GetString(section, key, ""), 10, 64)
+	if err != nil {
+		return defaultValue
 	}
-	state.CreateAccount(contractAddress)
-	state.SetCode(contractAddress, jsonBytes)
+	return v
+}
 
-	contract.ContractAddress = &contractAddress
-	result, _, _ := controller.Deploy(&contract)
-	if result.ResultType == 4 /*C.RETURN_TYPE_EXCEPTION*/ {
-		return "", errors.New(result.Content)
+// openSandbox opens the sandbox on the state and the block saved
+func (t *TvmCli) openSandbox() (*Sandbox, error) {
+	stateHash := t.settings.GetString("root", "StateHash", "")
+	state, err := account.NewAccountDB(common.HexToHash(stateHash), t.database)
+	if err != nil {
+		return nil, err
 	}
-	fmt.Println("gas: ", TransactionGasLimitMax-controller.VM.Gas())
+	chain := NewSandboxChain(t.getUint64("block", "Height", 0), int64(t.getUint64("block", "Time", 0)), int64(t.getUint64("block", "Interval", defaultBlockInterval)))
+	s := NewSandbox(state, chain)
+	s.Profile, s.Limits = t.profile, t.limits
+	return s, nil
+}
 
-	hash, error := state.Commit(false)
-	t.database.TrieDB().Commit(hash, false)
-	if error != nil {
-		fmt.Println(error)
+// commit saves the state and the block of the sandbox
+func (t *TvmCli) commit(s *Sandbox) {
+	hash, err := s.Commit()
+	if err != nil {
+		fmt.Println(err)
+		return
 	}
+	height, unixTime := s.Height()
 	t.settings.SetString("root", "StateHash", hash.Hex())
+	t.settings.SetString("block", "Height", strconv.FormatUint(height, 10))
+	t.settings.SetString("block", "Time", strconv.FormatInt(unixTime, 10))
 	fmt.Println(hash.Hex())
-	return contractAddress.AddrPrefixString(), nil
+}
+
+// printResult prints the result of the transaction with the logs, decoded if the contract
+// has the abi stored
+func printResult(w io.Writer, s *Sandbox, ret *ExecResult) {
+	fmt.Fprintln(w, "block: ", ret.Height)
+	fmt.Fprintln(w, "gas: ", ret.GasUsed)
+	fmt.Fprintf(w, "%d logs: \n", len(ret.Logs))
+	for _, log := range ret.Logs {
+		topics := make([]string, 0, len(log.Topics))
+		for _, topic := range log.Topics {
+			topics = append(topics, topic.Hex())
+		}
+		fmt.Fprintf(w, "		address: %s, topic: %s, topics: %v, data: %s\n", log.Address.AddrPrefixString(), log.Topic.Hex(), topics, string(log.Data))
+		if abi := tvm.LoadABI(s.state, log.Address); abi != nil {
+			decoded, _ := json.Marshal(abi.DecodeLog(log))
+			fmt.Fprintf(w, "		decoded: %s\n", decoded)
+		}
+	}
+	if ret.Profile != nil {
+		printProfile(w, ret.Profile)
+	}
+	if ret.Err != nil {
+		fmt.Fprintln(w, "error: ", ret.Err)
+	} else if ret.Result != nil {
+		fmt.Fprintln(w, "executeResult: ", ret.Result.Content)
+	}
+}
+
+// printProfile prints the gas attributed to the bridge operations
+func printProfile(w io.Writer, profile *tvm.GasProfile) {
+	fmt.Fprintf(w, "profile: total %d, execution %d\n", profile.Total, profile.Execution)
+	ops := make([]string, 0, len(profile.Ops))
+	for op := range profile.Ops {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	for _, op := range ops {
+		prof := profile.Ops[op]
+		fmt.Fprintf(w, "		%s: count %d, bytes %d, gas %d\n", op, prof.Count, prof.Bytes, prof.Gas)
+	}
+}
+
+func (t *TvmCli) Deploy(contractName string, contractCode string) (string, error) {
+	s, err := t.openSandbox()
+	if err != nil {
+		return "", err
+	}
+	ret := s.Deploy(t.sender, contractName, contractCode, t.value)
+	fmt.Println("contractAddress: ", ret.Contract.AddrPrefixString())
+	printResult(os.Stdout, s, ret)
+	t.commit(s)
+	if ret.Err != nil {
+		return "", ret.Err
+	}
+	return ret.Contract.AddrPrefixString(), nil
 }
 
 func (t *TvmCli) Call(contractAddress string, abiJSON string) {
-	stateHash := t.settings.GetString("root", "StateHash", "")
-	state, _ := account.NewAccountDB(common.HexToHash(stateHash), t.database)
+	s, err := t.openSandbox()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	ret := s.Call(t.sender, common.StringToAddress(contractAddress), abiJSON, t.value)
+	printResult(os.Stdout, s, ret)
+	t.commit(s)
+}
 
-	controller := tvm.NewController(state, FakeChainReader{}, &types.BlockHeader{}, Transaction{}, 0, nil)
-
-	//abi := tvm.ABI{}
-	//abiJsonError := json.Unmarshal([]byte(abiJSON), &abi)
-	//if abiJsonError != nil{
-	//	fmt.Println(abiJSON, " json.Unmarshal failed ", abiJsonError)
-	//	return
-	//}
-	_contractAddress := common.StringToAddress(contractAddress)
-	contract := tvm.LoadContract(_contractAddress)
-	//fmt.Println(contract.Code)
-	sender := common.StringToAddress(DefaultAccounts[0])
-	executeResult, logs, transactionError := controller.ExecuteAbiEval(&sender, contract, abiJSON)
-	if transactionError != nil {
-		fmt.Println(transactionError.Message)
+// Transfer transfers the value from the sender to the account
+func (t *TvmCli) Transfer(to string, value uint64) error {
+	target, err := parseAccount(to)
+	if err != nil {
+		return err
 	}
-	fmt.Println("gas: ", TransactionGasLimitMax-controller.VM.Gas())
-	fmt.Printf("%d logs: \n", len(logs))
-	for _, log := range logs {
-		fmt.Printf("		string: %s, data: %s\n", log.String(), string(log.Data))
+	s, err := t.openSandbox()
+	if err != nil {
+		return err
 	}
-	if executeResult == nil {
-		fmt.Println("ExecuteAbiEval error")
+	ret := s.Transfer(t.sender, target, value)
+	printResult(os.Stdout, s, ret)
+	t.commit(s)
+	return ret.Err
+}
+
+// Accounts prints the default accounts with the balances
+func (t *TvmCli) Accounts() {
+	s, err := t.openSandbox()
+	if err != nil {
+		fmt.Println(err)
 		return
-	} else if executeResult.ResultType == 4 /*C.RETURN_TYPE_EXCEPTION*/ {
-		fmt.Println("error code: ", executeResult.ErrorCode, " error info: ", executeResult.Content)
-	} else {
-		fmt.Println("executeResult: ", executeResult.Content)
 	}
+	for i, addr := range DefaultAccounts {
+		fmt.Printf("%d %s %v\n", i, addr, s.Balance(common.StringToAddress(addr)))
+	}
+}
 
-	hash, error := state.Commit(false)
-	t.database.TrieDB().Commit(hash, false)
-	if error != nil {
-		fmt.Println(error)
+// SetBlock sets the height and the unix time of the top block, and the seconds between
+// the blocks. The zero values are left unchanged
+func (t *TvmCli) SetBlock(height uint64, unixTime int64, interval int64) {
+	if height > 0 {
+		t.settings.SetString("block", "Height", strconv.FormatUint(height, 10))
 	}
-	t.settings.SetString("root", "StateHash", hash.Hex())
-	fmt.Println(hash.Hex())
+	if unixTime > 0 {
+		t.settings.SetString("block", "Time", strconv.FormatInt(unixTime, 10))
+	}
+	if interval > 0 {
+		t.settings.SetString("block", "Interval", strconv.FormatInt(interval, 10))
+	}
+	fmt.Printf("height: %v, time: %v, interval: %v\n", t.getUint64("block", "Height", 0), t.getUint64("block", "Time", 0), t.getUint64("block", "Interval", defaultBlockInterval))
+}
+
+// SaveSnapshot saves the state root and the block under the name. The revisions of
+// AccountDB.Snapshot don't survive the commit, so the snapshots across the commands are
+// kept by the roots, while the test scenarios use the revisions
+func (t *TvmCli) SaveSnapshot(name string) {
+	snapshot := fmt.Sprintf("%s,%s,%s", t.settings.GetString("root", "StateHash", ""),
+		t.settings.GetString("block", "Height", "0"), t.settings.GetString("block", "Time", "0"))
+	t.settings.SetString("snapshot", name, snapshot)
+	fmt.Println(name, snapshot)
+}
+
+// RevertSnapshot reverts the state root and the block to the snapshot saved
+func (t *TvmCli) RevertSnapshot(name string) error {
+	parts := strings.Split(t.settings.GetString("snapshot", name, ""), ",")
+	if len(parts) != 3 {
+		return fmt.Errorf("snapshot %v not found", name)
+	}
+	t.settings.SetString("root", "StateHash", parts[0])
+	t.settings.SetString("block", "Height", parts[1])
+	t.settings.SetString("block", "Time", parts[2])
+	fmt.Println(name, parts[0])
+	return nil
 }
 
 func (t *TvmCli) ExportAbi(contractName string, contractCode string) {
@@ -27,8 +27,11 @@ import (
 	"math/big"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/darren0718/zvchain/tvm"
 )
 
 func _deployContract(contractName string, filePath string) string {
@@ -406,6 +409,117 @@ func TestTvmCli_TestStorage(t *testing.T) {
 	_ = _deployContract("Token", "test_storage.py")
 }
 
+func TestSandbox_SnapshotRevert(t *testing.T) {
+	runner, err := NewScenarioRunner(&Scenario{Height: 10, Time: 1000}, ".", os.Stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := runner.sandbox
+	from, to := common.StringToAddress(DefaultAccounts[0]), common.StringToAddress(DefaultAccounts[1])
+
+	id := s.Snapshot()
+	if ret := s.Transfer(from, to, 50); ret.Err != nil || ret.Height != 11 {
+		t.Fatalf("transfer error %v at %v", ret.Err, ret.Height)
+	}
+	if s.Balance(to).Int64() != 250 {
+		t.Fatalf("balance should be 250, got %v", s.Balance(to))
+	}
+	if ret := s.Transfer(from, to, 1000); ret.Err == nil {
+		t.Fatalf("transfer more than the balance should fail")
+	}
+	if err := s.Revert(id); err != nil {
+		t.Fatal(err)
+	}
+	if height, unixTime := s.Height(); height != 10 || unixTime != 1000 {
+		t.Fatalf("block should be reverted, got %v %v", height, unixTime)
+	}
+	if s.Balance(from).Int64() != 200 || s.Balance(to).Int64() != 200 {
+		t.Fatalf("balances should be reverted, got %v %v", s.Balance(from), s.Balance(to))
+	}
+	if err := s.Revert(id); err == nil {
+		t.Fatalf("snapshot reverted shouldn't be reverted again")
+	}
+}
+
+func TestTvmCli_Scenario(t *testing.T) {
+	failed, err := RunScenario("token_scenario.yaml", os.Stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed > 0 {
+		t.Fatalf("%v steps failed", failed)
+	}
+}
+
 func init() {
 	params.InitChainConfig(1)
 }
+
+func TestSandbox_ProfileAndLimits(t *testing.T) {
+	runner, err := NewScenarioRunner(&Scenario{Height: 10, Time: 1000}, ".", os.Stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := runner.sandbox
+	code, err := ioutil.ReadFile("erc20.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := common.StringToAddress(DefaultAccounts[0])
+
+	s.Profile = true
+	ret := s.Deploy(sender, "Token", string(code), 0)
+	if ret.Err != nil || ret.Profile == nil {
+		t.Fatalf("deploy error %v", ret.Err)
+	}
+	if w := ret.Profile.Ops[tvm.OpStorageWrite]; w == nil || w.Count == 0 || w.Bytes == 0 {
+		t.Fatalf("storage writes should be profiled %+v", w)
+	}
+	if ret.Profile.Total != ret.GasUsed {
+		t.Fatalf("profile total %v should be the gas used %v", ret.Profile.Total, ret.GasUsed)
+	}
+
+	s.Limits = &tvm.CallLimits{MaxStorageBytes: 8}
+	if ret := s.Deploy(sender, "Token", string(code), 0); ret.Err == nil || !strings.Contains(ret.Err.Error(), "exceeds the limit") {
+		t.Fatalf("storage limit should be exceeded, got %v", ret.Err)
+	}
+}
+
+func TestSandbox_DeployAddressConflict(t *testing.T) {
+	runner, err := NewScenarioRunner(&Scenario{Height: 10, Time: 1000}, ".", os.Stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := runner.sandbox
+	code, err := ioutil.ReadFile("erc20.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := common.StringToAddress(DefaultAccounts[0])
+	next := func() common.Address {
+		return common.BytesToAddress(common.Sha256(common.BytesCombine(sender[:], common.Uint64ToByte(s.state.GetNonce(sender)))))
+	}
+
+	deployed := next()
+	if ret := s.Deploy(sender, "Token", string(code), 0); ret.Err != nil || ret.Contract != deployed {
+		t.Fatalf("deploy error %v", ret.Err)
+	}
+	if nonce := s.state.GetNonce(deployed); nonce != 1 {
+		t.Fatalf("nonce of the contract should be 1 as on chain, got %v", nonce)
+	}
+
+	// The existing account conflicts as on chain, even without code
+	funded := next()
+	if ret := s.Transfer(common.StringToAddress(DefaultAccounts[1]), funded, 10); ret.Err != nil {
+		t.Fatal(ret.Err)
+	}
+	if ret := s.Deploy(sender, "Token", string(code), 0); ret.Err == nil || !strings.Contains(ret.Err.Error(), "contract address conflict") {
+		t.Fatalf("deploy to the funded address should conflict, got %v", ret.Err)
+	}
+
+	taken := next()
+	s.state.SetCode(taken, []byte("code"))
+	if ret := s.Deploy(sender, "Token", string(code), 0); ret.Err == nil || !strings.Contains(ret.Err.Error(), "contract address conflict") {
+		t.Fatalf("deploy to the address with code should conflict, got %v", ret.Err)
+	}
+}
@@ -22,3 +22,6 @@ const ConsensusVersion = 1
 const ChainDataVersion = 1
 
 const ProtocolVersion = 1
+
+// MinProtocolVersion is the lowest protocol version of the peers still supported
+const MinProtocolVersion = 1
@@ -36,6 +36,7 @@ type createChecker struct {
 	ctx         *createContext
 	storeReader types.GroupStoreReader
 	minerReader minerReader
+	groupReader livedGroupReader
 	stat        *createStat
 	lock        sync.RWMutex
 }
@@ -232,22 +233,17 @@ func (checker *createChecker) shouldFreeze(rang *rRange, receivedNum int, requir
 	if float32(receivedNum)/float32(requiredNum) > doFreezeReceivedRate {
 		return true
 	}
-	dropRate := checker.calculateDropRate(rang.begin, rang.end)
+	if rang.end < rang.begin {
+		return true
+	}
+	realCount := checker.chain.CountBlocksInRange(rang.begin, rang.end)
+	dropRate := 1 - float32(realCount)/float32(rang.end-rang.begin+1)
 	if dropRate > noFreezeDropRate {
 		return false
 	}
 	return true
 }
 
-func (checker *createChecker) calculateDropRate(startHeight uint64, endHeight uint64) float32 {
-	if endHeight < startHeight {
-		return 0
-	}
-	realCount := checker.chain.CountBlocksInRange(startHeight, endHeight)
-	rate := float32(realCount) / float32(endHeight-startHeight+1)
-	return 1 - rate
-}
-
 func (checker *createChecker) shouldCreateGroup() bool {
 	return checker.ctx != nil && checker.ctx.cands.size() > 0
 }
@@ -0,0 +1,210 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package group
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/consensus/groupsig"
+	"github.com/darren0718/zvchain/consensus/model"
+)
+
+// Rounds of the group-create routine reported by the forecast
+const (
+	RoundNone        = "none"
+	RoundEncPiece    = "encrypted piece"
+	RoundMpk         = "mpk"
+	RoundOriginPiece = "origin piece"
+	RoundEnd         = "end"
+)
+
+// Projections of the current group-create routine
+const (
+	ProjectionIdle    = "idle"
+	ProjectionPending = "pending"
+	ProjectionSuccess = "success"
+	ProjectionFail    = "fail"
+)
+
+// CreateForecast is a dry-run of the group-create routine of the current era based on the packets received so far
+type CreateForecast struct {
+	Height     uint64
+	SeedHeight uint64
+	Seed       common.Hash
+	Round      string
+
+	CandidateCount    int
+	CandidateRequired int
+	CandidateEnough   bool
+
+	PieceReceived int
+	PieceRequired int
+	PieceEnough   bool
+
+	MpkReceived int
+	MpkRequired int
+	MpkEnough   bool
+
+	OriginPieceRequired bool
+	OriginPieceReceived int
+
+	// Candidates who have not sent the packet of each round yet
+	MissingPiece       []groupsig.ID
+	MissingMpk         []groupsig.ID
+	MissingOriginPiece []groupsig.ID
+
+	// Miners projected to be frozen if nothing else is received
+	FreezeList []groupsig.ID
+
+	Projection string
+	Reason     string
+}
+
+func (checker *createChecker) currentRound(era *era, h uint64) string {
+	switch {
+	case era.encPieceRange.inRange(h):
+		return RoundEncPiece
+	case era.mpkRange.inRange(h):
+		return RoundMpk
+	case era.oriPieceRange.inRange(h):
+		return RoundOriginPiece
+	case h >= era.endRange.begin:
+		return RoundEnd
+	}
+	return RoundNone
+}
+
+// elapsedRange returns the part of the given round before the given height, or nil if the round not started yet
+func elapsedRange(r *rRange, h uint64) *rRange {
+	if h < r.begin {
+		return nil
+	}
+	end := r.end
+	if h < end {
+		end = h
+	}
+	return &rRange{begin: r.begin, end: end}
+}
+
+// Forecast reports whether the group creation of the current era will succeed, based on the candidates,
+// the packets received so far and the freeze rules applied in CheckGroupCreateResult
+func (checker *createChecker) Forecast() (*CreateForecast, error) {
+	checker.lock.RLock()
+	defer checker.lock.RUnlock()
+
+	ctx := checker.ctx
+	if ctx == nil {
+		return nil, fmt.Errorf("create context not initialized")
+	}
+	era := ctx.era
+	h := checker.chain.Height()
+	cands := ctx.cands
+
+	fc := &CreateForecast{
+		Height:             h,
+		SeedHeight:         era.seedHeight,
+		Seed:               era.Seed(),
+		Round:              checker.currentRound(era, h),
+		CandidateCount:     cands.size(),
+		CandidateRequired:  model.Param.GroupMemberMin,
+		CandidateEnough:    candidateEnough(cands.size()),
+		PieceRequired:      int(math.Ceil(float64(cands.size()) * recvPieceMinRatio)),
+		MpkRequired:        cands.threshold(),
+		MissingPiece:       make([]groupsig.ID, 0),
+		MissingMpk:         make([]groupsig.ID, 0),
+		MissingOriginPiece: make([]groupsig.ID, 0),
+		FreezeList:         make([]groupsig.ID, 0),
+	}
+	if !era.seedExist() {
+		fc.Projection = ProjectionIdle
+		fc.Reason = fmt.Sprintf("seed not exists:%v", era.seedHeight)
+		return fc, nil
+	}
+	if !fc.CandidateEnough {
+		fc.Projection = ProjectionIdle
+		fc.Reason = fmt.Sprintf("not enough candidates:%v, required:%v", fc.CandidateCount, fc.CandidateRequired)
+		return fc, nil
+	}
+
+	piecePkt, err := checker.storeReader.GetEncryptedPiecePackets(era)
+	if err != nil {
+		return nil, fmt.Errorf("get encrypted piece error:%v", err)
+	}
+	mpkPkt, err := checker.storeReader.GetMpkPackets(era)
+	if err != nil {
+		return nil, fmt.Errorf("get mpks error:%v", err)
+	}
+	fc.PieceReceived = len(piecePkt)
+	fc.PieceEnough = pieceEnough(len(piecePkt), cands.size())
+	fc.MpkReceived = len(mpkPkt)
+	fc.MpkEnough = len(mpkPkt) >= fc.MpkRequired
+
+	for _, mem := range cands {
+		if ok, _ := findSender(piecePkt, mem.ID.Serialize()); !ok {
+			fc.MissingPiece = append(fc.MissingPiece, mem.ID)
+		}
+	}
+	for _, pkt := range piecePkt {
+		if ok, _ := findSender(mpkPkt, pkt.Sender()); !ok {
+			fc.MissingMpk = append(fc.MissingMpk, groupsig.DeserializeID(pkt.Sender()))
+		}
+	}
+
+	fc.OriginPieceRequired = checker.storeReader.IsOriginPieceRequired(era)
+	if fc.OriginPieceRequired {
+		oriPkt, err := checker.storeReader.GetOriginPiecePackets(era)
+		if err != nil {
+			return nil, fmt.Errorf("get origin piece error:%v", err)
+		}
+		fc.OriginPieceReceived = len(oriPkt)
+		for _, mpk := range mpkPkt {
+			if ok, _ := findSender(oriPkt, mpk.Sender()); !ok {
+				fc.MissingOriginPiece = append(fc.MissingOriginPiece, groupsig.DeserializeID(mpk.Sender()))
+			}
+		}
+	}
+
+	// Apply the same freeze rules as CheckGroupCreateResult on the elapsed part of each round
+	if r := elapsedRange(era.encPieceRange, h); r != nil && checker.shouldFreeze(r, len(piecePkt), cands.size()) {
+		fc.FreezeList = append(fc.FreezeList, fc.MissingPiece...)
+	}
+	if fc.PieceEnough {
+		if r := elapsedRange(era.mpkRange, h); r != nil && checker.shouldFreeze(r, len(mpkPkt), len(piecePkt)) {
+			fc.FreezeList = append(fc.FreezeList, fc.MissingMpk...)
+		}
+	}
+
+	// Not enough packets is not a failure as long as the round is still in progress
+	switch {
+	case !fc.PieceEnough:
+		fc.Projection = ProjectionFail
+		if h <= era.encPieceRange.end {
+			fc.Projection = ProjectionPending
+		}
+		fc.Reason = fmt.Sprintf("receives not enough share piece:%v, required:%v", fc.PieceReceived, fc.PieceRequired)
+	case !fc.MpkEnough:
+		fc.Projection = ProjectionFail
+		if h <= era.mpkRange.end {
+			fc.Projection = ProjectionPending
+		}
+		fc.Reason = fmt.Sprintf("receives not enough mpk:%v, threshold:%v", fc.MpkReceived, fc.MpkRequired)
+	default:
+		fc.Projection = ProjectionSuccess
+	}
+	return fc, nil
+}
@@ -0,0 +1,158 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package group
+
+import (
+	"testing"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/consensus/model"
+	"github.com/darren0718/zvchain/middleware/types"
+)
+
+type forecastChain struct {
+	types.BlockChain
+	height uint64
+}
+
+func (c *forecastChain) Height() uint64 {
+	return c.height
+}
+
+// No block dropped
+func (c *forecastChain) CountBlocksInRange(startHeight uint64, endHeight uint64) uint64 {
+	return endHeight - startHeight + 1
+}
+
+type forecastStore struct {
+	pieces []types.EncryptedSharePiecePacket
+	mpks   []types.MpkPacket
+}
+
+func (s *forecastStore) GetEncryptedPiecePackets(seed types.SeedI) ([]types.EncryptedSharePiecePacket, error) {
+	return s.pieces, nil
+}
+
+func (s *forecastStore) HasSentEncryptedPiecePacket(sender []byte, seed types.SeedI) bool {
+	ok, _ := findSender(s.pieces, sender)
+	return ok
+}
+
+func (s *forecastStore) HasSentMpkPacket(sender []byte, seed types.SeedI) bool {
+	ok, _ := findSender(s.mpks, sender)
+	return ok
+}
+
+func (s *forecastStore) GetMpkPackets(seed types.SeedI) ([]types.MpkPacket, error) {
+	return s.mpks, nil
+}
+
+func (s *forecastStore) IsOriginPieceRequired(seed types.SeedI) bool {
+	return false
+}
+
+func (s *forecastStore) GetOriginPiecePackets(seed types.SeedI) ([]types.OriginSharePiecePacket, error) {
+	return nil, nil
+}
+
+func (s *forecastStore) HasSentOriginPiecePacket(sender []byte, seed types.SeedI) bool {
+	return false
+}
+
+// newForecastChecker returns the checker for test and the function restoring the modified params
+func newForecastChecker(candNum int, pieceNum int, mpkNum int, height uint64) (*createChecker, func()) {
+	memberMin := model.Param.GroupMemberMin
+	model.Param.GroupMemberMin = 10
+	cands := genRandomMiners(candNum)
+	store := &forecastStore{}
+	for i := 0; i < pieceNum; i++ {
+		store.pieces = append(store.pieces, &encryptedSharePiecePacket{sharePiecePacket: &sharePiecePacket{sender: cands[i].ID}})
+	}
+	for i := 0; i < mpkNum; i++ {
+		store.mpks = append(store.mpks, &mpkPacket{sender: cands[i].ID})
+	}
+	checker := newCreateChecker(nil, &forecastChain{height: height}, store)
+	checker.ctx = newCreateContext(newEra(0, &types.BlockHeader{Hash: common.BytesToHash([]byte{1})}))
+	checker.ctx.cands = cands
+	return checker, func() {
+		model.Param.GroupMemberMin = memberMin
+	}
+}
+
+func TestForecast_Success(t *testing.T) {
+	checker, restore := newForecastChecker(20, 20, 20, eraWindow-1)
+	defer restore()
+	fc, err := checker.Forecast()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc.Projection != ProjectionSuccess {
+		t.Fatalf("expect success, got %v %v", fc.Projection, fc.Reason)
+	}
+	if len(fc.MissingPiece) != 0 || len(fc.MissingMpk) != 0 || len(fc.FreezeList) != 0 {
+		t.Fatalf("expect nobody missing")
+	}
+}
+
+func TestForecast_PendingInRound(t *testing.T) {
+	era := newEra(0, nil)
+	checker, restore := newForecastChecker(20, 5, 0, era.encPieceRange.begin+1)
+	defer restore()
+	fc, err := checker.Forecast()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc.Round != RoundEncPiece {
+		t.Fatalf("expect round %v, got %v", RoundEncPiece, fc.Round)
+	}
+	if fc.Projection != ProjectionPending {
+		t.Fatalf("expect pending, got %v", fc.Projection)
+	}
+	if len(fc.MissingPiece) != 15 {
+		t.Fatalf("expect 15 missing pieces, got %v", len(fc.MissingPiece))
+	}
+}
+
+func TestForecast_FailAndFreeze(t *testing.T) {
+	era := newEra(0, nil)
+	checker, restore := newForecastChecker(20, 18, 5, era.mpkRange.end+1)
+	defer restore()
+	fc, err := checker.Forecast()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc.Projection != ProjectionFail {
+		t.Fatalf("expect fail, got %v", fc.Projection)
+	}
+	if len(fc.MissingPiece) != 2 || len(fc.MissingMpk) != 13 {
+		t.Fatalf("unexpected missing %v %v", len(fc.MissingPiece), len(fc.MissingMpk))
+	}
+	if len(fc.FreezeList) != 15 {
+		t.Fatalf("expect 15 to be frozen, got %v", len(fc.FreezeList))
+	}
+}
+
+func TestForecast_NotEnoughCandidates(t *testing.T) {
+	checker, restore := newForecastChecker(5, 5, 5, 1)
+	defer restore()
+	fc, err := checker.Forecast()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc.Projection != ProjectionIdle || fc.CandidateEnough {
+		t.Fatalf("expect idle, got %v", fc.Projection)
+	}
+}
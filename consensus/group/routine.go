@@ -70,6 +70,10 @@ type joinedGroupFilter interface {
 	MinerJoinedLivedGroupCountFilter(maxCount int, height uint64) func(addr common.Address) bool
 }
 
+type livedGroupReader interface {
+	GetLivedGroupsAt(height uint64) []types.GroupI
+}
+
 type createRoutine struct {
 	*createChecker
 	packetSender types.GroupPacketSender
@@ -81,8 +85,9 @@ type createRoutine struct {
 var GroupRoutine *createRoutine
 var logger *logrus.Logger
 
-func InitRoutine(reader minerReader, chain types.BlockChain, provider groupContextProvider, joinedFilter joinedGroupFilter, miner *model.SelfMinerDO) *skStorage {
+func InitRoutine(reader minerReader, chain types.BlockChain, provider groupContextProvider, joinedFilter joinedGroupFilter, groupReader livedGroupReader, miner *model.SelfMinerDO) *skStorage {
 	checker := newCreateChecker(reader, chain, provider.GetGroupStoreReader())
+	checker.groupReader = groupReader
 	logger = log.GroupLogger
 	GroupRoutine = &createRoutine{
 		createChecker: checker,
@@ -207,8 +212,8 @@ func (routine *createRoutine) selectCandidates() error {
 		return fmt.Errorf("not enough candiates in availables:%v", len(availCandidates))
 	}
 
-	selector := newCandidateSelector(availCandidates, bh.Random)
-	selectedCandidates := selector.fts(memberCnt)
+	selector := routine.candidateSelectorAt(h)
+	selectedCandidates := selector.Select(availCandidates, bh.Random, memberCnt)
 
 	mems := make([]string, len(selectedCandidates))
 	for _, m := range selectedCandidates {
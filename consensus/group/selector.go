@@ -16,26 +16,68 @@
 package group
 
 import (
+	"bytes"
 	"container/list"
+	"sort"
+
+	"github.com/darren0718/zvchain/common"
 	"github.com/darren0718/zvchain/consensus/base"
 	"github.com/darren0718/zvchain/consensus/model"
+	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/darren0718/zvchain/params"
+)
+
+const (
+	stakeCapPercent     = 5      // Maximum percentage of the total weight one address can hold in the stake-capped selection
+	reputationLookback  = 100000 // The number of blocks looked back when calculating the drop rate of a candidate
+	reputationMinWeight = 1000   // Minimum basis points of the stake kept by a candidate, no matter how bad the drop rate is
+	basisPoints         = 10000  // The basis of the drop rates and the weight factors
 )
 
+// CandidateSelector selects group members from the candidates with the given random seed.
+// The result must be deterministic for the same candidates and seed
+type CandidateSelector interface {
+	Select(cands []*model.MinerDO, rand []byte, num int) []*model.MinerDO
+}
+
+// weightFunc returns the selecting weight of the given miner
+type weightFunc func(m *model.MinerDO) uint64
+
+// dropRateFunc returns the historical block drop rate related to the given miner, in basis points in range [0, 10000]
+type dropRateFunc func(m *model.MinerDO) uint64
+
+type weightedMiner struct {
+	miner  *model.MinerDO
+	weight uint64
+}
+
 type candidateSelector struct {
 	list        *list.List
 	remainStake uint64
 	rand        []byte
 }
 
+func stakeWeight(m *model.MinerDO) uint64 {
+	return m.Stake
+}
+
 func newCandidateSelector(cands []*model.MinerDO, rand []byte) *candidateSelector {
+	return newWeightedCandidateSelector(cands, rand, stakeWeight)
+}
+
+func newWeightedCandidateSelector(cands []*model.MinerDO, rand []byte, weight weightFunc) *candidateSelector {
 	list := list.New()
 	stake := uint64(0)
 	for _, c := range cands {
 		if c.Stake == 0 {
 			continue
 		}
-		list.PushBack(c)
-		stake += c.Stake
+		w := weight(c)
+		if w == 0 {
+			continue
+		}
+		list.PushBack(&weightedMiner{miner: c, weight: w})
+		stake += w
 	}
 	return &candidateSelector{list: list, remainStake: stake, rand: rand}
 }
@@ -51,15 +93,205 @@ func (cs *candidateSelector) fts(num int) []*model.MinerDO {
 		r := rand.Deri(len(result)).ModuloUint64(cs.remainStake)
 		cumulativeStake := uint64(0)
 		for e := cs.list.Front(); e != nil; e = e.Next() {
-			m := e.Value.(*model.MinerDO)
-			if m.Stake+cumulativeStake > r {
+			m := e.Value.(*weightedMiner)
+			if m.weight+cumulativeStake > r {
 				cs.list.Remove(e)
-				cs.remainStake -= m.Stake
-				result = append(result, m)
+				cs.remainStake -= m.weight
+				result = append(result, m.miner)
 				break
 			}
-			cumulativeStake += m.Stake
+			cumulativeStake += m.weight
 		}
 	}
 	return result
 }
+
+// ftsSelector selects candidates weighted by their stake, which is the default algorithm
+type ftsSelector struct{}
+
+func (ftsSelector) Select(cands []*model.MinerDO, rand []byte, num int) []*model.MinerDO {
+	return newCandidateSelector(cands, rand).fts(num)
+}
+
+// stakeCappedSelector selects candidates weighted by their stake, while the weight of any single address
+// is limited to capPercent of the total stake, so that a whale cannot dominate the group
+type stakeCappedSelector struct {
+	capPercent uint64
+}
+
+func (s *stakeCappedSelector) Select(cands []*model.MinerDO, rand []byte, num int) []*model.MinerDO {
+	limit := s.weightLimit(cands)
+	return newWeightedCandidateSelector(cands, rand, func(m *model.MinerDO) uint64 {
+		if m.Stake > limit {
+			return limit
+		}
+		return m.Stake
+	}).fts(num)
+}
+
+// weightLimit finds the limit c that satisfies c = capPercent% * sum(min(stake, c)), so that no address
+// holds more than capPercent of the total weight after capping.
+// If there are too few candidates to satisfy it, all of them are weighted equally
+func (s *stakeCappedSelector) weightLimit(cands []*model.MinerDO) uint64 {
+	stakes := make([]uint64, 0, len(cands))
+	sum := uint64(0)
+	for _, c := range cands {
+		if c.Stake == 0 {
+			continue
+		}
+		stakes = append(stakes, c.Stake)
+		sum += c.Stake
+	}
+	if len(stakes) == 0 {
+		return 0
+	}
+	sort.Slice(stakes, func(i, j int) bool {
+		return stakes[i] > stakes[j]
+	})
+	// Try to cap the top k stakes, the rest sums to sum
+	for k := 0; k < len(stakes) && uint64(k)*s.capPercent < 100; k++ {
+		limit := mulDiv(sum, s.capPercent, 100-uint64(k)*s.capPercent)
+		if stakes[k] <= limit {
+			if k == 0 {
+				return stakes[0]
+			}
+			return limit
+		}
+		sum -= stakes[k]
+	}
+	return stakes[len(stakes)-1]
+}
+
+// reputationSelector selects candidates weighted by their stake discounted by the historical drop rate.
+// A candidate always keeps reputationMinWeight of its stake so that it still has a chance to be selected
+type reputationSelector struct {
+	dropRate dropRateFunc
+}
+
+func (s *reputationSelector) Select(cands []*model.MinerDO, rand []byte, num int) []*model.MinerDO {
+	return newWeightedCandidateSelector(cands, rand, func(m *model.MinerDO) uint64 {
+		factor := uint64(reputationMinWeight)
+		if rate := s.dropRate(m); rate < basisPoints-reputationMinWeight {
+			factor = basisPoints - rate
+		}
+		return mulDiv(m.Stake, factor, basisPoints)
+	}).fts(num)
+}
+
+// mulDiv returns a*b/c rounded down, avoiding the overflow of a*b
+func mulDiv(a, b, c uint64) uint64 {
+	return a/c*b + a%c*b/c
+}
+
+// candidateSelectorAt returns the selector in effect at the given seed height
+func (checker *createChecker) candidateSelectorAt(h uint64) CandidateSelector {
+	cfg := params.GetChainConfig()
+	if cfg.IsZIP004(h) {
+		return &reputationSelector{dropRate: checker.minerDropRateFunc(h)}
+	}
+	if cfg.IsZIP003(h) {
+		return &stakeCappedSelector{capPercent: stakeCapPercent}
+	}
+	return ftsSelector{}
+}
+
+// minerDropRateFunc returns the function calculating the drop rate of the given miner from its own record,
+// which is the blocks dropped during the heights it worked in a group, limited to the last reputationLookback
+// blocks before the given height. A miner which has never worked in a group is considered reliable.
+// Only data on chain is used, so that all nodes come to the same result
+func (checker *createChecker) minerDropRateFunc(h uint64) dropRateFunc {
+	begin := uint64(0)
+	if h > reputationLookback {
+		begin = h - reputationLookback
+	}
+	groups := checker.workedGroupsInRange(begin, h)
+	counted := make(map[rRange]uint64)
+	return func(m *model.MinerDO) uint64 {
+		ranges := make([]rRange, 0)
+		id := m.ID.Serialize()
+		for _, g := range groups {
+			if !groupHasMember(g, id) {
+				continue
+			}
+			r := rRange{begin: g.Header().WorkHeight(), end: g.Header().DismissHeight() - 1}
+			if r.begin < begin {
+				r.begin = begin
+			}
+			if r.end > h {
+				r.end = h
+			}
+			if r.begin <= r.end {
+				ranges = append(ranges, r)
+			}
+		}
+		total, produced := uint64(0), uint64(0)
+		for _, r := range mergeHeightRanges(ranges) {
+			cnt, ok := counted[r]
+			if !ok {
+				cnt = checker.chain.CountBlocksInRange(r.begin, r.end)
+				counted[r] = cnt
+			}
+			total += r.end - r.begin + 1
+			produced += cnt
+		}
+		if total == 0 {
+			return 0
+		}
+		return basisPoints - mulDiv(produced, basisPoints, total)
+	}
+}
+
+// workedGroupsInRange returns the groups lived in the given height range. A group lives
+// GroupLiveEpochs epochs, so that sampling at that interval doesn't miss any of them
+func (checker *createChecker) workedGroupsInRange(begin, end uint64) []types.GroupI {
+	if checker.groupReader == nil {
+		return nil
+	}
+	step := uint64(types.GroupLiveEpochs * types.EpochLength)
+	seen := make(map[common.Hash]struct{})
+	groups := make([]types.GroupI, 0)
+	for sample := begin; ; sample += step {
+		if sample > end {
+			sample = end
+		}
+		for _, g := range checker.groupReader.GetLivedGroupsAt(sample) {
+			if _, ok := seen[g.Header().Seed()]; ok {
+				continue
+			}
+			seen[g.Header().Seed()] = struct{}{}
+			groups = append(groups, g)
+		}
+		if sample == end {
+			break
+		}
+	}
+	return groups
+}
+
+func groupHasMember(g types.GroupI, id []byte) bool {
+	for _, mem := range g.Members() {
+		if bytes.Equal(mem.ID(), id) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeHeightRanges merges the overlapping ranges so that no height is counted twice
+func mergeHeightRanges(ranges []rRange) []rRange {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].begin < ranges[j].begin
+	})
+	merged := make([]rRange, 0, len(ranges))
+	for _, r := range ranges {
+		last := len(merged) - 1
+		if last >= 0 && r.begin <= merged[last].end+1 {
+			if r.end > merged[last].end {
+				merged[last].end = r.end
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
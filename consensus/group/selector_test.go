@@ -19,6 +19,8 @@ import (
 	"github.com/darren0718/zvchain/common"
 	"github.com/darren0718/zvchain/consensus/groupsig"
 	"github.com/darren0718/zvchain/consensus/model"
+	"github.com/darren0718/zvchain/middleware/types"
+	"math"
 	"math/rand"
 	"testing"
 )
@@ -83,3 +85,191 @@ func BenchmarkFts(b *testing.B) {
 		selector.fts(100)
 	}
 }
+
+func TestStakeCappedSelector_LimitsWhale(t *testing.T) {
+	cands := genRandomMiners(100)
+	whale := cands[0]
+	whale.Stake = 100000000
+
+	selector := &stakeCappedSelector{capPercent: stakeCapPercent}
+	whaleSelected := 0
+	testCount := 1000
+	for i := 0; i < testCount; i++ {
+		selected := selector.Select(cands, common.Int32ToByte(int32(i)), 1)
+		if len(selected) != 1 {
+			t.Fatalf("expect 1 selected, got %v", len(selected))
+		}
+		if selected[0] == whale {
+			whaleSelected++
+		}
+	}
+	rate := float64(whaleSelected) / float64(testCount)
+	t.Log("whale selected rate", rate)
+	if rate > 0.1 {
+		t.Fatalf("whale selected too often: %v", rate)
+	}
+}
+
+func TestStakeCappedSelector_NoDuplicate(t *testing.T) {
+	cands := genRandomMiners(100)
+	selector := &stakeCappedSelector{capPercent: stakeCapPercent}
+	selected := selector.Select(cands, common.FromHex("0x1237"), 40)
+	if len(selected) != 40 {
+		t.Fatalf("expect 40 selected, got %v", len(selected))
+	}
+	ids := make(map[string]struct{})
+	for _, m := range selected {
+		if _, ok := ids[m.ID.GetAddrString()]; ok {
+			t.Fatalf("duplicate selected %v", m.ID.GetAddrString())
+		}
+		ids[m.ID.GetAddrString()] = struct{}{}
+	}
+}
+
+func TestStakeCappedSelector_WeightLimitPrecision(t *testing.T) {
+	cands := genRandomMiners(20)
+	cands[0].Stake = 1000
+	for _, c := range cands[1:] {
+		c.Stake = 101
+	}
+	// The other 19 sum to 1919, and 1919*5/95 is exactly 101
+	selector := &stakeCappedSelector{capPercent: 5}
+	if limit := selector.weightLimit(cands); limit != 101 {
+		t.Fatalf("expect limit 101, got %v", limit)
+	}
+}
+
+func TestMulDiv(t *testing.T) {
+	if v := mulDiv(math.MaxUint64, reputationMinWeight, basisPoints); v != math.MaxUint64/10 {
+		t.Fatalf("unexpected result %v", v)
+	}
+	if v := mulDiv(6000, basisPoints, 9000); v != 6666 {
+		t.Fatalf("unexpected result %v", v)
+	}
+}
+
+func TestReputationSelector_PrefersReliable(t *testing.T) {
+	cands := genRandomMiners(2)
+	cands[0].Stake = 1000
+	cands[1].Stake = 1000
+	selector := &reputationSelector{dropRate: func(m *model.MinerDO) uint64 {
+		if m == cands[0] {
+			return 8000
+		}
+		return 0
+	}}
+	unreliable := 0
+	testCount := 1000
+	for i := 0; i < testCount; i++ {
+		if selector.Select(cands, common.Int32ToByte(int32(i)), 1)[0] == cands[0] {
+			unreliable++
+		}
+	}
+	t.Log("unreliable selected rate", float64(unreliable)/float64(testCount))
+	if unreliable*2 > testCount {
+		t.Fatalf("unreliable miner selected too often: %v", unreliable)
+	}
+}
+
+// dropChain drops all blocks in the range [dropBegin, dropEnd]
+type dropChain struct {
+	types.BlockChain
+	dropBegin, dropEnd uint64
+}
+
+func (c *dropChain) CountBlocksInRange(startHeight uint64, endHeight uint64) uint64 {
+	cnt := uint64(0)
+	for h := startHeight; h <= endHeight; h++ {
+		if h < c.dropBegin || h > c.dropEnd {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+type testGroupReader struct {
+	groups []types.GroupI
+}
+
+func (r *testGroupReader) GetLivedGroupsAt(height uint64) []types.GroupI {
+	gs := make([]types.GroupI, 0)
+	for _, g := range r.groups {
+		if g.Header().WorkHeight() <= height && height < g.Header().DismissHeight() {
+			gs = append(gs, g)
+		}
+	}
+	return gs
+}
+
+func newTestGroup(seed byte, work, dismiss uint64, mems ...*model.MinerDO) *group {
+	members := make([]types.MemberI, 0)
+	for _, m := range mems {
+		members = append(members, &member{id: m.ID.Serialize()})
+	}
+	return &group{
+		header:  &groupHeader{seed: common.BytesToHash([]byte{seed}), workHeight: work, dismissHeight: dismiss},
+		members: members,
+	}
+}
+
+func TestMinerDropRate_OwnRecord(t *testing.T) {
+	miners := genRandomMiners(4)
+	life := uint64(types.GroupLiveEpochs * types.EpochLength)
+	checker := newCreateChecker(nil, &dropChain{dropBegin: 1000, dropEnd: 3999}, nil)
+	checker.groupReader = &testGroupReader{groups: []types.GroupI{
+		newTestGroup(1, 1000, 1000+life, miners[0], miners[1]),
+		newTestGroup(2, 4000, 4000+life, miners[1]),
+		newTestGroup(3, 12000, 12000+life, miners[2]),
+	}}
+	dropRate := checker.minerDropRateFunc(20000)
+
+	if r := dropRate(miners[0]); r != 5000 {
+		t.Fatalf("expect drop rate 5000 for miners[0], got %v", r)
+	}
+	// Worked in 1000-9999, in which 3000 blocks dropped
+	if r := dropRate(miners[1]); r != 3334 {
+		t.Fatalf("unexpected drop rate for miners[1]: %v", r)
+	}
+	if r := dropRate(miners[2]); r != 0 {
+		t.Fatalf("expect drop rate 0 for miners[2], got %v", r)
+	}
+	// Never worked in a group
+	if r := dropRate(miners[3]); r != 0 {
+		t.Fatalf("expect drop rate 0 for miners[3], got %v", r)
+	}
+}
+
+func TestFtsSelector_SameAsFts(t *testing.T) {
+	cands := genRandomMiners(100)
+	rand := common.FromHex("0x1237")
+	expect := newCandidateSelector(cands, rand).fts(20)
+	got := ftsSelector{}.Select(cands, rand, 20)
+	for i := range expect {
+		if expect[i] != got[i] {
+			t.Fatalf("selection differs at %v", i)
+		}
+	}
+}
+
+func TestSimulateSelection(t *testing.T) {
+	miners := make([]*SimulateMiner, 0)
+	for _, m := range genRandomMiners(50) {
+		miners = append(miners, &SimulateMiner{Address: m.ID.GetAddrString(), Stake: m.Stake})
+	}
+	for _, name := range []string{SelectorFTS, SelectorStakeCapped, SelectorReputation} {
+		stats, err := SimulateSelection(name, miners, 10, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total := 0
+		for _, st := range stats {
+			total += st.Selected
+		}
+		if total != 1000 {
+			t.Fatalf("%v: expect total selected 1000, got %v", name, total)
+		}
+	}
+	if _, err := SimulateSelection("unknown", miners, 10, 100); err == nil {
+		t.Fatalf("expect error for unknown selector")
+	}
+}
@@ -0,0 +1,115 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package group
+
+import (
+	"fmt"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/consensus/groupsig"
+	"github.com/darren0718/zvchain/consensus/model"
+)
+
+// Names of the candidate selecting algorithms supported by the simulation
+const (
+	SelectorFTS         = "fts"
+	SelectorStakeCapped = "capped"
+	SelectorReputation  = "reputation"
+)
+
+// SimulateMiner is the input of one miner in the selection simulation
+type SimulateMiner struct {
+	Address  string  `json:"address"`
+	Stake    uint64  `json:"stake"`
+	DropRate float32 `json:"drop_rate"`
+}
+
+// SelectionStat is the selection statistic of one miner in the simulation
+type SelectionStat struct {
+	Address      string  `json:"address"`
+	Stake        uint64  `json:"stake"`
+	StakeRate    float64 `json:"stake_rate"`
+	Selected     int     `json:"selected"`
+	SelectedRate float64 `json:"selected_rate"`
+}
+
+// NewCandidateSelector returns the selector of the given algorithm name. The drop rate function, in basis
+// points, is only used by the reputation-weighted selector
+func NewCandidateSelector(name string, dropRate func(m *model.MinerDO) uint64) (CandidateSelector, error) {
+	switch name {
+	case SelectorFTS:
+		return ftsSelector{}, nil
+	case SelectorStakeCapped:
+		return &stakeCappedSelector{capPercent: stakeCapPercent}, nil
+	case SelectorReputation:
+		if dropRate == nil {
+			return nil, fmt.Errorf("drop rate function required")
+		}
+		return &reputationSelector{dropRate: dropRate}, nil
+	}
+	return nil, fmt.Errorf("unknown selector:%v", name)
+}
+
+// SimulateSelection runs the given selecting algorithm for rounds times on the miner set, each with a different
+// seed, and reports how often each miner is selected
+func SimulateSelection(name string, miners []*SimulateMiner, num int, rounds int) ([]*SelectionStat, error) {
+	if num <= 0 || rounds <= 0 {
+		return nil, fmt.Errorf("num and rounds should be positive")
+	}
+	cands := make([]*model.MinerDO, 0, len(miners))
+	dropRates := make(map[string]uint64)
+	totalStake := uint64(0)
+	for _, m := range miners {
+		if !common.ValidateAddress(m.Address) {
+			return nil, fmt.Errorf("invalid address:%v", m.Address)
+		}
+		id := groupsig.NewIDFromAddress(common.StringToAddress(m.Address))
+		cands = append(cands, &model.MinerDO{ID: *id, Stake: m.Stake})
+		if m.DropRate < 0 || m.DropRate > 1 {
+			return nil, fmt.Errorf("drop rate should be in [0, 1]:%v", m.DropRate)
+		}
+		dropRates[id.GetAddrString()] = uint64(m.DropRate*basisPoints + 0.5)
+		totalStake += m.Stake
+	}
+	selector, err := NewCandidateSelector(name, func(m *model.MinerDO) uint64 {
+		return dropRates[m.ID.GetAddrString()]
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	selectedMap := make(map[string]int)
+	for i := 0; i < rounds; i++ {
+		for _, m := range selector.Select(cands, common.Int32ToByte(int32(i)), num) {
+			selectedMap[m.ID.GetAddrString()]++
+		}
+	}
+
+	stats := make([]*SelectionStat, 0, len(cands))
+	for _, m := range cands {
+		st := &SelectionStat{
+			Address:  m.ID.GetAddrString(),
+			Stake:    m.Stake,
+			Selected: selectedMap[m.ID.GetAddrString()],
+		}
+		if totalStake > 0 {
+			st.StakeRate = float64(m.Stake) / float64(totalStake)
+		}
+		st.SelectedRate = float64(st.Selected) / float64(rounds)
+		stats = append(stats, st)
+	}
+	return stats, nil
+}
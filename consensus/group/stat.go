@@ -17,6 +17,7 @@ package group
 
 import (
 	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/middleware/metrics"
 	lru "github.com/hashicorp/golang-lru"
 	"sync/atomic"
 )
@@ -86,6 +87,9 @@ func (st *createStat) statLog() {
 			fail++
 		}
 	}
+	metrics.GroupCreateEras.WithLabelValues("idle").Set(float64(idle))
+	metrics.GroupCreateEras.WithLabelValues("success").Set(float64(success))
+	metrics.GroupCreateEras.WithLabelValues("fail").Set(float64(fail))
 	total := idle + success + fail
 	start := success + fail
 	if start == 0 {
@@ -0,0 +1,124 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package groupsig
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	"github.com/darren0718/zvchain/consensus/groupsig/bncurve"
+)
+
+// batchScalarBits is the bit length of the random coefficients used in batch verification.
+// A forged batch passes with probability 2^-batchScalarBits
+const batchScalarBits = 64
+
+// SigItem is one (pubkey, message, signature) triple to be verified
+type SigItem struct {
+	Pub Pubkey
+	Msg []byte
+	Sig Signature
+}
+
+func (item *SigItem) valid() bool {
+	if item.Sig.IsNil() || !item.Sig.IsValid() {
+		return false
+	}
+	return item.Pub.IsValid()
+}
+
+func randomScalar() *big.Int {
+	buf := make([]byte, batchScalarBits/8)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	// Zero must be avoided, otherwise the corresponding signature is not checked at all
+	r := new(big.Int).SetBytes(buf)
+	return r.Add(r, big.NewInt(1))
+}
+
+// BatchVerifySigs verifies all the triples at once with random linear combination:
+//   e(Σ r_i·sig_i, G2) == Π e(Σ r_i·H(m_i), pk)
+// where the right side is grouped by distinct public key, so only one multi-pairing is computed.
+// It returns true only if all the signatures are valid, with overwhelming probability
+func BatchVerifySigs(items []SigItem) bool {
+	if len(items) == 0 {
+		return true
+	}
+	if len(items) == 1 {
+		return VerifySig(items[0].Pub, items[0].Msg, items[0].Sig)
+	}
+
+	aggSig := &bncurve.G1{}
+	hashes := make(map[string]*bncurve.G1)
+	pubs := make(map[string]*Pubkey)
+	keys := make([]string, 0)
+
+	for i := range items {
+		item := &items[i]
+		if !item.valid() {
+			return false
+		}
+		r := randomScalar()
+
+		rSig := new(bncurve.G1).ScalarMult(&item.Sig.value, r)
+		if i == 0 {
+			aggSig.Set(rSig)
+		} else {
+			aggSig.Add(aggSig, rSig)
+		}
+
+		rHash := new(bncurve.G1).ScalarMult(HashToG1(string(item.Msg)), r)
+		key := string(item.Pub.Serialize())
+		if h, ok := hashes[key]; ok {
+			h.Add(h, rHash)
+		} else {
+			hashes[key] = rHash
+			pubs[key] = &item.Pub
+			keys = append(keys, key)
+		}
+	}
+
+	g1s := make([]*bncurve.G1, 0, len(keys)+1)
+	g2s := make([]*bncurve.G2, 0, len(keys)+1)
+	g1s = append(g1s, new(bncurve.G1).Neg(aggSig))
+	g2s = append(g2s, bncurve.GetG2Base())
+	for _, key := range keys {
+		g1s = append(g1s, hashes[key])
+		g2s = append(g2s, &pubs[key].value)
+	}
+	return bncurve.PairingCheck(g1s, g2s)
+}
+
+// FirstInvalidSig returns the index of the first invalid signature in the items, or -1 if all valid.
+// The items are bisected and each half is batch verified, so that only the failing part is searched
+func FirstInvalidSig(items []SigItem) int {
+	return firstInvalidSig(items, 0)
+}
+
+func firstInvalidSig(items []SigItem, offset int) int {
+	if BatchVerifySigs(items) {
+		return -1
+	}
+	if len(items) == 1 {
+		return offset
+	}
+	mid := len(items) / 2
+	if idx := firstInvalidSig(items[:mid], offset); idx >= 0 {
+		return idx
+	}
+	return firstInvalidSig(items[mid:], offset+mid)
+}
@@ -0,0 +1,95 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package groupsig
+
+import (
+	"testing"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/consensus/base"
+)
+
+// genSigItems generates n signed items with keyNum distinct keys
+func genSigItems(n int, keyNum int) []SigItem {
+	sks := make([]Seckey, keyNum)
+	for i := range sks {
+		sks[i] = *NewSeckeyFromRand(base.NewRand())
+	}
+	items := make([]SigItem, n)
+	for i := range items {
+		sk := sks[i%keyNum]
+		msg := common.Int32ToByte(int32(i))
+		items[i] = SigItem{Pub: *NewPubkeyFromSeckey(sk), Msg: msg, Sig: Sign(sk, msg)}
+	}
+	return items
+}
+
+func TestBatchVerifySigs(t *testing.T) {
+	items := genSigItems(20, 3)
+	if !BatchVerifySigs(items) {
+		t.Fatalf("batch verify fail")
+	}
+	if idx := FirstInvalidSig(items); idx != -1 {
+		t.Fatalf("expect no invalid sig, got %v", idx)
+	}
+}
+
+func TestBatchVerifySigs_Empty(t *testing.T) {
+	if !BatchVerifySigs(nil) {
+		t.Fatalf("empty batch should pass")
+	}
+}
+
+func TestBatchVerifySigs_WrongMsg(t *testing.T) {
+	items := genSigItems(20, 3)
+	items[13].Msg = []byte("wrong")
+	if BatchVerifySigs(items) {
+		t.Fatalf("batch verify should fail")
+	}
+	if idx := FirstInvalidSig(items); idx != 13 {
+		t.Fatalf("expect invalid sig at 13, got %v", idx)
+	}
+}
+
+func TestBatchVerifySigs_SwappedSigs(t *testing.T) {
+	// Swapping two signatures keeps the plain aggregation unchanged, which must be detected
+	items := genSigItems(4, 4)
+	items[1].Sig, items[2].Sig = items[2].Sig, items[1].Sig
+	if BatchVerifySigs(items) {
+		t.Fatalf("batch verify should fail")
+	}
+	if idx := FirstInvalidSig(items); idx != 1 {
+		t.Fatalf("expect invalid sig at 1, got %v", idx)
+	}
+}
+
+func BenchmarkVerifySig_Single(b *testing.B) {
+	items := genSigItems(32, 2)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, item := range items {
+			VerifySig(item.Pub, item.Msg, item.Sig)
+		}
+	}
+}
+
+func BenchmarkBatchVerifySigs(b *testing.B) {
+	items := genSigItems(32, 2)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		BatchVerifySigs(items)
+	}
+}
@@ -187,7 +187,7 @@ func (g *G1) ScalarBaseMult(k *big.Int) *G1 {
 	if g.p == nil {
 		g.p = &curvePoint{}
 	}
-	g.p.Mul(curveGen, k)
+	curveBaseMul(g.p, k)
 	return g
 }
 
@@ -327,7 +327,7 @@ func (e *G2) ScalarBaseMult(k *big.Int) *G2 {
 	if e.p == nil {
 		e.p = &twistPoint{}
 	}
-	e.p.Mul(twistGen, k)
+	twistBaseMul(e.p, k)
 	return e
 }
 
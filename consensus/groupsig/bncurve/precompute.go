@@ -0,0 +1,289 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bncurve
+
+import (
+	"math/big"
+	"sync"
+)
+
+// lineCoeff is a line function evaluated without the G1 point.
+// The actual line is (a, b·x, c·y) where (x, y) is the affine G1 point
+type lineCoeff struct {
+	a, b, c gfP2
+}
+
+// G2Prepared holds the line functions of the Miller loop for a fixed G2 point,
+// so that pairings with the point only need to evaluate them at the G1 side.
+type G2Prepared struct {
+	lines    []lineCoeff
+	infinity bool
+}
+
+// Prepare precomputes the line functions of the Miller loop for e
+func (e *G2) Prepare() *G2Prepared {
+	if e.p == nil || e.p.IsInfinity() {
+		return &G2Prepared{infinity: true}
+	}
+	return &G2Prepared{lines: prepareLines(e.p)}
+}
+
+// prepareLines runs the same steps as miller with the G1 point replaced by (1, 1),
+// which leaves the factors of x and y in b and c of each line
+func prepareLines(q *twistPoint) []lineCoeff {
+	one := &curvePoint{}
+	one.x.Set(newGFp(1))
+	one.y.Set(newGFp(1))
+	one.z.Set(newGFp(1))
+	one.t.Set(newGFp(1))
+
+	lines := make([]lineCoeff, 0, 2*len(sixuPlus2NAF))
+	appendLine := func(a, b, c *gfP2) {
+		lines = append(lines, lineCoeff{a: *a, b: *b, c: *c})
+	}
+
+	aAffine := &twistPoint{}
+	aAffine.Set(q)
+	aAffine.MakeAffine()
+
+	minusA := &twistPoint{}
+	minusA.Neg(aAffine)
+
+	r := &twistPoint{}
+	r.Set(aAffine)
+
+	r2 := (&gfP2{}).Square(&aAffine.y)
+
+	for i := len(sixuPlus2NAF) - 1; i > 0; i-- {
+		a, b, c, newR := lineFunctionDouble(r, one)
+		appendLine(a, b, c)
+		r = newR
+
+		switch sixuPlus2NAF[i-1] {
+		case 1:
+			a, b, c, newR = lineFunctionAdd(r, aAffine, one, r2)
+		case -1:
+			a, b, c, newR = lineFunctionAdd(r, minusA, one, r2)
+		default:
+			continue
+		}
+		appendLine(a, b, c)
+		r = newR
+	}
+
+	q1 := &twistPoint{}
+	q1.x.Conjugate(&aAffine.x).Mul(&q1.x, xiToPMinus1Over3)
+	q1.y.Conjugate(&aAffine.y).Mul(&q1.y, xiToPMinus1Over2)
+	q1.z.SetOne()
+	q1.t.SetOne()
+
+	minusQ2 := &twistPoint{}
+	minusQ2.x.MulScalar(&aAffine.x, xiToPSquaredMinus1Over3)
+	minusQ2.y.Set(&aAffine.y)
+	minusQ2.z.SetOne()
+	minusQ2.t.SetOne()
+
+	r2.Square(&q1.y)
+	a, b, c, newR := lineFunctionAdd(r, q1, one, r2)
+	appendLine(a, b, c)
+	r = newR
+
+	r2.Square(&minusQ2.y)
+	a, b, c, _ = lineFunctionAdd(r, minusQ2, one, r2)
+	appendLine(a, b, c)
+
+	return lines
+}
+
+// millerPrepared is the same as miller, with the line functions taken from the prepared G2 point
+func millerPrepared(q *G2Prepared, p *curvePoint) *gfP12 {
+	ret := (&gfP12{}).SetOne()
+
+	bAffine := &curvePoint{}
+	bAffine.Set(p)
+	bAffine.MakeAffine()
+
+	b, c := &gfP2{}, &gfP2{}
+	idx := 0
+	mulPrepared := func() {
+		line := &q.lines[idx]
+		b.MulScalar(&line.b, &bAffine.x)
+		c.MulScalar(&line.c, &bAffine.y)
+		mulLine(ret, &line.a, b, c)
+		idx++
+	}
+
+	for i := len(sixuPlus2NAF) - 1; i > 0; i-- {
+		if i != len(sixuPlus2NAF)-1 {
+			ret.Square(ret)
+		}
+		mulPrepared()
+		if sixuPlus2NAF[i-1] != 0 {
+			mulPrepared()
+		}
+	}
+	mulPrepared()
+	mulPrepared()
+
+	return ret
+}
+
+// PairPrepared calculates an Optimal Ate pairing with a prepared G2 point
+func PairPrepared(g1 *G1, g2 *G2Prepared) *GT {
+	ret := &gfP12{}
+	if g2.infinity || g1.p.IsInfinity() {
+		ret.SetOne()
+	} else {
+		ret = finalExponentiation(millerPrepared(g2, g1.p))
+	}
+	return &GT{ret}
+}
+
+// PairingCheckPrepared calculates the Optimal Ate pairing for a set of points with prepared G2 points.
+func PairingCheckPrepared(a []*G1, b []*G2Prepared) bool {
+	acc := new(gfP12)
+	acc.SetOne()
+
+	for i := 0; i < len(a); i++ {
+		if a[i].p.IsInfinity() || b[i].infinity {
+			continue
+		}
+		acc.Mul(acc, millerPrepared(b[i], a[i].p))
+	}
+	return finalExponentiation(acc).IsOne()
+}
+
+// PairingCheckWithBase is the same as PairingCheck with one more pair (base, G2 generator),
+// which takes the prepared generator instead of computing its line functions
+func PairingCheckWithBase(base *G1, a []*G1, b []*G2) bool {
+	acc := new(gfP12)
+	acc.SetOne()
+
+	if !base.p.IsInfinity() {
+		acc.Mul(acc, millerPrepared(GetG2BasePrepared(), base.p))
+	}
+	for i := 0; i < len(a); i++ {
+		if a[i].p.IsInfinity() || b[i].p.IsInfinity() {
+			continue
+		}
+		acc.Mul(acc, miller(b[i].p, a[i].p))
+	}
+	return finalExponentiation(acc).IsOne()
+}
+
+var (
+	g2BasePrepared     *G2Prepared
+	g2BasePreparedOnce sync.Once
+)
+
+// GetG2BasePrepared returns the prepared generator of G2
+func GetG2BasePrepared() *G2Prepared {
+	g2BasePreparedOnce.Do(func() {
+		g2BasePrepared = GetG2Base().Prepare()
+	})
+	return g2BasePrepared
+}
+
+// Fixed-base tables for the generators: table[i][j] = j·2^(w·i)·g.
+// A scalar multiplication is then one addition per window, no doubling required.
+// The entry is added for every window, the zero digits included, as the scalars may be secret keys
+const (
+	fixedBaseWindow     = 4
+	fixedBaseWindowSize = 1 << fixedBaseWindow
+	fixedBaseScalarBits = 256
+	fixedBaseWindows    = fixedBaseScalarBits / fixedBaseWindow
+)
+
+var (
+	curveGenTable     [fixedBaseWindows][fixedBaseWindowSize]curvePoint
+	curveGenTableOnce sync.Once
+	twistGenTable     [fixedBaseWindows][fixedBaseWindowSize]twistPoint
+	twistGenTableOnce sync.Once
+)
+
+func initCurveGenTable() {
+	base := &curvePoint{}
+	base.Set(curveGen)
+	for i := 0; i < fixedBaseWindows; i++ {
+		row := &curveGenTable[i]
+		row[0].SetInfinity()
+		row[1].Set(base)
+		for j := 2; j < fixedBaseWindowSize; j++ {
+			row[j].Add(&row[j-1], base)
+		}
+		// The base of next window is 2^w times of current
+		base.Double(&row[fixedBaseWindowSize/2])
+	}
+}
+
+func initTwistGenTable() {
+	base := &twistPoint{}
+	base.Set(twistGen)
+	for i := 0; i < fixedBaseWindows; i++ {
+		row := &twistGenTable[i]
+		row[0].SetInfinity()
+		row[1].Set(base)
+		for j := 2; j < fixedBaseWindowSize; j++ {
+			row[j].Add(&row[j-1], base)
+		}
+		base.Double(&row[fixedBaseWindowSize/2])
+	}
+}
+
+// windowDigit returns the i-th w-bit window of k
+func windowDigit(k *big.Int, i int) int {
+	d := 0
+	for b := fixedBaseWindow - 1; b >= 0; b-- {
+		d = d<<1 | int(k.Bit(i*fixedBaseWindow+b))
+	}
+	return d
+}
+
+func fixedBaseApplicable(k *big.Int) bool {
+	return k.Sign() >= 0 && k.BitLen() <= fixedBaseScalarBits
+}
+
+// curveBaseMul sets c to k·curveGen with the fixed-base table
+func curveBaseMul(c *curvePoint, k *big.Int) {
+	if !fixedBaseApplicable(k) {
+		c.Mul(curveGen, k)
+		return
+	}
+	curveGenTableOnce.Do(initCurveGenTable)
+
+	sum := &curvePoint{}
+	sum.SetInfinity()
+	for i := 0; i < fixedBaseWindows; i++ {
+		sum.Add(sum, &curveGenTable[i][windowDigit(k, i)])
+	}
+	c.Set(sum)
+}
+
+// twistBaseMul sets c to k·twistGen with the fixed-base table
+func twistBaseMul(c *twistPoint, k *big.Int) {
+	if !fixedBaseApplicable(k) {
+		c.Mul(twistGen, k)
+		return
+	}
+	twistGenTableOnce.Do(initTwistGenTable)
+
+	sum := &twistPoint{}
+	sum.SetInfinity()
+	for i := 0; i < fixedBaseWindows; i++ {
+		sum.Add(sum, &twistGenTable[i][windowDigit(k, i)])
+	}
+	c.Set(sum)
+}
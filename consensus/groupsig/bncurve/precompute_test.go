@@ -0,0 +1,126 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bncurve
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestPairPrepared(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		_, a, _ := RandomG1(rand.Reader)
+		_, b, _ := RandomG2(rand.Reader)
+		expect := Pair(a, b)
+		got := PairPrepared(a, b.Prepare())
+		if !PairIsEuqal(expect, got) {
+			t.Fatalf("prepared pairing mismatch")
+		}
+	}
+}
+
+func TestPairingCheckPrepared(t *testing.T) {
+	k, b, _ := RandomG2(rand.Reader)
+	_, a, _ := RandomG1(rand.Reader)
+	ka := new(G1).ScalarMult(a, k)
+	nka := new(G1).Neg(ka)
+
+	// e(k·a, g2) == e(a, k·g2)
+	if !PairingCheckPrepared([]*G1{nka, a}, []*G2Prepared{GetG2BasePrepared(), b.Prepare()}) {
+		t.Fatalf("check gave false negative")
+	}
+	if PairingCheckPrepared([]*G1{ka, a}, []*G2Prepared{GetG2BasePrepared(), b.Prepare()}) {
+		t.Fatalf("check gave false positive")
+	}
+}
+
+func TestPairingCheckWithBase(t *testing.T) {
+	k, b, _ := RandomG2(rand.Reader)
+	_, a, _ := RandomG1(rand.Reader)
+	ka := new(G1).ScalarMult(a, k)
+	nka := new(G1).Neg(ka)
+
+	if !PairingCheckWithBase(nka, []*G1{a}, []*G2{b}) {
+		t.Fatalf("check gave false negative")
+	}
+	if PairingCheckWithBase(ka, []*G1{a}, []*G2{b}) {
+		t.Fatalf("check gave false positive")
+	}
+}
+
+func TestFixedBaseMult(t *testing.T) {
+	ks := []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(16), new(big.Int).Sub(Order, big.NewInt(1))}
+	for i := 0; i < 5; i++ {
+		k, _ := randomK(rand.Reader)
+		ks = append(ks, k)
+	}
+	ks = append(ks, new(big.Int).Lsh(Order, 10))
+
+	for _, k := range ks {
+		g1 := new(G1).ScalarBaseMult(k)
+		expect1 := &G1{&curvePoint{}}
+		expect1.p.Mul(curveGen, k)
+		if !bytes.Equal(g1.Marshal(), expect1.Marshal()) {
+			t.Fatalf("G1 fixed-base mult mismatch at %v", k)
+		}
+
+		g2 := new(G2).ScalarBaseMult(k)
+		expect2 := &G2{&twistPoint{}}
+		expect2.p.Mul(twistGen, k)
+		if !bytes.Equal(g2.Marshal(), expect2.Marshal()) {
+			t.Fatalf("G2 fixed-base mult mismatch at %v", k)
+		}
+	}
+}
+
+func BenchmarkPair(b *testing.B) {
+	_, a, _ := RandomG1(rand.Reader)
+	_, q, _ := RandomG2(rand.Reader)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Pair(a, q)
+	}
+}
+
+func BenchmarkPairPrepared(b *testing.B) {
+	_, a, _ := RandomG1(rand.Reader)
+	_, q, _ := RandomG2(rand.Reader)
+	pq := q.Prepare()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		PairPrepared(a, pq)
+	}
+}
+
+func BenchmarkG2ScalarMult(b *testing.B) {
+	k, _ := randomK(rand.Reader)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := &twistPoint{}
+		p.Mul(twistGen, k)
+	}
+}
+
+func BenchmarkG2ScalarBaseMult(b *testing.B) {
+	k, _ := randomK(rand.Reader)
+	new(G2).ScalarBaseMult(k)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		new(G2).ScalarBaseMult(k)
+	}
+}
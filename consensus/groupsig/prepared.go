@@ -0,0 +1,52 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package groupsig
+
+import "github.com/darren0718/zvchain/consensus/groupsig/bncurve"
+
+// PreparedPubkey is a public key with the pairing precomputation done.
+// It's worth preparing the public keys used for lots of verifications, e.g. the group public keys
+type PreparedPubkey struct {
+	pub      Pubkey
+	prepared *bncurve.G2Prepared
+}
+
+// NewPreparedPubkey precomputes the pairing of the given public key
+func NewPreparedPubkey(pub Pubkey) *PreparedPubkey {
+	return &PreparedPubkey{
+		pub:      pub,
+		prepared: pub.value.Prepare(),
+	}
+}
+
+// Pubkey returns the original public key
+func (pp *PreparedPubkey) Pubkey() Pubkey {
+	return pp.pub
+}
+
+// VerifySigPrepared is the same as VerifySig with the prepared public key, false if not given
+func VerifySigPrepared(pp *PreparedPubkey, msg []byte, sig Signature) bool {
+	if pp == nil {
+		return false
+	}
+	if sig.IsNil() || !sig.IsValid() {
+		return false
+	}
+	if !pp.pub.IsValid() {
+		return false
+	}
+	return verifySigPrepared(pp.prepared, msg, sig)
+}
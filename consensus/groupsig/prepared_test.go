@@ -0,0 +1,55 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package groupsig
+
+import (
+	"testing"
+)
+
+func TestVerifySigPrepared(t *testing.T) {
+	items := genSigItems(4, 2)
+	for i, item := range items {
+		pp := NewPreparedPubkey(item.Pub)
+		if !VerifySigPrepared(pp, item.Msg, item.Sig) {
+			t.Fatalf("verify prepared fail at %v", i)
+		}
+		if VerifySigPrepared(pp, []byte("wrong"), item.Sig) {
+			t.Fatalf("verify prepared should fail at %v", i)
+		}
+		if VerifySigPrepared(nil, item.Msg, item.Sig) {
+			t.Fatalf("verify without the prepared key should fail at %v", i)
+		}
+	}
+}
+
+// The group public keys are prepared once and used for lots of verifications
+func BenchmarkVerifySigPrepared(b *testing.B) {
+	items := genSigItems(32, 2)
+	pps := make([]*PreparedPubkey, len(items))
+	for i, item := range items {
+		if i < 2 {
+			pps[i] = NewPreparedPubkey(item.Pub)
+		} else {
+			pps[i] = pps[i%2]
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j, item := range items {
+			VerifySigPrepared(pps[j], item.Msg, item.Sig)
+		}
+	}
+}
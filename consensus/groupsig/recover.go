@@ -0,0 +1,42 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package groupsig
+
+import "sort"
+
+// MemberPubkeyGetter returns the public key of the given group member, or an invalid one if not a member
+type MemberPubkeyGetter func(id ID) Pubkey
+
+// BlameSignaturePieces verifies each signature piece in m against the member public key,
+// and returns the members whose piece is invalid, sorted by address
+func BlameSignaturePieces(m SignatureIMap, msg []byte, memberPk MemberPubkeyGetter) []ID {
+	keys := make([]string, 0)
+	for key, sig := range m {
+		var id ID
+		id.SetAddrString(key)
+		pk := memberPk(id)
+		if !pk.IsValid() || !VerifySig(pk, msg, sig) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	bad := make([]ID, len(keys))
+	for i, key := range keys {
+		bad[i].SetAddrString(key)
+	}
+	return bad
+}
@@ -0,0 +1,72 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package groupsig
+
+import (
+	"testing"
+
+	"github.com/darren0718/zvchain/consensus/base"
+)
+
+type thresholdGroup4Test struct {
+	gpk  Pubkey
+	ids  []ID
+	secs []Seckey
+	pks  map[string]Pubkey
+}
+
+func newThresholdGroup4Test(n, k int) *thresholdGroup4Test {
+	sec := NewSeckeyFromRand(base.NewRand())
+	msk := sec.GetMasterSecretKey(k)
+	g := &thresholdGroup4Test{
+		gpk:  *NewPubkeyFromSeckey(*sec),
+		ids:  make([]ID, n),
+		secs: make([]Seckey, n),
+		pks:  make(map[string]Pubkey),
+	}
+	for i := 0; i < n; i++ {
+		g.ids[i].SetLittleEndian([]byte{1, 2, 3, 4, 5, byte(i)})
+		g.secs[i].Set(msk, &g.ids[i])
+		g.pks[g.ids[i].GetAddrString()] = *NewPubkeyFromSeckey(g.secs[i])
+	}
+	return g
+}
+
+func (g *thresholdGroup4Test) memberPk(id ID) Pubkey {
+	return g.pks[id.GetAddrString()]
+}
+
+func (g *thresholdGroup4Test) sign(msg []byte, bad map[int]bool) SignatureIMap {
+	m := make(SignatureIMap)
+	for i, id := range g.ids {
+		if bad[i] {
+			m[id.GetAddrString()] = Sign(g.secs[i], []byte("wrong"))
+		} else {
+			m[id.GetAddrString()] = Sign(g.secs[i], msg)
+		}
+	}
+	return m
+}
+
+func TestBlameSignaturePieces(t *testing.T) {
+	msg := []byte("test message")
+	g := newThresholdGroup4Test(5, 3)
+
+	bad := BlameSignaturePieces(g.sign(msg, map[int]bool{3: true}), msg, g.memberPk)
+	if len(bad) != 1 || !bad[0].IsEqual(g.ids[3]) {
+		t.Fatalf("expect member 3 blamed, got %v", bad)
+	}
+}
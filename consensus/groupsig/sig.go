@@ -146,13 +146,16 @@ func VerifySig(pub Pubkey, msg []byte, sig Signature) bool {
 	if sig.value.IsNil() {
 		return false
 	}
-	bQ := bncurve.GetG2Base()
-	p1 := bncurve.Pair(&sig.value, bQ)
-
+	negSig := new(bncurve.G1).Neg(&sig.value)
 	Hm := HashToG1(string(msg))
-	p2 := bncurve.Pair(Hm, &pub.value)
+	return bncurve.PairingCheckWithBase(negSig, []*bncurve.G1{Hm}, []*bncurve.G2{&pub.value})
+}
 
-	return bncurve.PairIsEuqal(p1, p2)
+// verifySigPrepared checks e(sig, G2) == e(H(m), pub) with one multi-pairing
+func verifySigPrepared(pub *bncurve.G2Prepared, msg []byte, sig Signature) bool {
+	negSig := new(bncurve.G1).Neg(&sig.value)
+	Hm := HashToG1(string(msg))
+	return bncurve.PairingCheckPrepared([]*bncurve.G1{negSig, Hm}, []*bncurve.G2Prepared{bncurve.GetG2BasePrepared(), pub})
 }
 
 // VerifyAggregateSig is a fragmentation merge verification function.
@@ -151,16 +151,18 @@ type groupInfoReader interface {
 type groupGetter func(seed common.Hash) types.GroupI
 
 type groupReader struct {
-	skStore skStorage
-	cache   *lru.Cache
-	reader  groupInfoReader
+	skStore     skStorage
+	cache       *lru.Cache
+	preparedGpk *lru.Cache // Cache the group public keys with pairing precomputed. key: seed, value: *groupsig.PreparedPubkey
+	reader      groupInfoReader
 }
 
 func newGroupReader(infoReader groupInfoReader, skReader skStorage) *groupReader {
 	return &groupReader{
-		skStore: skReader,
-		reader:  infoReader,
-		cache:   common.MustNewLRUCache(200),
+		skStore:     skReader,
+		reader:      infoReader,
+		cache:       common.MustNewLRUCache(200),
+		preparedGpk: common.MustNewLRUCache(50),
 	}
 }
 
@@ -172,6 +174,20 @@ func (gr *groupReader) getGroupHeaderBySeed(seed common.Hash) *groupHeader {
 	return g.header
 }
 
+// getPreparedGpk returns the prepared public key of the given group, which speeds up the verifications with the group
+func (gr *groupReader) getPreparedGpk(seed common.Hash) *groupsig.PreparedPubkey {
+	if v, ok := gr.preparedGpk.Get(seed); ok {
+		return v.(*groupsig.PreparedPubkey)
+	}
+	gh := gr.getGroupHeaderBySeed(seed)
+	if gh == nil {
+		return nil
+	}
+	pp := groupsig.NewPreparedPubkey(gh.gpk)
+	gr.preparedGpk.ContainsOrAdd(seed, pp)
+	return pp
+}
+
 func (gr *groupReader) getGroupBySeed(seed common.Hash) *verifyGroup {
 	return gr.tryToGetOrConvert(seed, gr.reader.GetGroupBySeed)
 }
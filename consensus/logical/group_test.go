@@ -136,3 +136,19 @@ func TestGroupReader_GetActiveGroups(t *testing.T) {
 		t.Log(g.header.seed, g.header.workHeight, g.header.dismissHeight)
 	}
 }
+
+func TestGroupReader_GetPreparedGpk(t *testing.T) {
+	gr := newGroupReader(newGroupReader4Test(100), nil)
+
+	seed := common.BytesToHash(common.Uint64ToByte(uint64(3)))
+	pp := gr.getPreparedGpk(seed)
+	if pp == nil {
+		t.Fatalf("prepared gpk should exist")
+	}
+	if gr.getPreparedGpk(seed) != pp {
+		t.Fatalf("prepared gpk should be cached")
+	}
+	if gr.getPreparedGpk(common.BytesToHash([]byte("not exist"))) != nil {
+		t.Fatalf("prepared gpk of unknown group should be nil")
+	}
+}
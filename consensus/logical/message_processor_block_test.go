@@ -24,6 +24,7 @@ import (
 	"github.com/darren0718/zvchain/middleware/time"
 	"github.com/darren0718/zvchain/middleware/types"
 	"github.com/darren0718/zvchain/network"
+	"github.com/darren0718/zvchain/tvm/mock"
 )
 
 var data uint64
@@ -269,6 +270,7 @@ func initContext4Test() error {
 	if err != nil {
 		return err
 	}
+	core.SetContractEngine(mock.New().NewEngine)
 	err = core.InitCore(NewConsensusHelper4Test(groupsig.ID{}), nil)
 	core.GroupManagerImpl.RegisterGroupCreateChecker(&GroupCreateChecker4Test{})
 
@@ -471,6 +473,10 @@ func (helper *ConsensusHelperImpl4Test) VerifyBlockHeaders(pre, bh *types.BlockH
 	return true, nil
 }
 
+func (helper *ConsensusHelperImpl4Test) VerifyBlockHeadersBatch(pre *types.BlockHeader, bhs []*types.BlockHeader) (verified int, err error) {
+	return len(bhs), nil
+}
+
 type Account4Test struct {
 	Address  string
 	Pk       string
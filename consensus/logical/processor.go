@@ -83,6 +83,10 @@ type Processor struct {
 
 	cachedMinElapseByEpoch *lru.Cache // Cache the min elapse milliseconds in a epoch. key: common.Hash, value: int32
 
+	verifiedSigs *lru.Cache // Cache the signatures verified in batch. key: common.Hash of the sign item, value: struct{}
+
+	blamedMembers *lru.Cache // Members sent bad signature pieces. key: block hash, value: []groupsig.ID
+
 	gNetMgr *groupNetMgr
 }
 
@@ -147,11 +151,13 @@ func (p *Processor) Init(mi model.SelfMinerDO, conf common.ConfManager) bool {
 	p.Ticker = ticker.NewGlobalTicker("consensus")
 
 	provider := core.GroupManagerImpl
-	sr := group2.InitRoutine(p.minerReader, p.MainChain, provider, provider, &mi)
+	sr := group2.InitRoutine(p.minerReader, p.MainChain, provider, provider, provider, &mi)
 	p.groupReader = newGroupReader(provider, sr)
 	p.selector = newGroupSelector(provider)
 
 	p.cachedMinElapseByEpoch = common.MustNewLRUCache(10)
+	p.verifiedSigs = common.MustNewLRUCache(verifiedSigCacheSize)
+	p.blamedMembers = common.MustNewLRUCache(100)
 
 	p.gNetMgr = newGroupNetMgr(p.NetServer, p.groupReader, core.MinerManagerImpl, mi.ID)
 
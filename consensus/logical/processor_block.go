@@ -166,17 +166,11 @@ func (p *Processor) VerifyBlock(bh *types.BlockHeader, preBH *types.BlockHeader)
 		err = core.ErrPkNotExists
 		return
 	}
-	gpk := group.gpk
-	pubArray := [2]groupsig.Pubkey{*pPubkey, gpk}
-	aggSign := groupsig.DeserializeSign(bh.Signature)
-	b := groupsig.VerifyAggregateSig(pubArray[:], bh.Hash.Bytes(), *aggSign)
-	if !b {
+	if !p.verifySigItem(blockSignItem(bh, group.gpk, *pPubkey), nil) {
 		err = core.ErrorGroupSign
 		return
 	}
-	randomSig := groupsig.DeserializeSign(bh.Random)
-	b = groupsig.VerifySig(gpk, preBH.Random, *randomSig)
-	if !b {
+	if !p.verifySigItem(randomSignItem(preBH, bh, group.gpk), p.groupReader.getPreparedGpk(group.seed)) {
 		err = core.ErrorRandomSign
 		return
 	}
@@ -197,17 +191,12 @@ func (p *Processor) VerifyBlockSign(bh *types.BlockHeader) (ok bool, err error)
 		return
 	}
 
-	gpk := group.gpk
-
 	ppk := p.getProposerPubKeyInBlock(bh)
 	if ppk == nil || !ppk.IsValid() {
 		err = core.ErrPkNil
 		return
 	}
-	pkArray := [2]groupsig.Pubkey{*ppk, gpk}
-	aggSign := groupsig.DeserializeSign(bh.Signature)
-	b := groupsig.VerifyAggregateSig(pkArray[:], bh.Hash.Bytes(), *aggSign)
-	if !b {
+	if !p.verifySigItem(blockSignItem(bh, group.gpk, *ppk), nil) {
 		err = fmt.Errorf("signature verify fail")
 		return
 	}
@@ -226,3 +215,108 @@ func (p *Processor) VerifyBlockHeaders(pre, bh *types.BlockHeader) (ok bool, err
 	}
 	return p.VerifyBlockSign(bh)
 }
+
+const verifiedSigCacheSize = 1000
+
+// blockSignItem returns the aggregate signature item of the proposer and the verify group on the block hash
+func blockSignItem(bh *types.BlockHeader, gpk groupsig.Pubkey, ppk groupsig.Pubkey) groupsig.SigItem {
+	return groupsig.SigItem{
+		Pub: *groupsig.AggregatePubkeys([]groupsig.Pubkey{ppk, gpk}),
+		Msg: bh.Hash.Bytes(),
+		Sig: *groupsig.DeserializeSign(bh.Signature),
+	}
+}
+
+// randomSignItem returns the random signature item of the verify group on the random of the pre block
+func randomSignItem(pre *types.BlockHeader, bh *types.BlockHeader, gpk groupsig.Pubkey) groupsig.SigItem {
+	return groupsig.SigItem{
+		Pub: gpk,
+		Msg: pre.Random,
+		Sig: *groupsig.DeserializeSign(bh.Random),
+	}
+}
+
+func sigItemKey(item *groupsig.SigItem) common.Hash {
+	buf := make([]byte, 0)
+	buf = append(buf, item.Pub.Serialize()...)
+	buf = append(buf, item.Msg...)
+	buf = append(buf, item.Sig.Serialize()...)
+	return common.BytesToHash(common.Sha256(buf))
+}
+
+// verifySigItem verifies the signature item unless it has been verified in batch before.
+// The prepared public key is used if given, which should be the same as the item's
+func (p *Processor) verifySigItem(item groupsig.SigItem, pp *groupsig.PreparedPubkey) bool {
+	if p.verifiedSigs != nil {
+		if _, ok := p.verifiedSigs.Get(sigItemKey(&item)); ok {
+			return true
+		}
+	}
+	if pp != nil {
+		return groupsig.VerifySigPrepared(pp, item.Msg, item.Sig)
+	}
+	return groupsig.VerifySig(item.Pub, item.Msg, item.Sig)
+}
+
+func (p *Processor) markSigItemsVerified(items []groupsig.SigItem) {
+	if p.verifiedSigs == nil {
+		return
+	}
+	for i := range items {
+		p.verifiedSigs.Add(sigItemKey(&items[i]), struct{}{})
+	}
+}
+
+// VerifyBlockHeadersBatch checks the chained headers following pre like VerifyBlockHeaders, but verifies all
+// the signatures with one multi-pairing. If the batch fails, the headers are verified one by one to find out
+// the first illegal one.
+// It returns the number of leading headers verified. Verification stops without error at the first header
+// whose group or proposer is unknown yet, and those left will be checked when added on chain
+func (p *Processor) VerifyBlockHeadersBatch(pre *types.BlockHeader, bhs []*types.BlockHeader) (verified int, err error) {
+	items := make([]groupsig.SigItem, 0, 2*len(bhs))
+	checked := 0
+	for _, bh := range bhs {
+		if bh.PreHash != pre.Hash {
+			err = fmt.Errorf("prehash not equal to pre at %v", bh.Height)
+			break
+		}
+		if bh.Hash != bh.GenHash() {
+			err = fmt.Errorf("block hash error at %v", bh.Height)
+			break
+		}
+		gSeed := p.CalcVerifyGroup(pre, bh.Height)
+		if gSeed != bh.Group {
+			err = fmt.Errorf("verify group error at %v: expect %v, infact %v", bh.Height, gSeed, bh.Group)
+			break
+		}
+		group := p.groupReader.getGroupHeaderBySeed(bh.Group)
+		if group == nil {
+			break
+		}
+		ppk := p.getProposerPubKeyInBlock(bh)
+		if ppk == nil || !ppk.IsValid() {
+			break
+		}
+		items = append(items, blockSignItem(bh, group.gpk, *ppk), randomSignItem(pre, bh, group.gpk))
+		checked++
+		pre = bh
+	}
+
+	if groupsig.BatchVerifySigs(items) {
+		p.markSigItemsVerified(items)
+		return checked, err
+	}
+
+	// Fall back to find out the first illegal header
+	idx := groupsig.FirstInvalidSig(items)
+	if idx < 0 {
+		p.markSigItemsVerified(items)
+		return checked, err
+	}
+	p.markSigItemsVerified(items[:idx-idx%2])
+	bh := bhs[idx/2]
+	if idx%2 == 0 {
+		return idx / 2, fmt.Errorf("signature verify fail at %v-%v", bh.Height, bh.Hash)
+	}
+	return idx / 2, fmt.Errorf("random signature verify fail at %v-%v", bh.Height, bh.Hash)
+}
@@ -170,11 +170,13 @@ func (p *Processor) consensusFinalize(vctx *VerifyContext, slot *SlotContext) {
 		return
 	}
 
-	gpk := vctx.group.header.gpk
-
 	// Group signature verification passed
-	if !slot.VerifyGroupSigns(gpk, vctx.prevBH.Random) {
-		result = "verify group sig fail"
+	verified := slot.VerifyGroupSigns(vctx.group, vctx.prevBH.Random)
+	if blamed := slot.Blamed(); len(blamed) > 0 {
+		p.recordBlamedMembers(bh, blamed)
+	}
+	if !verified {
+		result = fmt.Sprintf("verify group sig fail, slot status %v", slot.GetSlotStatus())
 		return
 	}
 
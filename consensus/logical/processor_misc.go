@@ -145,7 +145,11 @@ func (p *Processor) VerifyRewardTransaction(tx *types.Transaction) (ok bool, err
 
 	gpk := group.header.gpk
 	gSign := groupsig.DeserializeSign(signBytes[0:groupsig.SignatureLength]) //size of groupsig == 33
-	if !groupsig.VerifySig(gpk, tx.Hash.Bytes(), *gSign) {
+	pp := p.groupReader.getPreparedGpk(group.header.seed)
+	if pp == nil {
+		return false, common.ErrGroupNil
+	}
+	if !groupsig.VerifySigPrepared(pp, tx.Hash.Bytes(), *gSign) {
 		return false, fmt.Errorf("verify reward sign fail, blockHash=%v, gSign=%v, txHash=%v, gpk=%v, tx=%+v", blockHash, gSign.GetHexString(), tx.Hash.Hex(), gpk.GetHexString(), tx.RawTransaction)
 	}
 
@@ -198,3 +202,23 @@ func (p *Processor) GetBlockMinElapse(height uint64) int32 {
 		currentEpoch, result, firstBlock.Height, lastBlock.Height, spends, realCount)
 	return result
 }
+
+// recordBlamedMembers records the members sent bad signature pieces for the given block, for punishment and diagnostics
+func (p *Processor) recordBlamedMembers(bh *types.BlockHeader, ids []groupsig.ID) {
+	blog := newBizLog("recordBlamedMembers")
+	blog.warn("bad signature pieces excluded, height=%v, hash=%v, members=%v", bh.Height, bh.Hash, ids)
+	if p.blamedMembers != nil {
+		p.blamedMembers.Add(bh.Hash, ids)
+	}
+}
+
+// GetBlamedMembers returns the members sent bad signature pieces for the given block
+func (p *Processor) GetBlamedMembers(hash common.Hash) []groupsig.ID {
+	if p.blamedMembers == nil {
+		return nil
+	}
+	if v, ok := p.blamedMembers.Get(hash); ok {
+		return v.([]groupsig.ID)
+	}
+	return nil
+}
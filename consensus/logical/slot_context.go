@@ -22,6 +22,7 @@ import (
 	"github.com/darren0718/zvchain/common"
 	"github.com/darren0718/zvchain/consensus/groupsig"
 	"github.com/darren0718/zvchain/consensus/model"
+	"github.com/darren0718/zvchain/middleware/metrics"
 	"github.com/darren0718/zvchain/middleware/types"
 	"gopkg.in/fatih/set.v0"
 )
@@ -54,6 +55,19 @@ const (
 	slRewardSent
 )
 
+// Slot status names counted in the metrics
+var slotStatusNames = map[int32]string{
+	slIniting:       "initing",
+	slWaiting:       "waiting",
+	slSigned:        "signed",
+	slRecovered:     "recovered",
+	slVerified:      "verified",
+	slSuccess:       "success",
+	slFailed:        "failed",
+	slRewardSignReq: "reward_sign_req",
+	slRewardSent:    "reward_sent",
+}
+
 // SlotContext stores the contextual infos of a specified block proposal
 type SlotContext struct {
 
@@ -86,7 +100,9 @@ func createSlotContext(bh *types.BlockHeader, threshold int) *SlotContext {
 }
 
 func (sc *SlotContext) setSlotStatus(st int32) {
-	atomic.StoreInt32(&sc.slotStatus, st)
+	if old := atomic.SwapInt32(&sc.slotStatus, st); old != st {
+		metrics.ConsensusSlots.WithLabelValues(slotStatusNames[st]).Inc()
+	}
 }
 
 func (sc *SlotContext) IsFailed() bool {
@@ -105,23 +121,40 @@ func (sc SlotContext) MessageSize() int {
 	return sc.gSignGenerator.WitnessSize()
 }
 
-// VerifyGroupSigns verifies both the verifyGroup signature and the random number(also a signature in fact)
-func (sc *SlotContext) VerifyGroupSigns(pk groupsig.Pubkey, preRandom []byte) bool {
+// VerifyGroupSigns verifies both the verifyGroup signature and the random number(also a signature in fact).
+// If the verification fails, the bad pieces are found out with the member public keys and excluded, then the
+// signatures are recovered again with the pieces left. The slot goes back to waiting for more pieces if not
+// enough left
+func (sc *SlotContext) VerifyGroupSigns(group *verifyGroup, preRandom []byte) bool {
 	if sc.IsVerified() || sc.IsSuccess() {
 		return true
 	}
-	b := sc.gSignGenerator.VerifyGroupSign(pk, sc.BH.Hash.Bytes())
-	if b {
-		b = sc.rSignGenerator.VerifyGroupSign(pk, preRandom)
-		if b {
+	pk := group.header.gpk
+	for {
+		if sc.gSignGenerator.VerifyGroupSign(pk, sc.BH.Hash.Bytes()) && sc.rSignGenerator.VerifyGroupSign(pk, preRandom) {
 			// Group signature verification
 			sc.setSlotStatus(slVerified)
+			return true
+		}
+		bad := sc.gSignGenerator.Blame(sc.BH.Hash.Bytes(), group.getMemberPubkey)
+		bad = append(bad, sc.rSignGenerator.Blame(preRandom, group.getMemberPubkey)...)
+		if len(bad) == 0 {
+			sc.setSlotStatus(slFailed)
+			return false
+		}
+		// Both generators hold the pieces from the same members, so they recover or not at the same time
+		gRecovered := sc.gSignGenerator.Exclude(bad)
+		rRecovered := sc.rSignGenerator.Exclude(bad)
+		if !gRecovered || !rRecovered {
+			sc.setSlotStatus(slWaiting)
+			return false
 		}
 	}
-	if !b {
-		sc.setSlotStatus(slFailed)
-	}
-	return b
+}
+
+// Blamed returns the members whose signature pieces are excluded for invalid
+func (sc *SlotContext) Blamed() []groupsig.ID {
+	return sc.gSignGenerator.Blamed()
 }
 
 func (sc *SlotContext) IsVerified() bool {
@@ -16,11 +16,15 @@
 package logical
 
 import (
-	"github.com/darren0718/zvchain/common"
-	"gopkg.in/fatih/set.v0"
 	"math/rand"
 	"sync"
 	"testing"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/consensus/base"
+	"github.com/darren0718/zvchain/consensus/groupsig"
+	"github.com/darren0718/zvchain/middleware/types"
+	"gopkg.in/fatih/set.v0"
 )
 
 func TestSlotContext_addSignedTxHash_Concurrent(t *testing.T) {
@@ -39,3 +43,89 @@ func TestSlotContext_addSignedTxHash_Concurrent(t *testing.T) {
 	wg.Wait()
 	t.Logf("finished, add size %v", sc.signedRewardTxHashs.Size())
 }
+
+func newThresholdGroup4Test(n, k int) (*verifyGroup, []groupsig.Seckey) {
+	sec := groupsig.NewSeckeyFromRand(base.NewRand())
+	msk := sec.GetMasterSecretKey(k)
+	vg := &verifyGroup{
+		header:   &groupHeader{gpk: *groupsig.NewPubkeyFromSeckey(*sec), threshold: uint32(k)},
+		memIndex: make(map[string]int),
+	}
+	secs := make([]groupsig.Seckey, n)
+	for i := 0; i < n; i++ {
+		id := groupsig.DeserializeID([]byte{1, 2, 3, byte(i)})
+		secs[i].Set(msk, &id)
+		vg.members = append(vg.members, &member{id: id, pk: *groupsig.NewPubkeyFromSeckey(secs[i])})
+		vg.memIndex[id.GetAddrString()] = i
+	}
+	return vg, secs
+}
+
+func TestSlotContext_VerifyGroupSigns_Blame(t *testing.T) {
+	vg, secs := newThresholdGroup4Test(5, 3)
+	bh := &types.BlockHeader{Hash: common.BytesToHash([]byte("block"))}
+	preRandom := []byte("pre random")
+	sc := createSlotContext(bh, 3)
+
+	accept := func(i int, bad bool) {
+		sign := groupsig.Sign(secs[i], bh.Hash.Bytes())
+		if bad {
+			sign = groupsig.Sign(secs[i], []byte("wrong"))
+		}
+		sc.AcceptVerifyPiece(vg.members[i].id, sign, groupsig.Sign(secs[i], preRandom))
+	}
+	accept(0, true)
+	accept(1, false)
+	accept(2, false)
+
+	// Member 0 excluded and not enough pieces left
+	if sc.VerifyGroupSigns(vg, preRandom) {
+		t.Fatalf("verify should fail with bad piece")
+	}
+	if !sc.IsWaiting() {
+		t.Fatalf("slot should wait for more pieces, status %v", sc.GetSlotStatus())
+	}
+	if blamed := sc.Blamed(); len(blamed) != 1 || !blamed[0].IsEqual(vg.members[0].id) {
+		t.Fatalf("member 0 should be blamed, got %v", blamed)
+	}
+
+	// Pieces from the blamed member are rejected
+	accept(0, false)
+	if sc.MessageSize() != 2 {
+		t.Fatalf("piece of blamed member should be rejected")
+	}
+	accept(3, false)
+	if !sc.IsRecovered() {
+		t.Fatalf("slot should be recovered")
+	}
+	if !sc.VerifyGroupSigns(vg, preRandom) {
+		t.Fatalf("verify should pass with good pieces")
+	}
+}
+
+func TestProcessor_RecordBlamedMembers(t *testing.T) {
+	vg, secs := newThresholdGroup4Test(5, 3)
+	bh := &types.BlockHeader{Hash: common.BytesToHash([]byte("block"))}
+	preRandom := []byte("pre random")
+	sc := createSlotContext(bh, 3)
+	for i := 0; i < 3; i++ {
+		sign := groupsig.Sign(secs[i], bh.Hash.Bytes())
+		if i == 2 {
+			sign = groupsig.Sign(secs[i], []byte("wrong"))
+		}
+		sc.AcceptVerifyPiece(vg.members[i].id, sign, groupsig.Sign(secs[i], preRandom))
+	}
+	if sc.VerifyGroupSigns(vg, preRandom) {
+		t.Fatalf("verify should fail with bad piece")
+	}
+
+	p := &Processor{blamedMembers: common.MustNewLRUCache(10)}
+	p.recordBlamedMembers(bh, sc.Blamed())
+	blamed := p.GetBlamedMembers(bh.Hash)
+	if len(blamed) != 1 || !blamed[0].IsEqual(vg.members[2].id) {
+		t.Fatalf("member 2 should be recorded, got %v", blamed)
+	}
+	if p.GetBlamedMembers(common.BytesToHash([]byte("other"))) != nil {
+		t.Fatalf("no member should be recorded for other blocks")
+	}
+}
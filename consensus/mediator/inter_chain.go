@@ -94,6 +94,10 @@ func (helper *ConsensusHelperImpl) VerifyBlockHeaders(pre, bh *types.BlockHeader
 	return Proc.VerifyBlockHeaders(pre, bh)
 }
 
+func (helper *ConsensusHelperImpl) VerifyBlockHeadersBatch(pre *types.BlockHeader, bhs []*types.BlockHeader) (verified int, err error) {
+	return Proc.VerifyBlockHeadersBatch(pre, bhs)
+}
+
 func (helper *ConsensusHelperImpl) GroupSkipCountsBetween(preBH *types.BlockHeader, h uint64) map[common.Hash]uint16 {
 	return Proc.GroupSkipCountsBetween(preBH, h)
 }
@@ -28,6 +28,7 @@ type GroupSignGenerator struct {
 	witnesses map[string]groupsig.Signature // Signature pieces
 	threshold int                           // Threshold
 	gSign     groupsig.Signature            // Group signature generated
+	blamed    map[string]groupsig.ID        // Members excluded for sending bad pieces
 	lock      sync.RWMutex
 }
 
@@ -35,6 +36,7 @@ func NewGroupSignGenerator(threshold int) *GroupSignGenerator {
 	return &GroupSignGenerator{
 		witnesses: make(map[string]groupsig.Signature, 0),
 		threshold: threshold,
+		blamed:    make(map[string]groupsig.ID),
 	}
 }
 
@@ -60,6 +62,9 @@ func (gs *GroupSignGenerator) AddWitnessForce(id groupsig.ID, signature groupsig
 	if _, ok := gs.witnesses[key]; ok {
 		return false, false
 	}
+	if _, ok := gs.blamed[key]; ok {
+		return false, false
+	}
 	gs.witnesses[key] = signature
 
 	if len(gs.witnesses) >= gs.threshold {
@@ -103,6 +108,42 @@ func (gs *GroupSignGenerator) VerifyGroupSign(gpk groupsig.Pubkey, data []byte)
 	return groupsig.VerifySig(gpk, data, gs.GetGroupSign())
 }
 
+// Blame verifies each piece with the member public key and returns the members whose piece is invalid
+func (gs *GroupSignGenerator) Blame(data []byte, memberPk groupsig.MemberPubkeyGetter) []groupsig.ID {
+	gs.lock.RLock()
+	defer gs.lock.RUnlock()
+	return groupsig.BlameSignaturePieces(gs.witnesses, data, memberPk)
+}
+
+// Exclude removes the pieces of the given members and rejects their pieces afterwards.
+// The group signature is recovered again with the pieces left, and it returns whether recovered
+func (gs *GroupSignGenerator) Exclude(ids []groupsig.ID) bool {
+	gs.lock.Lock()
+	defer gs.lock.Unlock()
+
+	for _, id := range ids {
+		key := id.GetAddrString()
+		delete(gs.witnesses, key)
+		gs.blamed[key] = id
+	}
+	gs.gSign = groupsig.Signature{}
+	if len(gs.witnesses) >= gs.threshold {
+		return gs.generate()
+	}
+	return false
+}
+
+// Blamed returns the members excluded
+func (gs *GroupSignGenerator) Blamed() []groupsig.ID {
+	gs.lock.RLock()
+	defer gs.lock.RUnlock()
+	ids := make([]groupsig.ID, 0, len(gs.blamed))
+	for _, id := range gs.blamed {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 func (gs *GroupSignGenerator) WitnessSize() int {
 	gs.lock.RLock()
 	defer gs.lock.RUnlock()
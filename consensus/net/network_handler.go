@@ -74,47 +74,47 @@ func (c *ConsensusHandler) Handle(sourceID string, msg network.Message) error {
 	case network.CastVerifyMsg:
 		m, e := unMarshalConsensusCastMessage(body)
 		if e != nil {
-			err = e
-			return e
+			err = reportBadMessage(sourceID, e)
+			return err
 		}
 		err = c.processor.OnMessageCast(m)
 	case network.VerifiedCastMsg:
 		m, e := unMarshalConsensusVerifyMessage(body)
 		if e != nil {
-			err = e
-			return e
+			err = reportBadMessage(sourceID, e)
+			return err
 		}
 
 		err = c.processor.OnMessageVerify(m)
 	case network.CastRewardSignReq:
 		m, e := unMarshalCastRewardReqMessage(body)
 		if e != nil {
-			err = e
-			return e
+			err = reportBadMessage(sourceID, e)
+			return err
 		}
 
 		err = c.processor.OnMessageCastRewardSignReq(m)
 	case network.CastRewardSignGot:
 		m, e := unMarshalCastRewardSignMessage(body)
 		if e != nil {
-			err = e
-			return e
+			err = reportBadMessage(sourceID, e)
+			return err
 		}
 
 		err = c.processor.OnMessageCastRewardSign(m)
 	case network.ReqProposalBlock:
 		m, e := unmarshalReqProposalBlockMessage(body)
 		if e != nil {
-			err = e
-			return e
+			err = reportBadMessage(sourceID, e)
+			return err
 		}
 		err = c.processor.OnMessageReqProposalBlock(m, sourceID)
 
 	case network.ResponseProposalBlock:
 		m, e := unmarshalResponseProposalBlockMessage(body)
 		if e != nil {
-			err = e
-			return e
+			err = reportBadMessage(sourceID, e)
+			return err
 		}
 		err = c.processor.OnMessageResponseProposalBlock(m)
 		logger.Debugf("recv proposal block %v response from %v", m.Hash, sourceID)
@@ -122,3 +122,9 @@ func (c *ConsensusHandler) Handle(sourceID string, msg network.Message) error {
 
 	return nil
 }
+
+// reportBadMessage reports the source of the message failed to decode to the network
+func reportBadMessage(sourceID string, err error) error {
+	network.ReportPeer(sourceID, network.PeerEventBadMessage)
+	return err
+}
@@ -25,6 +25,7 @@ import (
 	"math"
 	"math/rand"
 	"sync"
+	"time"
 
 	"github.com/darren0718/zvchain/middleware/notify"
 	tas_middleware_pb "github.com/darren0718/zvchain/middleware/pb"
@@ -65,7 +66,8 @@ var (
 var blockSync *blockSyncer
 
 type blockSyncer struct {
-	chain *FullBlockChain
+	chain       *FullBlockChain
+	networkImpl network.Network
 
 	candidatePool map[string]*types.CandidateBlockHeader
 	syncingPeers  map[string]*types.SyncingPeerTop
@@ -77,6 +79,10 @@ type blockSyncer struct {
 	syncNeightborTimeout uint32
 
 	notifyCounters *lru.Cache
+
+	headerSync        *headerSyncer
+	headerSyncEnabled bool
+	headerSyncFailed  *lru.Cache // Time of the last failed header-first sync by peer
 }
 
 type topBlockInfo struct {
@@ -91,20 +97,23 @@ func newTopBlockInfo(topBH *types.BlockHeader) *topBlockInfo {
 	}
 }
 
-func newBlockSyncer(chain *FullBlockChain) *blockSyncer {
+func newBlockSyncer(chain *FullBlockChain, networkImpl network.Network) *blockSyncer {
 	return &blockSyncer{
 		candidatePool:        make(map[string]*types.CandidateBlockHeader),
 		chain:                chain,
+		networkImpl:          networkImpl,
 		syncingPeers:         make(map[string]*types.SyncingPeerTop),
 		notifyCounters:       common.MustNewLRUCache(notifyCounterCacheSize),
 		syncNeightborTimeout: uint32(common.GlobalConf.GetInt(configSec, configSyncNeightborTimeout, defaultSyncNeightborTimeout)),
+		headerSyncEnabled:    common.GlobalConf.GetBool(configSec, configHeaderSync, true),
+		headerSyncFailed:     common.MustNewLRUCache(blockSyncCandidatePoolSize),
 	}
 }
 
 // InitBlockSyncer initialize the blockSyncer. Register the ticker for sending and requesting blocks to neighbors timely
 // and also subscribe these events to handle requests from neighbors
 func InitBlockSyncer(chain *FullBlockChain) {
-	blockSync = newBlockSyncer(chain)
+	blockSync = newBlockSyncer(chain, network.GetNetInstance())
 	blockSync.ticker = blockSync.chain.ticker
 	blockSync.logger = log.BlockSyncLogger
 	blockSync.ticker.RegisterPeriodicRoutine(tickerSendLocalTop, blockSync.notifyLocalTopBlockRoutine, sendLocalTopInterval)
@@ -117,6 +126,11 @@ func InitBlockSyncer(chain *FullBlockChain) {
 	notify.BUS.Subscribe(notify.BlockReq, blockSync.blockReqHandler)
 	notify.BUS.Subscribe(notify.BlockResponse, blockSync.blockResponseMsgHandler)
 
+	blockSync.headerSync = newHeaderSyncer(chain, chain.consensusHelper, blockSync.networkImpl, blockSync.logger, blockSync.syncNeightborTimeout)
+	blockSync.headerSync.peers = blockSync.headerSyncPeers
+	blockSync.headerSync.onFinish = blockSync.onHeaderSyncFinish
+	blockSync.headerSync.subscribe()
+
 	blockSync.logger.Debugf("init block syncer,block sync timeout:%v", blockSync.syncNeightborTimeout)
 
 }
@@ -249,6 +263,7 @@ func (bs *blockSyncer) addBlackWithLock(candidateID string) {
 
 func (bs *blockSyncer) addBlackProcess(candidateID string) {
 	peerManagerImpl.addEvilCount(candidateID)
+	network.ReportPeer(candidateID, network.PeerEventBadBlock)
 	bs.logger.Debugf("getBestCandidate verify blockHeader error!we will add it to evil,peer is %v", candidateID)
 }
 
@@ -331,6 +346,11 @@ func (bs *blockSyncer) syncFrom(from string) bool {
 	bs.lock.Lock()
 	defer bs.lock.Unlock()
 
+	if bs.headerSync != nil && bs.headerSync.isSyncing() {
+		bs.logger.Debugf("header sync in progress, won't sync")
+		return false
+	}
+
 	candidate, candidateTop := bs.getCandidateById(from)
 	if candidate == "" {
 		bs.logger.Debugf("Get no candidate for sync!")
@@ -374,10 +394,47 @@ func (bs *blockSyncer) syncFrom(from string) bool {
 
 	notify.BUS.Publish(notify.BlockSync, &syncMessage{CandidateInfo: candInfo})
 
+	if bs.useHeaderSync(candidate, candidateTop.BH.Height, beginHeight) {
+		return bs.headerSync.start(candidate, candidateTop.BH, beginHeight)
+	}
 	bs.requestBlock(candInfo, candidateTop)
 	return true
 }
 
+// useHeaderSync returns whether to sync from the candidate header-first, which is used
+// if the candidate supports it and is far higher
+func (bs *blockSyncer) useHeaderSync(candidate string, candidateHeight, beginHeight uint64) bool {
+	if bs.headerSync == nil || !bs.headerSyncEnabled || candidateHeight < beginHeight+headerSyncMinGap {
+		return false
+	}
+	if v, ok := bs.headerSyncFailed.Get(candidate); ok && time.Since(v.(time.Time)) < headerSyncRetryInterval {
+		return false
+	}
+	return network.PeerSupports(candidate, network.CapHeaderSync)
+}
+
+// headerSyncPeers returns the top heights of the candidates supporting the header-first sync
+func (bs *blockSyncer) headerSyncPeers() map[string]uint64 {
+	bs.lock.RLock()
+	defer bs.lock.RUnlock()
+	peers := make(map[string]uint64, len(bs.candidatePool))
+	for id, top := range bs.candidatePool {
+		if network.PeerSupports(id, network.CapHeaderSync) {
+			peers[id] = top.BH.Height
+		}
+	}
+	return peers
+}
+
+func (bs *blockSyncer) onHeaderSyncFinish(source string, ok bool) {
+	if !ok {
+		// Sync from the peer block by block for a while
+		bs.headerSyncFailed.Add(source, time.Now())
+		return
+	}
+	go bs.trySyncRoutine()
+}
+
 func (bs *blockSyncer) requestBlock(ci *SyncCandidateInfo, top *types.CandidateBlockHeader) {
 	id := ci.Candidate
 	height := ci.ReqHeight
@@ -399,7 +456,7 @@ func (bs *blockSyncer) requestBlock(ci *SyncCandidateInfo, top *types.CandidateB
 	}
 
 	message := network.Message{Code: network.ReqBlock, Body: body}
-	network.GetNetInstance().Send(id, message)
+	bs.networkImpl.Send(id, message)
 
 	bs.syncingPeers[id] = &types.SyncingPeerTop{top, ci.ReqHeight}
 
@@ -427,7 +484,7 @@ func (bs *blockSyncer) notifyLocalTopBlockRoutine() bool {
 		blacklist = append(blacklist, nodeStr.(string))
 	}
 
-	network.GetNetInstance().TransmitToNeighbor(message, blacklist)
+	bs.networkImpl.TransmitToNeighbor(message, blacklist)
 	return true
 }
 
@@ -526,6 +583,10 @@ func (bs *blockSyncer) blockResponseMsgHandler(msg notify.Message) error {
 
 		allSuccess := true
 		hasAddBlack := false
+		blocks = bs.preVerifyBlocks(source, blocks)
+		if len(blocks) == 0 {
+			return nil
+		}
 		err := bs.chain.batchAddBlockOnChain(source, false, blocks, func(b *types.Block, ret types.AddBlockResult) bool {
 			bs.logger.Debugf("sync block from %v, hash=%v,height=%v,addResult=%v", source, b.Header.Hash.Hex(), b.Header.Height, ret)
 			if ret == types.AddBlockSucc || ret == types.AddBlockExisted {
@@ -553,6 +614,26 @@ func (bs *blockSyncer) blockResponseMsgHandler(msg notify.Message) error {
 	return nil
 }
 
+// preVerifyBlocks verifies the signatures of the blocks in batch before adding them on chain.
+// Blocks after the first illegal one are discarded and the source is added to blacklist
+func (bs *blockSyncer) preVerifyBlocks(source string, blocks []*types.Block) []*types.Block {
+	pre := bs.chain.QueryBlockHeaderByHash(blocks[0].Header.PreHash)
+	if pre == nil {
+		return blocks
+	}
+	headers := make([]*types.BlockHeader, len(blocks))
+	for i, b := range blocks {
+		headers[i] = b.Header
+	}
+	verified, err := bs.chain.consensusHelper.VerifyBlockHeadersBatch(pre, headers)
+	if err != nil {
+		bs.logger.Warnf("batch verify blocks from %v error:%v, discard blocks from %v", source, err, headers[verified].Height)
+		bs.addBlackWithLock(source)
+		return blocks[:verified]
+	}
+	return blocks
+}
+
 func (bs *blockSyncer) addCandidatePool(source string, header *types.BlockHeader) {
 	bs.lock.Lock()
 	defer bs.lock.Unlock()
@@ -608,17 +689,17 @@ func (bs *blockSyncer) blockReqHandler(msg notify.Message) error {
 
 	bs.logger.Debugf("Rcv block request:reqHeight:%d, reqSize:%v, localHeight:%d", br.ReqHeight, br.ReqSize, localHeight)
 	blocks := bs.chain.BatchGetBlocksAfterHeight(br.ReqHeight, int(br.ReqSize))
-	responseBlocks(m.Source(), blocks)
+	bs.responseBlocks(m.Source(), blocks)
 	return nil
 }
 
-func responseBlocks(targetID string, blocks []*types.Block) {
+func (bs *blockSyncer) responseBlocks(targetID string, blocks []*types.Block) {
 	body, e := marshalBlockMsgResponse(&blockResponseMessage{Blocks: blocks})
 	if e != nil {
 		return
 	}
 	message := network.Message{Code: network.BlockResponseMsg, Body: body}
-	network.GetNetInstance().Send(targetID, message)
+	bs.networkImpl.Send(targetID, message)
 }
 
 func marshalBlockMsgResponse(bmr *blockResponseMessage) ([]byte, error) {
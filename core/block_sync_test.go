@@ -15,6 +15,7 @@ import (
 	"github.com/darren0718/zvchain/log"
 	tas_middleware_pb "github.com/darren0718/zvchain/middleware/pb"
 	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/darren0718/zvchain/network"
 	"github.com/darren0718/zvchain/storage/account"
 	"github.com/gogo/protobuf/proto"
 )
@@ -29,7 +30,7 @@ func init() {
 }
 func initContext(t *testing.T) {
 	initContext4Test(t)
-	blockSyncForTest = newBlockSyncer(BlockChainImpl)
+	blockSyncForTest = newBlockSyncer(BlockChainImpl, network.GetNetInstance())
 	blockSyncForTest.logger = log.BlockSyncLogger
 
 	initPeerManager()
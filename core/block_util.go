@@ -26,7 +26,6 @@ import (
 	"github.com/darren0718/zvchain/storage/account"
 	"github.com/darren0718/zvchain/storage/serialize"
 	"github.com/darren0718/zvchain/storage/trie"
-	"github.com/darren0718/zvchain/tvm"
 )
 
 const (
@@ -114,8 +113,12 @@ func setupFoundationContract(stateDB *account.AccountDB, adminAddr common.Addres
 	txRaw.Source = &addr
 	txRaw.Value = &types.BigInt{Int: *big.NewInt(0)}
 	txRaw.GasLimit = &types.BigInt{Int: *big.NewInt(300000)}
-	controller := tvm.NewController(stateDB, nil, nil, types.NewTransaction(txRaw, txRaw.GenHash()), 0, nil)
-	contract := tvm.Contract{
+	engine := newContractEngine(stateDB, nil, nil, types.NewTransaction(txRaw, txRaw.GenHash()), 0, nil)
+	// The code in the format of the tvm contract
+	contract := struct {
+		Code         string `json:"code"`
+		ContractName string `json:"contract_name"`
+	}{
 		Code:         code,
 		ContractName: "Foundation",
 	}
@@ -127,11 +130,9 @@ func setupFoundationContract(stateDB *account.AccountDB, adminAddr common.Addres
 	stateDB.CreateAccount(contractAddress)
 	stateDB.SetCode(contractAddress, jsonBytes)
 
-	contract.ContractAddress = &contractAddress
-	controller.VM.SetGas(500000)
-	_, _, transactionError := controller.Deploy(&contract)
+	transactionError := engine.Deploy(contractAddress)
 	if transactionError != nil {
 		panic(fmt.Sprintf("deploy FoundationContract error: %s", transactionError.Message))
 	}
-	return contract.ContractAddress
+	return &contractAddress
 }
@@ -272,6 +272,7 @@ func initBlockChain(helper types.ConsensusHelper, minerAccount types.Account) er
 	chain.cpChecker.init()
 
 	initStakeGetter(MinerManagerImpl, chain)
+	registerChainMetrics(chain)
 
 	chain.LogDbStats()
 	return nil
@@ -29,6 +29,7 @@ import (
 	"github.com/darren0718/zvchain/monitor"
 
 	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/middleware/metrics"
 	"github.com/darren0718/zvchain/middleware/notify"
 	"github.com/darren0718/zvchain/middleware/types"
 	"github.com/darren0718/zvchain/storage/account"
@@ -269,6 +270,7 @@ func (chain *FullBlockChain) addBlockOnChain(source string, block *types.Block)
 
 	defer func() {
 		traceLog.Log("ret=%v, err=%v", ret, err)
+		metrics.BlockProcessTime.WithLabelValues(addBlockResultLabel(ret)).Observe(time.Since(begin).Seconds())
 		Logger.Debugf("addBlockOnchain hash=%v, height=%v, err=%v, cost=%v", block.Header.Hash, block.Header.Height, err, time.Since(begin).String())
 	}()
 
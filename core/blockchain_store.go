@@ -427,6 +427,29 @@ func (chain *FullBlockChain) batchGetBlocksAfterHeight(h uint64, limit int) []*t
 	return blocks
 }
 
+// batchGetBlockHeadersAfterHeight returns the headers from the specified height, at most limit
+func (chain *FullBlockChain) batchGetBlockHeadersAfterHeight(h uint64, limit int) []*types.BlockHeader {
+	headers := make([]*types.BlockHeader, 0)
+	iter := chain.blockHeight.NewIterator()
+	defer iter.Release()
+
+	// No higher block after the specified block height
+	if !iter.Seek(common.UInt64ToByte(h)) {
+		return headers
+	}
+	for len(headers) < limit {
+		header := chain.queryBlockHeaderByHash(common.BytesToHash(iter.Value()))
+		if header == nil {
+			break
+		}
+		headers = append(headers, header)
+		if !iter.Next() {
+			break
+		}
+	}
+	return headers
+}
+
 // scanBlockHeightsInRange returns the heights of block in the given height range. the block with startHeight and endHeight
 // will be included
 func (chain *FullBlockChain) scanBlockHeightsInRange(startHeight uint64, endHeight uint64) []uint64 {
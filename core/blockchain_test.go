@@ -658,6 +658,10 @@ func (helper *ConsensusHelperImpl4Test) VerifyBlockHeaders(pre, bh *types.BlockH
 	return true, nil
 }
 
+func (helper *ConsensusHelperImpl4Test) VerifyBlockHeadersBatch(pre *types.BlockHeader, bhs []*types.BlockHeader) (verified int, err error) {
+	return len(bhs), nil
+}
+
 type Account4Test struct {
 	Address  string
 	Pk       string
@@ -38,6 +38,7 @@ const tickerForkProcess = "fork_process_ticker"
 
 type blockVerifier interface {
 	VerifyBlockHeaders(pre, bh *types.BlockHeader) (ok bool, err error)
+	VerifyBlockHeadersBatch(pre *types.BlockHeader, bhs []*types.BlockHeader) (verified int, err error)
 }
 
 type peerCheckpoint interface {
@@ -199,6 +200,7 @@ func (fp *forkProcessor) processFork(targetNode string, bh *types.BlockHeader) (
 	// In order to solve this problem, we designed that when A is more than 1 epoch higher than B, A also triggers the fork process with B.
 	ret = true
 	ctx = fp.updateContext(targetNode, targetTop)
+	countForkEvent(forkEventStart)
 
 	fp.logger.Debugf("fork process from %v: targetTop:%v-%v, local:%v-%v", ctx.target, ctx.targetTop.Hash.Hex(), ctx.targetTop.Height, ctx.localTop.Hash.Hex(), ctx.localTop.Height)
 	fp.findAncestorRequest(fp.chain.QueryTopBlock().Hash)
@@ -213,6 +215,7 @@ func (fp *forkProcessor) reqPieceTimeout() {
 		return
 	}
 	fp.logger.Warnf("req piece from %v timeout, target top %v", fp.syncCtx.target, fp.syncCtx.targetTop.Height)
+	countForkEvent(forkEventTimeout)
 	peerManagerImpl.timeoutPeer(fp.syncCtx.target)
 	peerManagerImpl.updateReqBlockCnt(fp.syncCtx.target, false)
 	fp.reset()
@@ -606,13 +609,31 @@ func (fp *forkProcessor) allBlocksReceived() {
 		return
 	}
 	pre = first
-	// Checks the blocks legality
-	for _, block := range blocks {
-		if ok, err := fp.verifier.VerifyBlockHeaders(pre, block.Header); !ok {
-			fp.logger.Errorf("verify block headers err:%v %v %v", block.Header.Hash, block.Header.Height, err)
+	// Checks the blocks legality, signatures verified in batch
+	headers := make([]*types.BlockHeader, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header
+	}
+	verified, err := fp.verifier.VerifyBlockHeadersBatch(pre, headers)
+	if err != nil {
+		bh := headers[verified]
+		fp.logger.Errorf("verify block headers err:%v %v %v", bh.Hash, bh.Height, err)
+		network.ReportPeer(fp.syncCtx.target, network.PeerEventBadForkBlock)
+		countForkEvent(forkEventBadBlock)
+		return
+	}
+	if verified > 0 {
+		pre = headers[verified-1]
+	}
+	// The headers left can't be batch verified currently, check them one by one
+	for _, bh := range headers[verified:] {
+		if ok, err := fp.verifier.VerifyBlockHeaders(pre, bh); !ok {
+			fp.logger.Errorf("verify block headers err:%v %v %v", bh.Hash, bh.Height, err)
+			network.ReportPeer(fp.syncCtx.target, network.PeerEventBadForkBlock)
+			countForkEvent(forkEventBadBlock)
 			return
 		}
-		pre = block.Header
+		pre = bh
 	}
 	// Peer cp
 	peerCP := fp.peerCP.checkPointOf(blocks)
@@ -629,16 +650,20 @@ func (fp *forkProcessor) allBlocksReceived() {
 		localLast := fp.chain.QueryBlockHeaderFloor(types.EpochAt(peerLast.Height).End() - 1)
 		if types.NewBlockWeight(localLast).MoreWeight(types.NewBlockWeight(peerLast)) {
 			fp.logger.Infof("local more weight than peer, local %v %v, peer %v %v", localLast.Height, localLast.Hash, peerLast.Height, peerLast.Hash)
+			countForkEvent(forkEventLocalMoreWeight)
 			return
 		}
 	}
 	// Accept peer fork, and add the chain slice to local
-	err := fp.chain.batchAddBlockOnChain(fp.syncCtx.target, true, blocks, func(b *types.Block, ret types.AddBlockResult) bool {
+	err = fp.chain.batchAddBlockOnChain(fp.syncCtx.target, true, blocks, func(b *types.Block, ret types.AddBlockResult) bool {
 		fp.logger.Debugf("sync fork block from %v, hash=%v,height=%v,addResult=%v", fp.syncCtx.target, b.Header.Hash, b.Header.Height, ret)
 		return ret == types.AddBlockSucc || ret == types.AddBlockExisted
 	})
 	if err != nil {
 		fp.logger.Warnf("add blocks from %v error:%v, block range %v-%v", fp.syncCtx.target, err, blocks[0].Header.Height, blocks[len(blocks)-1].Header.Height)
+		countForkEvent(forkEventAddFailed)
+	} else {
+		countForkEvent(forkEventAccepted)
 	}
 }
 
@@ -49,6 +49,8 @@ var (
 	chains     = make(map[string]*FullBlockChain)
 	chainPath1 = "d_b"
 	chainPath2 = "d_b2"
+	chainPath3 = "d_b3"
+	chainPath4 = "d_b4"
 	id1        = "1"
 	id2        = "2"
 )
@@ -209,6 +211,8 @@ func build2Chains(chain1Limit, chain2Limit uint64, forkLength uint64) (chain1, c
 func clearDatas() {
 	os.RemoveAll(chainPath1)
 	os.RemoveAll(chainPath2)
+	os.RemoveAll(chainPath3)
+	os.RemoveAll(chainPath4)
 	os.RemoveAll("logs")
 }
 
@@ -0,0 +1,632 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/middleware/notify"
+	tas_middleware_pb "github.com/darren0718/zvchain/middleware/pb"
+	"github.com/darren0718/zvchain/middleware/ticker"
+	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/darren0718/zvchain/network"
+	"github.com/gogo/protobuf/proto"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxReqHeaderCount = 256  // Maximum number of headers per request
+	maxReqBodyCount   = 64   // Maximum number of bodies per request
+	headerSyncWindow  = 2048 // Maximum number of headers fetched in one header-first sync
+
+	// Gap to the candidate top below which the blocks are synced as a whole
+	headerSyncMinGap = maxReqBlockCount
+	// Interval of retrying the header-first sync with the peer failed
+	headerSyncRetryInterval = time.Minute
+)
+
+const (
+	tickerHeaderSyncTimeout = "header_sync_timeout"
+	tickerBodySyncTimeout   = "body_sync_timeout"
+	configHeaderSync        = "header_sync"
+)
+
+// headerSyncTask is the state of a header-first sync. The header chain is fetched from
+// the source peer and verified first, then the bodies of the verified headers are
+// downloaded from all the peers having them in parallel, and the blocks are committed in order
+type headerSyncTask struct {
+	source  string             // Peer serving the headers
+	top     *types.BlockHeader // Top block of the source when the sync started
+	from    uint64             // Height the sync started from
+	headers []*types.BlockHeader
+	index   map[common.Hash]int // Index of the headers by hash
+	bodies  map[common.Hash][]*types.RawTransaction
+
+	headerSeq   int  // Sequence of the pending header request, 0 if none
+	headersDone bool // No more headers to fetch
+
+	assigned map[common.Hash]string // Peer each pending body requested from
+	requests map[string]*bodyRequest
+	dropped  map[string]bool // Peers dropped for being slow or lying
+	seq      int             // Sequence of the requests, distinguishing the stale timeouts
+
+	committed int // Number of the headers committed on chain
+}
+
+type bodyRequest struct {
+	seq    int
+	hashes []common.Hash
+}
+
+func (t *headerSyncTask) nextHeight() uint64 {
+	if len(t.headers) == 0 {
+		return t.from
+	}
+	return t.headers[len(t.headers)-1].Height + 1
+}
+
+func (t *headerSyncTask) lastHeader() *types.BlockHeader {
+	if len(t.headers) == 0 {
+		return nil
+	}
+	return t.headers[len(t.headers)-1]
+}
+
+// headerSyncer runs the header-first sync, one task at a time
+type headerSyncer struct {
+	chain    *FullBlockChain
+	verifier blockVerifier
+	sender   msgSender
+	ticker   *ticker.GlobalTicker
+	logger   *logrus.Logger
+	timeout  uint32
+
+	// peers returns the top heights of the peers supporting the header-first sync
+	peers func() map[string]uint64
+	// onFinish is called after the task finished, ok if some blocks committed and no error occurred
+	onFinish func(source string, ok bool)
+
+	lock     sync.Mutex
+	task     *headerSyncTask
+	commitMu sync.Mutex
+}
+
+func newHeaderSyncer(chain *FullBlockChain, verifier blockVerifier, sender msgSender, logger *logrus.Logger, timeout uint32) *headerSyncer {
+	return &headerSyncer{
+		chain:    chain,
+		verifier: verifier,
+		sender:   sender,
+		ticker:   chain.ticker,
+		logger:   logger,
+		timeout:  timeout,
+		peers:    func() map[string]uint64 { return nil },
+		onFinish: func(source string, ok bool) {},
+	}
+}
+
+func (hs *headerSyncer) subscribe() {
+	notify.BUS.Subscribe(notify.BlockHeadersReq, hs.onHeadersRequest)
+	notify.BUS.Subscribe(notify.BlockHeadersResponse, hs.onHeadersResponse)
+	notify.BUS.Subscribe(notify.BlockBodiesReq, hs.onBodiesRequest)
+	notify.BUS.Subscribe(notify.BlockBodiesResponse, hs.onBodiesResponse)
+}
+
+func (hs *headerSyncer) isSyncing() bool {
+	hs.lock.Lock()
+	defer hs.lock.Unlock()
+	return hs.task != nil
+}
+
+// start starts syncing the blocks from the height with the headers of the source,
+// returns false if another task is running
+func (hs *headerSyncer) start(source string, top *types.BlockHeader, height uint64) bool {
+	hs.lock.Lock()
+	defer hs.lock.Unlock()
+	if hs.task != nil {
+		return false
+	}
+	t := &headerSyncTask{
+		source:   source,
+		top:      top,
+		from:     height,
+		index:    make(map[common.Hash]int),
+		bodies:   make(map[common.Hash][]*types.RawTransaction),
+		assigned: make(map[common.Hash]string),
+		requests: make(map[string]*bodyRequest),
+		dropped:  make(map[string]bool),
+	}
+	hs.task = t
+	hs.logger.Debugf("header sync start from %v, height %v, peer top %v %v", source, height, top.Height, top.Hash)
+	hs.requestHeaders(t, height)
+	return true
+}
+
+func (hs *headerSyncer) requestHeaders(t *headerSyncTask, height uint64) {
+	size := maxReqHeaderCount
+	if left := headerSyncWindow - len(t.headers); left < size {
+		size = left
+	}
+	body, err := marshalSyncRequest(&syncRequest{ReqHeight: height, ReqSize: int32(size)})
+	if err != nil {
+		hs.logger.Errorf("marshal header request error:%v", err)
+		return
+	}
+	t.seq++
+	t.headerSeq = t.seq
+	seq, source := t.seq, t.source
+	hs.ticker.RegisterOneTimeRoutine(tickerHeaderSyncTimeout+source, func() bool {
+		hs.onHeadersTimeout(source, seq)
+		return true
+	}, hs.timeout)
+	if err := hs.sender.Send(source, network.Message{Code: network.ReqBlockHeaders, Body: body}); err != nil {
+		hs.logger.Warnf("request headers from %v error:%v", source, err)
+	}
+}
+
+func (hs *headerSyncer) onHeadersTimeout(source string, seq int) {
+	peers := hs.peers()
+	hs.lock.Lock()
+	t := hs.task
+	if t == nil || t.source != source || t.headerSeq != seq {
+		hs.lock.Unlock()
+		return
+	}
+	hs.logger.Warnf("request headers from %v timeout", source)
+	peerManagerImpl.timeoutPeer(source)
+	hs.switchSource(t, peers)
+	hs.lock.Unlock()
+	hs.commit()
+}
+
+// switchSource drops the source and fetches the rest headers from another peer,
+// the task ends after the verified headers committed if no peer has them
+func (hs *headerSyncer) switchSource(t *headerSyncTask, peers map[string]uint64) {
+	t.dropped[t.source] = true
+	t.headerSeq = 0
+	next := t.nextHeight()
+	for id, height := range peers {
+		if t.dropped[id] || height < next || peerManagerImpl.isEvil(id) {
+			continue
+		}
+		hs.logger.Debugf("header sync switch source from %v to %v", t.source, id)
+		hs.ticker.RemoveRoutine(tickerHeaderSyncTimeout + t.source)
+		t.source = id
+		hs.requestHeaders(t, next)
+		return
+	}
+	t.headersDone = true
+}
+
+func (hs *headerSyncer) onHeadersResponse(msg notify.Message) error {
+	m := notify.AsDefault(msg)
+	source := m.Source()
+	resp, err := unMarshalBlockMsgResponse(m.Body())
+	if err != nil {
+		return fmt.Errorf("unmarshal headers response error:%v", err)
+	}
+	peers := hs.peers()
+
+	hs.lock.Lock()
+	t := hs.task
+	if t == nil || t.source != source || t.headerSeq == 0 {
+		hs.lock.Unlock()
+		return fmt.Errorf("unexpected headers from %v", source)
+	}
+	hs.ticker.RemoveRoutine(tickerHeaderSyncTimeout + source)
+	t.headerSeq = 0
+	peerManagerImpl.heardFromPeer(source)
+
+	headers := make([]*types.BlockHeader, 0, len(resp.Blocks))
+	for _, b := range resp.Blocks {
+		if b.Header != nil {
+			headers = append(headers, b.Header)
+		}
+	}
+	fork := false
+	if len(headers) == 0 {
+		if len(t.headers) == 0 {
+			// The source announced the higher top but has no block after the height
+			peerManagerImpl.timeoutPeer(source)
+		}
+		t.headersDone = true
+	} else {
+		pre := t.lastHeader()
+		if pre == nil {
+			pre = hs.chain.QueryBlockHeaderByHash(headers[0].PreHash)
+			fork = pre == nil
+		}
+		if fork {
+			t.headersDone = true
+		} else {
+			verified, err := hs.verifyHeaders(t, pre, headers)
+			for _, h := range headers[:verified] {
+				t.index[h.Hash] = len(t.headers)
+				t.headers = append(t.headers, h)
+			}
+			last := t.lastHeader()
+			if err != nil {
+				hs.logger.Warnf("verify headers from %v error:%v, %v of %v verified", source, err, verified, len(headers))
+				hs.addBlack(source)
+				hs.switchSource(t, peers)
+			} else if last.Hash == t.top.Hash || last.Height >= t.top.Height || len(t.headers) >= headerSyncWindow {
+				t.headersDone = true
+			} else {
+				hs.requestHeaders(t, last.Height+1)
+			}
+		}
+	}
+	hs.dispatchBodies(t, peers)
+	top := t.top
+	hs.lock.Unlock()
+
+	if fork {
+		// Local chain doesn't have the parent of the headers, turn to the fork processing
+		hs.logger.Debugf("header sync from %v found fork at %v %v", source, headers[0].Height, headers[0].Hash)
+		hs.finish(t, false)
+		go hs.chain.forkProcessor.tryToProcessFork(source, &types.Block{Header: top})
+		return nil
+	}
+	hs.commit()
+	return nil
+}
+
+// verifyHeaders checks the headers are chained after pre and signed by the groups,
+// returns the number of the headers verified before the first illegal one
+func (hs *headerSyncer) verifyHeaders(t *headerSyncTask, pre *types.BlockHeader, headers []*types.BlockHeader) (int, error) {
+	last := pre
+	for i, h := range headers {
+		if h.Hash != h.GenHash() {
+			return i, fmt.Errorf("hash error at %v", h.Height)
+		}
+		if h.PreHash != last.Hash || h.Height <= last.Height {
+			return i, fmt.Errorf("header %v %v not chained", h.Height, h.Hash)
+		}
+		if h.Height > t.top.Height {
+			return i, fmt.Errorf("header %v higher than the top %v", h.Height, t.top.Height)
+		}
+		last = h
+	}
+	return hs.verifier.VerifyBlockHeadersBatch(pre, headers)
+}
+
+// dispatchBodies requests the bodies of the verified headers from the idle peers having them
+func (hs *headerSyncer) dispatchBodies(t *headerSyncTask, peers map[string]uint64) {
+	if peers == nil {
+		peers = make(map[string]uint64)
+	}
+	if _, ok := peers[t.source]; !ok {
+		peers[t.source] = t.top.Height
+	}
+	pending := make([]*types.BlockHeader, 0)
+	for _, h := range t.headers[t.committed:] {
+		if _, ok := t.bodies[h.Hash]; ok {
+			continue
+		}
+		// No need to request the empty body
+		if h.TxTree == common.EmptyHash {
+			t.bodies[h.Hash] = []*types.RawTransaction{}
+			continue
+		}
+		if _, ok := t.assigned[h.Hash]; !ok {
+			pending = append(pending, h)
+		}
+	}
+	for id, height := range peers {
+		if len(pending) == 0 {
+			return
+		}
+		if t.requests[id] != nil || t.dropped[id] || peerManagerImpl.isEvil(id) {
+			continue
+		}
+		size := peerManagerImpl.getPeerReqBlockCount(id) * maxReqBodyCount / maxReqBlockCount
+		if size < 1 {
+			size = 1
+		}
+		hashes := make([]common.Hash, 0, size)
+		rest := pending[:0]
+		for _, h := range pending {
+			if len(hashes) < size && h.Height <= height {
+				hashes = append(hashes, h.Hash)
+			} else {
+				rest = append(rest, h)
+			}
+		}
+		pending = rest
+		if len(hashes) > 0 {
+			hs.requestBodies(t, id, hashes)
+		}
+	}
+}
+
+func (hs *headerSyncer) requestBodies(t *headerSyncTask, id string, hashes []common.Hash) {
+	hashBytes := make([][]byte, len(hashes))
+	for i, h := range hashes {
+		hashBytes[i] = h.Bytes()
+		t.assigned[h] = id
+	}
+	body, err := proto.Marshal(&tas_middleware_pb.Hashes{Hashes: hashBytes})
+	if err != nil {
+		hs.logger.Errorf("marshal body request error:%v", err)
+		return
+	}
+	t.seq++
+	t.requests[id] = &bodyRequest{seq: t.seq, hashes: hashes}
+	seq := t.seq
+	hs.ticker.RegisterOneTimeRoutine(tickerBodySyncTimeout+id, func() bool {
+		hs.onBodiesTimeout(id, seq)
+		return true
+	}, hs.timeout)
+	hs.logger.Debugf("request %v bodies from %v", len(hashes), id)
+	if err := hs.sender.Send(id, network.Message{Code: network.ReqBlockBodies, Body: body}); err != nil {
+		hs.logger.Warnf("request bodies from %v error:%v", id, err)
+	}
+}
+
+// dropPeer cancels the request of the peer and excludes it from the task
+func (hs *headerSyncer) dropPeer(t *headerSyncTask, id string) {
+	if req := t.requests[id]; req != nil {
+		for _, h := range req.hashes {
+			delete(t.assigned, h)
+		}
+		delete(t.requests, id)
+	}
+	hs.ticker.RemoveRoutine(tickerBodySyncTimeout + id)
+	t.dropped[id] = true
+}
+
+// addBlack punishes the peer sending the illegal data
+func (hs *headerSyncer) addBlack(id string) {
+	peerManagerImpl.addEvilCount(id)
+	network.ReportPeer(id, network.PeerEventBadBlock)
+	hs.logger.Debugf("header sync add black %v", id)
+}
+
+func (hs *headerSyncer) onBodiesTimeout(id string, seq int) {
+	peers := hs.peers()
+	hs.lock.Lock()
+	t := hs.task
+	if t == nil || t.requests[id] == nil || t.requests[id].seq != seq {
+		hs.lock.Unlock()
+		return
+	}
+	hs.logger.Warnf("request bodies from %v timeout, dropped", id)
+	peerManagerImpl.timeoutPeer(id)
+	peerManagerImpl.updateReqBlockCnt(id, false)
+	hs.dropPeer(t, id)
+	hs.dispatchBodies(t, peers)
+	hs.lock.Unlock()
+	hs.commit()
+}
+
+// stalled returns whether no body request is pending while some bodies are missing,
+// which means all the peers having them dropped
+func (hs *headerSyncer) stalled(t *headerSyncTask) bool {
+	return len(t.requests) == 0 && t.headerSeq == 0 && len(t.bodies) < len(t.headers)-t.committed
+}
+
+func (hs *headerSyncer) onBodiesResponse(msg notify.Message) error {
+	m := notify.AsDefault(msg)
+	source := m.Source()
+	resp, err := unMarshalBlockMsgResponse(m.Body())
+	peers := hs.peers()
+
+	hs.lock.Lock()
+	t := hs.task
+	if t == nil || t.requests[source] == nil {
+		hs.lock.Unlock()
+		return fmt.Errorf("unexpected bodies from %v", source)
+	}
+	req := t.requests[source]
+	delete(t.requests, source)
+	hs.ticker.RemoveRoutine(tickerBodySyncTimeout + source)
+
+	requested := make(map[common.Hash]bool, len(req.hashes))
+	for _, h := range req.hashes {
+		requested[h] = true
+	}
+	matched, lying := 0, err != nil
+	if err == nil {
+		received := make(map[common.Hash]bool, len(resp.Blocks))
+		for _, b := range resp.Blocks {
+			if b.Header == nil || !requested[b.Header.Hash] {
+				lying = true
+				break
+			}
+			// The duplicates are ignored so that they are not counted as matched
+			if received[b.Header.Hash] {
+				continue
+			}
+			received[b.Header.Hash] = true
+			header := t.headers[t.index[b.Header.Hash]]
+			if calcTxTree(b.Transactions) != header.TxTree {
+				hs.logger.Warnf("body of %v %v from %v doesn't match the tx tree", header.Height, header.Hash, source)
+				lying = true
+				break
+			}
+			t.bodies[header.Hash] = b.Transactions
+			matched++
+		}
+	}
+	for _, h := range req.hashes {
+		delete(t.assigned, h)
+	}
+	switch {
+	case lying:
+		hs.addBlack(source)
+		hs.dropPeer(t, source)
+	case matched == 0:
+		// The peer doesn't have the bodies
+		peerManagerImpl.updateReqBlockCnt(source, false)
+		hs.dropPeer(t, source)
+	default:
+		peerManagerImpl.heardFromPeer(source)
+		peerManagerImpl.updateReqBlockCnt(source, matched == len(req.hashes))
+	}
+	hs.dispatchBodies(t, peers)
+	hs.lock.Unlock()
+
+	hs.commit()
+	if lying {
+		return fmt.Errorf("illegal bodies from %v", source)
+	}
+	return nil
+}
+
+// commit adds the blocks whose bodies are ready on chain in order, and finishes the task
+// if all done or nothing more can be done
+func (hs *headerSyncer) commit() {
+	hs.commitMu.Lock()
+	defer hs.commitMu.Unlock()
+
+	hs.lock.Lock()
+	t := hs.task
+	if t == nil {
+		hs.lock.Unlock()
+		return
+	}
+	blocks := make([]*types.Block, 0)
+	for _, h := range t.headers[t.committed:] {
+		txs, ok := t.bodies[h.Hash]
+		if !ok {
+			break
+		}
+		blocks = append(blocks, &types.Block{Header: h, Transactions: txs})
+	}
+	source := t.source
+	hs.lock.Unlock()
+
+	ok := true
+	if len(blocks) > 0 {
+		hasAddBlack := false
+		err := hs.chain.batchAddBlockOnChain(source, false, blocks, func(b *types.Block, ret types.AddBlockResult) bool {
+			hs.logger.Debugf("header sync add block %v %v, ret=%v", b.Header.Height, b.Header.Hash, ret)
+			if ret == types.AddBlockSucc || ret == types.AddBlockExisted {
+				return true
+			}
+			if ret == types.AddBlockConsensusFailed && !hasAddBlack {
+				hasAddBlack = true
+				hs.addBlack(source)
+			}
+			return false
+		})
+		added := 0
+		for _, b := range blocks {
+			if !hs.chain.HasBlock(b.Header.Hash) {
+				break
+			}
+			added++
+		}
+		if added < len(blocks) {
+			hs.logger.Warnf("header sync add blocks error:%v, %v of %v added", err, added, len(blocks))
+			ok = false
+		}
+		hs.lock.Lock()
+		for _, b := range blocks[:added] {
+			delete(t.bodies, b.Header.Hash)
+		}
+		t.committed += added
+		hs.lock.Unlock()
+	}
+
+	hs.lock.Lock()
+	done := t.headersDone && t.committed == len(t.headers)
+	anyCommitted := t.committed > 0
+	stalled := hs.stalled(t)
+	hs.lock.Unlock()
+	if !ok || done || stalled {
+		hs.finish(t, ok && done && anyCommitted)
+	}
+}
+
+// finish ends the task and cancels the pending requests
+func (hs *headerSyncer) finish(t *headerSyncTask, ok bool) {
+	hs.lock.Lock()
+	if hs.task != t {
+		hs.lock.Unlock()
+		return
+	}
+	hs.task = nil
+	hs.ticker.RemoveRoutine(tickerHeaderSyncTimeout + t.source)
+	for id := range t.requests {
+		hs.ticker.RemoveRoutine(tickerBodySyncTimeout + id)
+	}
+	committed, total, dropped := t.committed, len(t.headers), len(t.dropped)
+	hs.lock.Unlock()
+
+	hs.logger.Debugf("header sync from %v finished, ok=%v, %v of %v blocks committed, %v peers dropped", t.source, ok, committed, total, dropped)
+	hs.onFinish(t.source, ok)
+}
+
+func (hs *headerSyncer) onHeadersRequest(msg notify.Message) error {
+	m := notify.AsDefault(msg)
+	req, err := unmarshalSyncRequest(m.Body())
+	if err != nil {
+		return fmt.Errorf("unmarshal header request error:%v", err)
+	}
+	localHeight := hs.chain.Height()
+	if req.ReqHeight <= 0 || req.ReqHeight > localHeight || req.ReqSize <= 0 || req.ReqSize > maxReqHeaderCount {
+		return fmt.Errorf("error param,ReqHeight=%d,ReqSize=%d", req.ReqHeight, req.ReqSize)
+	}
+	headers := hs.chain.batchGetBlockHeadersAfterHeight(req.ReqHeight, int(req.ReqSize))
+	blocks := make([]*types.Block, len(headers))
+	for i, h := range headers {
+		blocks[i] = &types.Block{Header: h}
+	}
+	return hs.respond(m.Source(), network.BlockHeadersResponse, blocks)
+}
+
+func (hs *headerSyncer) onBodiesRequest(msg notify.Message) error {
+	m := notify.AsDefault(msg)
+	req := new(tas_middleware_pb.Hashes)
+	if err := proto.Unmarshal(m.Body(), req); err != nil {
+		return fmt.Errorf("unmarshal body request error:%v", err)
+	}
+	if len(req.Hashes) == 0 || len(req.Hashes) > maxReqBodyCount {
+		return fmt.Errorf("error param,size=%d", len(req.Hashes))
+	}
+	blocks := make([]*types.Block, 0, len(req.Hashes))
+	for _, b := range req.Hashes {
+		hash := common.BytesToHash(b)
+		if !hs.chain.hasBlock(hash) {
+			continue
+		}
+		blocks = append(blocks, &types.Block{
+			Header:       &types.BlockHeader{Hash: hash},
+			Transactions: hs.chain.queryBlockTransactionsAll(hash),
+		})
+	}
+	return hs.respond(m.Source(), network.BlockBodiesResponse, blocks)
+}
+
+func (hs *headerSyncer) respond(id string, code uint32, blocks []*types.Block) error {
+	body, err := marshalBlockMsgResponse(&blockResponseMessage{Blocks: blocks})
+	if err != nil {
+		return err
+	}
+	return hs.sender.Send(id, network.Message{Code: code, Body: body})
+}
+
+// calcTxTree calculates the tx tree of the raw transactions in the block
+func calcTxTree(raws []*types.RawTransaction) common.Hash {
+	txs := make(txSlice, len(raws))
+	for i, raw := range raws {
+		txs[i] = types.NewTransaction(raw, raw.GenHash())
+	}
+	return txs.calcTxTree()
+}
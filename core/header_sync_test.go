@@ -0,0 +1,340 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/middleware/notify"
+	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/darren0718/zvchain/network"
+	"github.com/sirupsen/logrus"
+)
+
+var headerSyncers = make(map[string]*headerSyncer)
+
+type headerSyncSender4Test struct {
+	myId   string
+	silent map[string]bool // Peers never responding
+}
+
+func (s *headerSyncSender4Test) Send(id string, msg network.Message) error {
+	if s.silent[id] {
+		return nil
+	}
+	hs := headerSyncers[id]
+	notifyMsg := notify.NewDefaultMessage(msg.Body, s.myId, 1, 1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		switch msg.Code {
+		case network.ReqBlockHeaders:
+			hs.onHeadersRequest(notifyMsg)
+		case network.BlockHeadersResponse:
+			hs.onHeadersResponse(notifyMsg)
+		case network.ReqBlockBodies:
+			hs.onBodiesRequest(notifyMsg)
+		case network.BlockBodiesResponse:
+			hs.onBodiesResponse(notifyMsg)
+		}
+	}()
+	return nil
+}
+
+func initHeaderSyncer(chain *FullBlockChain, id string) *headerSyncer {
+	hs := newHeaderSyncer(chain, chain.consensusHelper, &headerSyncSender4Test{myId: id}, logrus.StandardLogger(), 10)
+	headerSyncers[id] = hs
+	return hs
+}
+
+func TestHeaderSync_SyncFromPeer(t *testing.T) {
+	clearDatas()
+	defer clearDatas()
+	chain1, chain2 := build2Chains(100, 400, 0)
+	hs1 := initHeaderSyncer(chain1, id1)
+	initHeaderSyncer(chain2, id2)
+
+	var finished, succeed bool
+	hs1.onFinish = func(source string, ok bool) {
+		finished, succeed = true, ok
+	}
+	top := chain2.QueryTopBlock()
+	if !hs1.start(id2, top, chain1.Height()+1) {
+		t.Fatalf("start header sync fail")
+	}
+	wg.Wait()
+
+	if !finished || !succeed {
+		t.Fatalf("header sync not finished: %v %v", finished, succeed)
+	}
+	if chain1.QueryTopBlock().Hash != top.Hash {
+		t.Fatalf("top not synced, local %v, peer %v", chain1.QueryTopBlock().Height, top.Height)
+	}
+	if hs1.isSyncing() {
+		t.Fatalf("task should be cleared")
+	}
+}
+
+func TestHeaderSync_OnBodiesResponse_TxTreeMismatch(t *testing.T) {
+	clearDatas()
+	defer clearDatas()
+	chain := initChain(chainPath1, id1)
+	hs := initHeaderSyncer(chain, id1)
+
+	peer := "lying_peer"
+	header := &types.BlockHeader{Hash: common.BytesToHash([]byte{1}), Height: chain.Height() + 1, TxTree: common.BytesToHash([]byte{2})}
+	task := &headerSyncTask{
+		source:      peer,
+		top:         header,
+		headers:     []*types.BlockHeader{header},
+		index:       map[common.Hash]int{header.Hash: 0},
+		bodies:      make(map[common.Hash][]*types.RawTransaction),
+		headersDone: true,
+		assigned:    map[common.Hash]string{header.Hash: peer},
+		requests:    map[string]*bodyRequest{peer: {seq: 1, hashes: []common.Hash{header.Hash}}},
+		dropped:     make(map[string]bool),
+	}
+	hs.task = task
+
+	body, err := marshalBlockMsgResponse(&blockResponseMessage{Blocks: []*types.Block{{Header: &types.BlockHeader{Hash: header.Hash}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hs.onBodiesResponse(notify.NewDefaultMessage(body, peer, 1, 1)); err == nil {
+		t.Fatalf("should be error with the body mismatching the tx tree")
+	}
+	if !task.dropped[peer] || len(task.bodies) != 0 {
+		t.Fatalf("lying peer should be dropped")
+	}
+	if hs.isSyncing() {
+		t.Fatalf("stalled task should be finished")
+	}
+	if chain.Height() >= header.Height {
+		t.Fatalf("block shouldn't be added")
+	}
+}
+
+func TestHeaderSync_VerifyHeaders_BrokenLink(t *testing.T) {
+	clearDatas()
+	defer clearDatas()
+	chain := initChain(chainPath1, id1)
+	buildChain(20, chain)
+	hs := initHeaderSyncer(chain, id1)
+
+	list := chain.batchGetBlockHeadersAfterHeight(1, 10)
+	if len(list) < 4 {
+		t.Fatalf("not enough blocks %v", len(list))
+	}
+	pre := list[0]
+	headers := []*types.BlockHeader{list[1], list[3]}
+	task := &headerSyncTask{top: chain.QueryTopBlock()}
+	n, err := hs.verifyHeaders(task, pre, headers)
+	if err == nil || n != 1 {
+		t.Fatalf("broken link should be detected, verified %v, err %v", n, err)
+	}
+}
+
+// newBodyTask4Test returns a task of the fake headers from the height, each having one transaction
+func newBodyTask4Test(source string, height uint64, n int) (*headerSyncTask, map[common.Hash][]*types.RawTransaction) {
+	task := &headerSyncTask{
+		source:      source,
+		index:       make(map[common.Hash]int),
+		bodies:      make(map[common.Hash][]*types.RawTransaction),
+		headersDone: true,
+		assigned:    make(map[common.Hash]string),
+		requests:    make(map[string]*bodyRequest),
+		dropped:     make(map[string]bool),
+	}
+	bodies := make(map[common.Hash][]*types.RawTransaction)
+	for i := 0; i < n; i++ {
+		txs := []*types.RawTransaction{genTestTx(1, "1", uint64(i), 1).RawTransaction}
+		h := &types.BlockHeader{
+			Hash:   common.BytesToHash(genHash(fmt.Sprintf("header%v", i))),
+			Height: height + uint64(i),
+			TxTree: calcTxTree(txs),
+		}
+		task.index[h.Hash] = len(task.headers)
+		task.headers = append(task.headers, h)
+		bodies[h.Hash] = txs
+	}
+	task.top = task.lastHeader()
+	return task, bodies
+}
+
+func TestHeaderSync_DispatchBodies_MultiPeer(t *testing.T) {
+	clearDatas()
+	defer clearDatas()
+	chain := initChain(chainPath1, id1)
+	hs := initHeaderSyncer(chain, id1)
+	peerA, peerB, peerC := "multi_peer_a", "multi_peer_b", "multi_peer_c"
+	hs.sender = &headerSyncSender4Test{myId: id1, silent: map[string]bool{peerA: true, peerB: true, peerC: true}}
+
+	task, _ := newBodyTask4Test(peerA, chain.Height()+1, 40)
+	hs.task = task
+	peerManagerImpl.getOrAddPeer(peerA).reqBlockCount = 2
+	peerManagerImpl.getOrAddPeer(peerB).reqBlockCount = 0
+	peerManagerImpl.getOrAddPeer(peerC).reqBlockCount = 2
+	lowTop := task.headers[9].Height
+	hs.dispatchBodies(task, map[string]uint64{peerA: task.top.Height, peerB: task.top.Height, peerC: lowTop})
+
+	for _, id := range []string{peerA, peerB, peerC} {
+		if task.requests[id] == nil {
+			t.Fatalf("bodies should be requested from %v", id)
+		}
+	}
+	if n := len(task.requests[peerA].hashes); n != 8 {
+		t.Fatalf("peer a should be requested 8 bodies, got %v", n)
+	}
+	// The batch size of the peer is clamped to 1
+	if n := len(task.requests[peerB].hashes); n != 1 {
+		t.Fatalf("peer b should be requested 1 body, got %v", n)
+	}
+	for _, h := range task.requests[peerC].hashes {
+		if task.headers[task.index[h]].Height > lowTop {
+			t.Fatalf("body above the top %v requested from peer c", lowTop)
+		}
+	}
+	total := 0
+	for id, req := range task.requests {
+		for _, h := range req.hashes {
+			if task.assigned[h] != id {
+				t.Fatalf("body %v assigned to %v, requested from %v", h, task.assigned[h], id)
+			}
+		}
+		total += len(req.hashes)
+	}
+	if total != len(task.assigned) {
+		t.Fatalf("bodies requested more than once, %v requested, %v assigned", total, len(task.assigned))
+	}
+}
+
+func TestHeaderSync_OnBodiesTimeout_Reassign(t *testing.T) {
+	clearDatas()
+	defer clearDatas()
+	chain := initChain(chainPath1, id1)
+	hs := initHeaderSyncer(chain, id1)
+	slow, fast, holder := "timeout_peer_slow", "timeout_peer_fast", "timeout_peer_holder"
+	hs.sender = &headerSyncSender4Test{myId: id1, silent: map[string]bool{slow: true, fast: true, holder: true}}
+	peers := map[string]uint64{}
+	hs.peers = func() map[string]uint64 {
+		return peers
+	}
+
+	task, bodies := newBodyTask4Test(slow, chain.Height()+1, 17)
+	// The first body is held by another peer so that nothing is committed
+	first := task.headers[0].Hash
+	task.seq = 1
+	task.assigned[first] = holder
+	task.requests[holder] = &bodyRequest{seq: 1, hashes: []common.Hash{first}}
+	hs.task = task
+	peerManagerImpl.getOrAddPeer(slow).reqBlockCount = 2
+	peerManagerImpl.getOrAddPeer(fast).reqBlockCount = 2
+	peers[slow], peers[fast] = task.top.Height, task.top.Height
+	hs.lock.Lock()
+	hs.dispatchBodies(task, hs.peers())
+	hs.lock.Unlock()
+	if task.requests[slow] == nil || task.requests[fast] == nil {
+		t.Fatalf("bodies should be requested from both peers")
+	}
+	slowHashes := task.requests[slow].hashes
+
+	hs.onBodiesTimeout(slow, task.requests[slow].seq)
+	if !task.dropped[slow] || task.requests[slow] != nil {
+		t.Fatalf("slow peer should be dropped")
+	}
+	for _, h := range slowHashes {
+		if _, ok := task.assigned[h]; ok {
+			t.Fatalf("bodies of the slow peer should be released")
+		}
+	}
+
+	blocks := make([]*types.Block, 0)
+	for _, h := range task.requests[fast].hashes {
+		blocks = append(blocks, &types.Block{Header: &types.BlockHeader{Hash: h}, Transactions: bodies[h]})
+	}
+	body, err := marshalBlockMsgResponse(&blockResponseMessage{Blocks: blocks})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hs.onBodiesResponse(notify.NewDefaultMessage(body, fast, 1, 1)); err != nil {
+		t.Fatalf("bodies response error:%v", err)
+	}
+	for _, h := range slowHashes {
+		if task.assigned[h] != fast {
+			t.Fatalf("bodies of the slow peer should be requested from the fast one")
+		}
+	}
+	if !hs.isSyncing() {
+		t.Fatalf("task shouldn't be finished")
+	}
+}
+
+func TestHeaderSync_SwitchSourceOnTimeout(t *testing.T) {
+	clearDatas()
+	defer clearDatas()
+	id3 := "3"
+	chain1, chain2 := build2Chains(100, 400, 0)
+	os.RemoveAll(chainPath3)
+	if err := exec.Command("cp", "-rf", chainPath2, chainPath3).Run(); err != nil {
+		t.Fatal(err)
+	}
+	chain3 := initChain(chainPath3, id3)
+	hs1 := initHeaderSyncer(chain1, id1)
+	initHeaderSyncer(chain2, id2)
+	initHeaderSyncer(chain3, id3)
+
+	top := chain2.QueryTopBlock()
+	if chain3.QueryTopBlock().Hash != top.Hash {
+		t.Fatalf("peers should have the same top")
+	}
+	// Peer 2 never responds
+	hs1.sender = &headerSyncSender4Test{myId: id1, silent: map[string]bool{id2: true}}
+	hs1.peers = func() map[string]uint64 {
+		return map[string]uint64{id2: top.Height, id3: top.Height}
+	}
+	var finished, succeed bool
+	hs1.onFinish = func(source string, ok bool) {
+		finished, succeed = true, ok
+	}
+	if !hs1.start(id2, top, chain1.Height()+1) {
+		t.Fatalf("start header sync fail")
+	}
+	wg.Wait()
+
+	hs1.lock.Lock()
+	task := hs1.task
+	seq := task.headerSeq
+	hs1.lock.Unlock()
+	if seq == 0 {
+		t.Fatalf("headers should be requested from peer 2")
+	}
+	hs1.onHeadersTimeout(id2, seq)
+	wg.Wait()
+
+	if !task.dropped[id2] || task.source != id3 {
+		t.Fatalf("source should be switched to peer 3")
+	}
+	if !finished || !succeed {
+		t.Fatalf("header sync not finished: %v %v", finished, succeed)
+	}
+	if chain1.QueryTopBlock().Hash != top.Hash {
+		t.Fatalf("top not synced, local %v, peer %v", chain1.QueryTopBlock().Height, top.Height)
+	}
+}
@@ -15,10 +15,17 @@
 
 package core
 
-import "github.com/darren0718/zvchain/middleware/types"
+import (
+	"fmt"
+
+	"github.com/darren0718/zvchain/middleware/types"
+)
 
 // InitCore initialize the peerManagerImpl, BlockChainImpl and GroupChainImpl
 func InitCore(helper types.ConsensusHelper, account types.Account) error {
+	if newContractEngine == nil {
+		return fmt.Errorf("contract engine not set")
+	}
 	initPeerManager()
 	if nil == BlockChainImpl {
 		err := initBlockChain(helper, account)
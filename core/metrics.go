@@ -0,0 +1,72 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/darren0718/zvchain/middleware/metrics"
+	"github.com/darren0718/zvchain/middleware/types"
+)
+
+// Events of the fork processing counted in the metrics
+const (
+	forkEventStart           = "start"             // Fork processing started with a peer
+	forkEventTimeout         = "timeout"           // Peer not responded in time
+	forkEventBadBlock        = "bad_block"         // Blocks of the peer failed to verify
+	forkEventLocalMoreWeight = "local_more_weight" // Local chain kept for more weight
+	forkEventAccepted        = "accepted"          // Chain slice of the peer added on chain
+	forkEventAddFailed       = "add_failed"        // Chain slice of the peer failed to add
+)
+
+var addBlockResultLabels = map[types.AddBlockResult]string{
+	types.AddBlockFailed:              "failed",
+	types.AddBlockConsensusFailed:     "consensus_failed",
+	types.AddBlockExisted:             "existed",
+	types.AddBlockLessWeightThanLocal: "less_weight",
+	types.AddBlockSucc:                "success",
+}
+
+func addBlockResultLabel(ret types.AddBlockResult) string {
+	if l, ok := addBlockResultLabels[ret]; ok {
+		return l
+	}
+	return fmt.Sprintf("%d", ret)
+}
+
+func countForkEvent(event string) {
+	metrics.ForkEvents.WithLabelValues(event).Inc()
+}
+
+// registerChainMetrics registers the gauges read from the chain and the transaction pool on each scrape
+func registerChainMetrics(chain *FullBlockChain) {
+	metrics.RegisterGauge("chain", "height", "Height of the local top block", func() float64 {
+		return float64(chain.Height())
+	})
+	metrics.RegisterGauge("chain", "checkpoint_height", "Height of the latest checkpoint", func() float64 {
+		if cp := chain.LatestCheckPoint(); cp != nil {
+			return float64(cp.Height)
+		}
+		return 0
+	})
+	metrics.RegisterGauge("txpool", "pending", "Executable transactions in the pool", func() float64 {
+		pool := chain.GetTransactionPool()
+		return float64(pool.TxNum() - pool.TxQueueNum())
+	})
+	metrics.RegisterGauge("txpool", "queued", "Transactions in the pool waiting for the nonce gap", func() float64 {
+		return float64(chain.GetTransactionPool().TxQueueNum())
+	})
+}
@@ -0,0 +1,222 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/middleware/notify"
+	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/darren0718/zvchain/network"
+)
+
+// simNode4Test is a chain running on the simulated network with its own bus
+type simNode4Test struct {
+	id    string
+	chain *FullBlockChain
+	node  *network.SimNode
+	sync  *blockSyncer
+}
+
+func (n *simNode4Test) onNewBlock(msg notify.Message) error {
+	m := notify.AsDefault(msg)
+	block, err := types.UnMarshalBlock(m.Body())
+	if err != nil {
+		return err
+	}
+	n.chain.forkProcessor.tryToProcessFork(m.Source(), block)
+	return nil
+}
+
+// mine adds the blocks up to the height and broadcasts each of them
+func (n *simNode4Test) mine(height uint64) {
+	for h := n.chain.Height() + 1; h <= height; h++ {
+		addRandomBlock(n.chain, h)
+		n.broadcastTop()
+	}
+}
+
+func (n *simNode4Test) broadcastTop() {
+	b := n.chain.QueryBlockByHash(n.chain.QueryTopBlock().Hash)
+	body, err := types.MarshalBlock(b)
+	if err != nil {
+		Logger.Panicf("marshal block error:%v", err)
+	}
+	n.node.Broadcast(network.Message{Code: network.NewBlockMsg, Body: body})
+}
+
+// newSimCluster4Test starts the chains sharing the same blocks up to the base height
+// on the simulated network
+func newSimCluster4Test(sn *network.SimNetwork, ids []string, paths []string, baseHeight uint64) []*simNode4Test {
+	base := initChain(paths[0], ids[0])
+	buildChain(baseHeight, base)
+
+	nodes := make([]*simNode4Test, 0, len(ids))
+	for i, id := range ids {
+		chain := base
+		if i > 0 {
+			os.RemoveAll(paths[i])
+			if err := exec.Command("cp", "-rf", paths[0], paths[i]).Run(); err != nil {
+				Logger.Panicf("copy chain error:%v", err)
+			}
+			chain = initChain(paths[i], id)
+		}
+		bus := notify.NewBus()
+		n := &simNode4Test{id: id, chain: chain, node: sn.AddNode(id, nil, bus)}
+		n.sync = newBlockSyncer(chain, n.node)
+		n.sync.ticker = chain.ticker
+		n.sync.logger = Logger
+		bus.Subscribe(notify.BlockInfoNotify, n.sync.topBlockInfoNotifyHandler)
+		bus.Subscribe(notify.BlockReq, n.sync.blockReqHandler)
+		bus.Subscribe(notify.BlockResponse, n.sync.blockResponseMsgHandler)
+		fp := chain.forkProcessor
+		fp.msgSender = n.node
+		bus.Subscribe(notify.ForkFindAncestorResponse, fp.onFindAncestorResponse)
+		bus.Subscribe(notify.ForkFindAncestorReq, fp.onFindAncestorReq)
+		bus.Subscribe(notify.ForkChainSliceReq, fp.onChainSliceRequest)
+		bus.Subscribe(notify.ForkChainSliceResponse, fp.onChainSliceResponse)
+		bus.Subscribe(notify.NewBlock, n.onNewBlock)
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// waitTop waits until the tops of all the nodes are the block
+func waitTop(sn *network.SimNetwork, nodes []*simNode4Test, hash common.Hash, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		sn.Wait()
+		converged := true
+		for _, n := range nodes {
+			if n.chain.QueryTopBlock().Hash != hash {
+				converged = false
+				break
+			}
+		}
+		if converged {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
+
+// waitSync drives the block syncers of the nodes until the tops of all the nodes are the block
+func waitSync(sn *network.SimNetwork, nodes []*simNode4Test, hash common.Hash, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		for _, n := range nodes {
+			n.sync.notifyLocalTopBlockRoutine()
+		}
+		sn.Wait()
+		for _, n := range nodes {
+			n.sync.trySyncRoutine()
+		}
+		if waitTop(sn, nodes, hash, 100*time.Millisecond) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPartition_ConvergeToHeavierChain(t *testing.T) {
+	clearDatas()
+	defer clearDatas()
+
+	sn := network.NewSimNetwork()
+	sn.SetDefaultLink(network.LinkConfig{Latency: 5 * time.Millisecond})
+	nodes := newSimCluster4Test(sn, []string{"1", "2", "3", "4"}, []string{chainPath1, chainPath2, chainPath3, chainPath4}, 100)
+	sideA, sideB := nodes[:2], nodes[2:]
+
+	sn.Partition([]string{"1", "2"}, []string{"3", "4"})
+	sideA[0].mine(110)
+	sideB[0].mine(115)
+
+	topA, topB := sideA[0].chain.QueryTopBlock(), sideB[0].chain.QueryTopBlock()
+	if !waitTop(sn, sideA, topA.Hash, 10*time.Second) || !waitTop(sn, sideB, topB.Hash, 10*time.Second) {
+		t.Fatalf("nodes in the same partition should converge")
+	}
+	if topA.Hash == topB.Hash || sideB[1].chain.HasBlock(topA.Hash) || sideA[1].chain.HasBlock(topB.Hash) {
+		t.Fatalf("blocks shouldn't cross the partition")
+	}
+
+	heavier := topB
+	if types.NewBlockWeight(topA).MoreWeight(types.NewBlockWeight(topB)) {
+		heavier = topA
+	}
+	Logger.Infof("side A top %v %v, side B top %v %v, heavier %v", topA.Height, topA.Hash, topB.Height, topB.Hash, heavier.Hash)
+
+	sn.Heal()
+	for _, n := range nodes {
+		n.broadcastTop()
+	}
+	if !waitTop(sn, nodes, heavier.Hash, 30*time.Second) {
+		for _, n := range nodes {
+			top := n.chain.QueryTopBlock()
+			t.Logf("node %v top %v %v", n.id, top.Height, top.Hash)
+		}
+		t.Fatalf("nodes should converge to the heavier chain %v %v", heavier.Height, heavier.Hash)
+	}
+}
+
+func TestPartition_LossyLinkConverge(t *testing.T) {
+	clearDatas()
+	defer clearDatas()
+
+	sn := network.NewSimNetwork()
+	sn.SetDefaultLink(network.LinkConfig{Latency: 5 * time.Millisecond, Bandwidth: 1024 * 1024})
+	nodes := newSimCluster4Test(sn, []string{"1", "2"}, []string{chainPath1, chainPath2}, 50)
+
+	// The follower misses most of the new blocks, then catches up by the fork processing
+	sn.SetLink("1", "2", network.LinkConfig{Latency: 5 * time.Millisecond, Loss: 0.8})
+	nodes[0].mine(60)
+	sn.SetLink("1", "2", network.LinkConfig{Latency: 5 * time.Millisecond})
+	nodes[0].broadcastTop()
+
+	if !waitTop(sn, nodes, nodes[0].chain.QueryTopBlock().Hash, 30*time.Second) {
+		t.Fatalf("follower should catch up after the link recovered")
+	}
+}
+
+func TestPartition_BlockSyncAfterHeal(t *testing.T) {
+	clearDatas()
+	defer clearDatas()
+
+	sn := network.NewSimNetwork()
+	sn.SetDefaultLink(network.LinkConfig{Latency: 5 * time.Millisecond})
+	nodes := newSimCluster4Test(sn, []string{"1", "2", "3"}, []string{chainPath1, chainPath2, chainPath3}, 100)
+
+	sn.Partition([]string{"1"}, []string{"2", "3"})
+	nodes[1].mine(140)
+	top := nodes[1].chain.QueryTopBlock()
+	if !waitTop(sn, nodes[1:], top.Hash, 10*time.Second) {
+		t.Fatalf("nodes in the same partition should converge")
+	}
+	if nodes[0].chain.HasBlock(top.Hash) {
+		t.Fatalf("blocks shouldn't cross the partition")
+	}
+
+	// No new block is broadcast after the heal, the isolated node catches up by the block sync only
+	sn.Heal()
+	if !waitSync(sn, nodes, top.Hash, 30*time.Second) {
+		local := nodes[0].chain.QueryTopBlock()
+		t.Fatalf("isolated node should sync to %v %v, local top %v %v", top.Height, top.Hash, local.Height, local.Hash)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/network"
 	lru "github.com/hashicorp/golang-lru"
 )
 
@@ -78,6 +79,7 @@ func (m *peerMeter) increaseTimeout() {
 	if m.timeoutMeter >= evilTimeoutMeterMax {
 		m.addEvilCount()
 		m.timeoutMeter = 0
+		network.ReportPeer(m.id, network.PeerEventTimeout)
 	}
 }
 func (m *peerMeter) resetTimeoutMeter() {
@@ -157,6 +159,10 @@ func (bpm *peerManager) isEvil(id string) bool {
 	if id == "" {
 		return false
 	}
+	// Peers banned by the network are regarded as evil too
+	if network.IsPeerBanned(id) {
+		return true
+	}
 	if !bpm.isPeerExists(id) {
 		return false
 	}
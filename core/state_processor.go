@@ -24,8 +24,8 @@ import (
 
 	"github.com/darren0718/zvchain/common"
 	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/darren0718/zvchain/params"
 	"github.com/darren0718/zvchain/storage/account"
-	"github.com/darren0718/zvchain/tvm"
 )
 
 const (
@@ -49,6 +49,14 @@ var (
 	errNonceError       = fmt.Errorf("nonce error")
 )
 
+// newContractEngine creates the engine running the contracts, set before the core initialized
+var newContractEngine types.ContractEngineFactory
+
+// SetContractEngine sets the engine running the contracts of the transactions
+func SetContractEngine(factory types.ContractEngineFactory) {
+	newContractEngine = factory
+}
+
 // stateTransition define some functions on state transition
 type stateTransition interface {
 	ParseTransaction() error // Parse the input transaction
@@ -234,18 +242,17 @@ func (ss *contractCreator) ParseTransaction() error {
 
 func (ss *contractCreator) Transition() *result {
 	ret := newResult()
-	controller := tvm.NewController(ss.accountDB, BlockChainImpl, ss.bh, ss.msg, ss.intrinsicGasUsed.Uint64(), MinerManagerImpl)
-	contractAddress, txErr := createContract(ss.accountDB, ss.msg)
+	engine := newContractEngine(ss.accountDB, BlockChainImpl, ss.bh, ss.msg, ss.intrinsicGasUsed.Uint64(), MinerManagerImpl)
+	contractAddress, txErr := createContract(ss.accountDB, ss.msg, ss.height)
 	if txErr != nil {
 		ret.setError(txErr, types.RSFail)
 	} else {
-		contract := tvm.LoadContract(contractAddress)
-		isTransferSuccess := transfer(ss.accountDB, ss.source, *contract.ContractAddress, ss.msg.Amount())
+		isTransferSuccess := transfer(ss.accountDB, ss.source, contractAddress, ss.msg.Amount())
 		if !isTransferSuccess {
 			ret.setError(fmt.Errorf("balance not enough ,address is %v", ss.source.AddrPrefixString()), types.RSBalanceNotEnough)
 		} else {
-			_, logs, err := controller.Deploy(contract)
-			ret.logs = logs
+			err := engine.Deploy(contractAddress)
+			ret.logs = engine.Logs()
 			if err != nil {
 				if err.Code == types.TVMGasNotEnoughError {
 					ret.setError(fmt.Errorf(err.Message), types.RSGasNotEnoughError)
@@ -257,7 +264,7 @@ func (ss *contractCreator) Transition() *result {
 			}
 		}
 	}
-	gasLeft := new(big.Int).SetUint64(controller.GetGasLeft())
+	gasLeft := new(big.Int).SetUint64(engine.GasLeft())
 	allUsed := new(big.Int).Sub(ss.msg.GetGasLimitOriginal(), gasLeft)
 	ss.gasUsed = allUsed
 	ret.contractAddress = contractAddress
@@ -274,17 +281,17 @@ func (ss *contractCaller) ParseTransaction() error {
 
 func (ss *contractCaller) Transition() *result {
 	ret := newResult()
-	controller := tvm.NewController(ss.accountDB, BlockChainImpl, ss.bh, ss.msg, ss.intrinsicGasUsed.Uint64(), MinerManagerImpl)
-	contract := tvm.LoadContract(*ss.msg.OpTarget())
-	if contract.Code == "" {
-		ret.setError(fmt.Errorf("no code at the given address %v", ss.msg.OpTarget().AddrPrefixString()), types.RSNoCodeError)
+	engine := newContractEngine(ss.accountDB, BlockChainImpl, ss.bh, ss.msg, ss.intrinsicGasUsed.Uint64(), MinerManagerImpl)
+	contractAddress := *ss.msg.OpTarget()
+	if !engine.HasCode(contractAddress) {
+		ret.setError(fmt.Errorf("no code at the given address %v", contractAddress.AddrPrefixString()), types.RSNoCodeError)
 	} else {
-		isTransferSuccess := transfer(ss.accountDB, *ss.msg.Operator(), *contract.ContractAddress, ss.msg.Amount())
+		isTransferSuccess := transfer(ss.accountDB, *ss.msg.Operator(), contractAddress, ss.msg.Amount())
 		if !isTransferSuccess {
 			ret.setError(fmt.Errorf("balance not enough ,address is %v", ss.msg.Operator().AddrPrefixString()), types.RSBalanceNotEnough)
 		} else {
-			_, logs, err := controller.ExecuteAbiEval(ss.msg.Operator(), contract, string(ss.msg.Payload()))
-			ret.logs = logs
+			err := engine.Call(*ss.msg.Operator(), contractAddress, string(ss.msg.Payload()))
+			ret.logs = engine.Logs()
 			if err != nil {
 				if err.Code == types.TVMCheckABIError {
 					ret.setError(fmt.Errorf(err.Message), types.RSAbiError)
@@ -294,11 +301,11 @@ func (ss *contractCaller) Transition() *result {
 					ret.setError(fmt.Errorf(err.Message), types.RSTvmError)
 				}
 			} else {
-				Logger.Debugf("Contract call success! contract addr:%s，abi is %s", contract.ContractAddress.AddrPrefixString(), string(ss.msg.Payload()))
+				Logger.Debugf("Contract call success! contract addr:%s，abi is %s", contractAddress.AddrPrefixString(), string(ss.msg.Payload()))
 			}
 		}
 	}
-	gasLeft := new(big.Int).SetUint64(controller.GetGasLeft())
+	gasLeft := new(big.Int).SetUint64(engine.GasLeft())
 	allUsed := new(big.Int).Sub(ss.msg.GetGasLimitOriginal(), gasLeft)
 	ss.gasUsed = allUsed
 	return ret
@@ -465,6 +472,7 @@ func (executor *stateProcessor) process(accountDB *account.AccountDB, bh *types.
 
 		// Accumulate gas fee
 		cumulativeGas := uint64(0)
+		var fee *big.Int
 		if ret.cumulativeGasUsed != nil {
 			cumulativeGas = ret.cumulativeGasUsed.Uint64()
 			totalGasUsed += cumulativeGas
@@ -476,7 +484,7 @@ func (executor *stateProcessor) process(accountDB *account.AccountDB, bh *types.
 				break
 			}
 
-			fee := big.NewInt(0).Mul(ret.cumulativeGasUsed, tx.GasPrice.Value())
+			fee = big.NewInt(0).Mul(ret.cumulativeGasUsed, tx.GasPrice.Value())
 			gasFee += fee.Uint64()
 		}
 
@@ -497,6 +505,14 @@ func (executor *stateProcessor) process(accountDB *account.AccountDB, bh *types.
 		receipt.ContractAddress = ret.contractAddress
 		receipt.TxIndex = uint16(idx)
 		receipt.Height = bh.Height
+		if params.GetChainConfig().IsZIP006(bh.Height) {
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		}
+		if fee != nil && params.GetChainConfig().IsZIP009(bh.Height) {
+			receipt.GasUsed = cumulativeGas
+			receipt.GasPrice = tx.GasPrice.Uint64()
+			receipt.Fee = fee.Uint64()
+		}
 		receipts = append(receipts, receipt)
 		//errs[i] = err
 
@@ -536,10 +552,19 @@ func validateNonce(accountDB types.AccountDB, transaction *types.Transaction) bo
 	return true
 }
 
-func createContract(accountDB types.AccountDB, transaction types.TxMessage) (common.Address, error) {
+func createContract(accountDB types.AccountDB, transaction types.TxMessage, height uint64) (common.Address, error) {
 	contractAddr := common.BytesToAddress(common.Sha256(common.BytesCombine(transaction.Operator()[:], common.Uint64ToByte(transaction.GetNonce()))))
+	var free bool
+	// The address derived from the salt and the code is known before the deployment, and may
+	// be funded already
+	if salt, ok := types.ContractSalt(transaction.GetExtraData()); ok && params.GetChainConfig().IsZIP008(height) {
+		contractAddr = types.SaltedContractAddress(*transaction.Operator(), salt, transaction.Payload())
+		free = types.IsContractAddressFree(accountDB, contractAddr)
+	} else {
+		free = accountDB.GetCodeHash(contractAddr) == (common.Hash{})
+	}
 
-	if accountDB.GetCodeHash(contractAddr) != (common.Hash{}) {
+	if !free {
 		return common.Address{}, fmt.Errorf("contract address conflict")
 	}
 	accountDB.CreateAccount(contractAddr)
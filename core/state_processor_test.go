@@ -1,6 +1,8 @@
 package core
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/darren0718/zvchain/consensus/groupsig"
 	"github.com/darren0718/zvchain/log"
@@ -12,8 +14,10 @@ import (
 
 	"github.com/darren0718/zvchain/common"
 	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/darren0718/zvchain/params"
 	"github.com/darren0718/zvchain/storage/account"
 	"github.com/darren0718/zvchain/storage/tasdb"
+	"github.com/darren0718/zvchain/tvm/mock"
 
 	"github.com/syndtr/goleveldb/leveldb/filter"
 	"github.com/syndtr/goleveldb/leveldb/opt"
@@ -25,6 +29,13 @@ var (
 	accountdb account.AccountDatabase
 )
 
+// testContractEngine runs the contracts of the core tests by the scripts
+var testContractEngine = mock.New()
+
+func init() {
+	SetContractEngine(testContractEngine.NewEngine)
+}
+
 type cp4Test struct {
 }
 
@@ -195,3 +206,160 @@ func Test_validGasPrice(t *testing.T) {
 	}
 
 }
+
+func Test_createContract_Salted(t *testing.T) {
+	diskdb, _ := tasdb.NewMemDatabase()
+	db, _ := account.NewAccountDB(common.Hash{}, account.NewDatabase(diskdb))
+	source := randomAddress()
+	salt := common.BytesToHash(common.Sha256([]byte("salt")))
+	code := []byte(`{"code":"class A(object):\n    pass","contract_name":"A"}`)
+	newTx := func(nonce uint64, extraData []byte) *types.Transaction {
+		return types.NewTransaction(&types.RawTransaction{Source: &source, Nonce: nonce, Data: code, ExtraData: extraData}, common.Hash{})
+	}
+
+	cfg := params.GetChainConfig()
+	defer func(h uint64) { cfg.ZIP008 = h }(cfg.ZIP008)
+	cfg.ZIP008 = 100
+
+	// The salt is ignored before zip008
+	addr, err := createContract(db, newTx(1, types.SaltExtraData(salt)), 99)
+	if err != nil || addr != common.BytesToAddress(common.Sha256(common.BytesCombine(source[:], common.Uint64ToByte(1)))) {
+		t.Fatalf("contract address before zip008 error %v %v", addr.AddrPrefixString(), err)
+	}
+
+	expect := types.SaltedContractAddress(source, salt.Bytes(), code)
+	addr, err = createContract(db, newTx(2, types.SaltExtraData(salt)), 100)
+	if err != nil || addr != expect {
+		t.Fatalf("salted contract address error %v %v", addr.AddrPrefixString(), err)
+	}
+	if _, err = createContract(db, newTx(3, types.SaltExtraData(salt)), 101); err == nil {
+		t.Fatalf("deploying to the same salted address should conflict")
+	}
+}
+
+func Test_createContract_PreFunded(t *testing.T) {
+	diskdb, _ := tasdb.NewMemDatabase()
+	db, _ := account.NewAccountDB(common.Hash{}, account.NewDatabase(diskdb))
+	source := randomAddress()
+	salt := common.BytesToHash(common.Sha256([]byte("salt")))
+	code := []byte(`{"code":"class A(object):\n    pass","contract_name":"A"}`)
+	tx := types.NewTransaction(&types.RawTransaction{Source: &source, Nonce: 1, Data: code, ExtraData: types.SaltExtraData(salt)}, common.Hash{})
+
+	cfg := params.GetChainConfig()
+	defer func(h uint64) { cfg.ZIP008 = h }(cfg.ZIP008)
+	cfg.ZIP008 = 0
+
+	// The salted address is known before the deployment, so it can be funded before
+	expect := types.SaltedContractAddress(source, salt.Bytes(), code)
+	db.AddBalance(expect, big.NewInt(100))
+	root, _ := db.Commit(true)
+	db, _ = account.NewAccountDB(root, db.Database())
+
+	addr, err := createContract(db, tx, 1)
+	if err != nil || addr != expect {
+		t.Fatalf("deploy to the pre-funded address error %v %v", addr.AddrPrefixString(), err)
+	}
+	if db.GetBalance(addr).Int64() != 100 {
+		t.Fatalf("balance of the pre-funded address should be kept, got %v", db.GetBalance(addr))
+	}
+	if !bytes.Equal(db.GetCode(addr), code) || db.GetNonce(addr) != 1 {
+		t.Fatalf("contract account not set up")
+	}
+}
+
+func Test_createContract_NonceAddressTaken(t *testing.T) {
+	diskdb, _ := tasdb.NewMemDatabase()
+	db, _ := account.NewAccountDB(common.Hash{}, account.NewDatabase(diskdb))
+	source := randomAddress()
+	code := []byte(`{"code":"class A(object):\n    pass","contract_name":"A"}`)
+	tx := types.NewTransaction(&types.RawTransaction{Source: &source, Nonce: 1, Data: code}, common.Hash{})
+
+	cfg := params.GetChainConfig()
+	defer func(h uint64) { cfg.ZIP008 = h }(cfg.ZIP008)
+	cfg.ZIP008 = 0
+
+	// Only the salted deployments accept the existing account, the others keep the check of the code hash
+	addr := common.BytesToAddress(common.Sha256(common.BytesCombine(source[:], common.Uint64ToByte(1))))
+	db.AddBalance(addr, big.NewInt(100))
+	root, _ := db.Commit(true)
+	db, _ = account.NewAccountDB(root, db.Database())
+
+	if _, err := createContract(db, tx, 1); err == nil {
+		t.Fatalf("deploying to the existing account should conflict")
+	}
+}
+
+func TestContractTransition_Mock(t *testing.T) {
+	diskdb, _ := tasdb.NewMemDatabase()
+	db, _ := account.NewAccountDB(common.Hash{}, account.NewDatabase(diskdb))
+	source := randomAddress()
+	db.SetBalance(source, big.NewInt(1000))
+	topic := common.BytesToHash(common.Sha256([]byte("added")))
+	testContractEngine.Register("Counter", &mock.Script{
+		Deploy: func(ctx *mock.Context) *types.TransactionError {
+			ctx.DB.SetData(ctx.Contract, []byte("count"), []byte{0})
+			return nil
+		},
+		Methods: map[string]mock.Handler{
+			"add": func(ctx *mock.Context) *types.TransactionError {
+				if err := ctx.UseGas(500); err != nil {
+					return err
+				}
+				n, _ := ctx.Args[0].(json.Number).Int64()
+				count := ctx.DB.GetData(ctx.Contract, []byte("count"))[0]
+				ctx.DB.SetData(ctx.Contract, []byte("count"), []byte{count + byte(n)})
+				ctx.Emit(topic, []byte(ctx.Args[0].(json.Number).String()))
+				return nil
+			},
+		},
+	})
+	bh := &types.BlockHeader{Height: 10}
+	run := func(raw *types.RawTransaction) (stateTransition, *result) {
+		raw.Source = &source
+		if raw.Value == nil {
+			raw.Value = types.NewBigInt(0)
+		}
+		raw.GasLimit = types.NewBigInt(100000)
+		raw.GasPrice = types.NewBigInt(500)
+		st := newStateTransition(db, types.NewTransaction(raw, raw.GenHash()), bh)
+		if err := st.ParseTransaction(); err != nil {
+			t.Fatal(err)
+		}
+		return st, st.Transition()
+	}
+
+	code := []byte(`{"code":"class Counter(object):\n    pass","contract_name":"Counter"}`)
+	_, ret := run(&types.RawTransaction{Type: types.TransactionTypeContractCreate, Nonce: 1, Data: code, Value: types.NewBigInt(100)})
+	if ret.err != nil || ret.transitionStatus != types.RSSuccess {
+		t.Fatalf("deploy error %v", ret.err)
+	}
+	contractAddress := ret.contractAddress
+	if db.GetBalance(contractAddress).Uint64() != 100 || db.GetData(contractAddress, []byte("count"))[0] != 0 {
+		t.Fatalf("contract not deployed")
+	}
+
+	st, ret := run(&types.RawTransaction{Type: types.TransactionTypeContractCall, Nonce: 2, Target: &contractAddress, Data: []byte(`{"func_name":"add","args":[2]}`)})
+	if ret.err != nil || db.GetData(contractAddress, []byte("count"))[0] != 2 {
+		t.Fatalf("call error %v", ret.err)
+	}
+	if len(ret.logs) != 1 || ret.logs[0].Topic != topic || ret.logs[0].Address != contractAddress || ret.logs[0].BlockNumber != 10 {
+		t.Fatalf("logs error %+v", ret.logs)
+	}
+	if st.GasUsed().Uint64() != st.(*contractCaller).intrinsicGasUsed.Uint64()+500 {
+		t.Fatalf("gas used error %v", st.GasUsed())
+	}
+
+	_, ret = run(&types.RawTransaction{Type: types.TransactionTypeContractCall, Nonce: 3, Target: &contractAddress, Data: []byte(`{"func_name":"sub","args":[1]}`)})
+	if ret.transitionStatus != types.RSAbiError || ret.logs != nil {
+		t.Fatalf("calling the unknown function should fail with the abi error, got %v", ret.transitionStatus)
+	}
+	noCode := randomAddress()
+	_, ret = run(&types.RawTransaction{Type: types.TransactionTypeContractCall, Nonce: 4, Target: &noCode, Data: []byte(`{"func_name":"add","args":[1]}`)})
+	if ret.transitionStatus != types.RSNoCodeError {
+		t.Fatalf("calling the address without code should fail, got %v", ret.transitionStatus)
+	}
+	_, ret = run(&types.RawTransaction{Type: types.TransactionTypeContractCall, Nonce: 5, Target: &contractAddress, Data: []byte(`{"func_name":"add","args":[1]}`), Value: types.NewBigInt(10000)})
+	if ret.transitionStatus != types.RSBalanceNotEnough {
+		t.Fatalf("calling with value more than the balance should fail, got %v", ret.transitionStatus)
+	}
+}
@@ -446,6 +446,7 @@ func (ts *txSyncer) onTxResponse(msg notify.Message) error {
 	}
 	if evilCount > txValidteErrorLimit {
 		peerManagerImpl.addEvilCount(nm.Source())
+		network.ReportPeer(nm.Source(), network.PeerEventBadTxs)
 		err := fmt.Errorf("rec tx evil count over limit,count is %d", evilCount)
 		ts.logger.Error(err)
 		return err
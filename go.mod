@@ -13,11 +13,13 @@ require (
 	github.com/gogo/protobuf v1.2.1
 	github.com/gohouse/gorose v1.0.5
 	github.com/golang/protobuf v1.3.1
+	github.com/golang/snappy v0.0.0-20180518054509-2e65f85255db
 	github.com/hashicorp/golang-lru v0.5.1
 	github.com/howeyc/gopass v0.0.0-20170109162249-bf9dde6d0d2c
 	github.com/minio/sha256-simd v0.1.0
 	github.com/peterh/liner v1.1.0
 	github.com/pmylund/sortutil v0.0.0-20120526081524-abeda66eb583
+	github.com/prometheus/client_golang v0.9.4
 	github.com/rs/cors v1.6.0
 	github.com/sirupsen/logrus v1.4.2
 	github.com/syndtr/goleveldb v1.0.0
@@ -29,4 +31,5 @@ require (
 	gopkg.in/alecthomas/kingpin.v2 v2.2.6
 	gopkg.in/fatih/set.v0 v0.2.1
 	gopkg.in/natefinch/npipe.v2 v2.0.0-20160621034901-c1b8fa8bdcce
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,142 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package metrics exposes the metrics of the node in the prometheus format.
+// The metrics are always collected, the http endpoint is opt-in by Start
+package metrics
+
+import (
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "zvchain"
+
+	// Path of the http endpoint
+	Path = "/metrics"
+)
+
+// Metrics updated by the modules
+var (
+	// BlockProcessTime is the time of adding the block on chain, labeled by the add result
+	BlockProcessTime = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "chain",
+		Name:      "block_process_seconds",
+		Help:      "Time of adding the block on chain",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"result"})
+
+	// ForkEvents counts the events of the fork processing
+	ForkEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "chain",
+		Name:      "fork_events_total",
+		Help:      "Events of the fork processing",
+	}, []string{"event"})
+
+	// P2PMessages counts the p2p messages by the direction(in or out) and the message code
+	P2PMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "p2p",
+		Name:      "messages_total",
+		Help:      "P2P messages by direction and code",
+	}, []string{"direction", "code"})
+
+	// P2PBytes counts the bytes of the p2p messages by the direction(in or out) and the message code
+	P2PBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "p2p",
+		Name:      "bytes_total",
+		Help:      "Bytes of the p2p messages by direction and code",
+	}, []string{"direction", "code"})
+
+	// ConsensusSlots counts the status changes of the block proposal slots
+	ConsensusSlots = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "consensus",
+		Name:      "slot_status_total",
+		Help:      "Status changes of the block proposal slots",
+	}, []string{"status"})
+
+	// GroupCreateEras is the number of the group-creation eras by status, see consensus/group createStat
+	GroupCreateEras = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "group",
+		Name:      "create_eras",
+		Help:      "Group-creation eras by status",
+	}, []string{"status"})
+)
+
+var (
+	registry = prometheus.NewRegistry()
+	gauges   = make(map[string]prometheus.Collector)
+	mutex    sync.Mutex
+)
+
+func init() {
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		BlockProcessTime,
+		ForkEvents,
+		P2PMessages,
+		P2PBytes,
+		ConsensusSlots,
+		GroupCreateEras,
+	)
+}
+
+// RegisterGauge registers the gauge whose value is read by f on each scrape.
+// The gauge registered before with the same name is replaced
+func RegisterGauge(subsystem, name, help string, f func() float64) {
+	g := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      name,
+		Help:      help,
+	}, f)
+	key := prometheus.BuildFQName(namespace, subsystem, name)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if old, ok := gauges[key]; ok {
+		registry.Unregister(old)
+	}
+	registry.MustRegister(g)
+	gauges[key] = g
+}
+
+// Handler returns the http handler serving the metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Start serves the metrics at the address in the background
+func Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(Path, Handler())
+	go http.Serve(listener, mux)
+	return nil
+}
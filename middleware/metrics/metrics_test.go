@@ -0,0 +1,66 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T) string {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("scrape status %v", rec.Code)
+	}
+	body, err := ioutil.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestRegisterGauge(t *testing.T) {
+	RegisterGauge("chain", "height", "test", func() float64 { return 10 })
+	// Registered again on reinit
+	RegisterGauge("chain", "height", "test", func() float64 { return 20 })
+	P2PMessages.WithLabelValues("in", "10001").Inc()
+	P2PBytes.WithLabelValues("in", "10001").Add(100)
+
+	body := scrape(t)
+	for _, s := range []string{
+		"zvchain_chain_height 20",
+		`zvchain_p2p_messages_total{code="10001",direction="in"} 1`,
+		`zvchain_p2p_bytes_total{code="10001",direction="in"} 100`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, s) {
+			t.Fatalf("%v not found in:\n%v", s, body)
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	if err := Start("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Start("bad address"); err == nil {
+		t.Fatalf("start should fail")
+	}
+}
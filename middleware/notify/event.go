@@ -35,4 +35,9 @@ const (
 	TxSyncNotify   = "tx_sync_notify"
 	TxSyncReq      = "tx_sync_req"
 	TxSyncResponse = "tx_sync_response"
+
+	BlockHeadersReq      = "block_headers_req"
+	BlockHeadersResponse = "block_headers_response"
+	BlockBodiesReq       = "block_bodies_req"
+	BlockBodiesResponse  = "block_bodies_response"
 )
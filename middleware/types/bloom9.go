@@ -89,6 +89,9 @@ func LogsBloom(logs []*Log) *big.Int {
 	for _, log := range logs {
 		bin.Or(bin, bloom9(log.Address.Bytes()))
 		bin.Or(bin, bloom9(log.Topic[:]))
+		for _, topic := range log.Topics {
+			bin.Or(bin, bloom9(topic[:]))
+		}
 
 	}
 
@@ -16,8 +16,12 @@
 package types
 
 import (
+	"bytes"
 	"math/big"
 	"testing"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/vmihailenco/msgpack"
 )
 
 func TestBloom(t *testing.T) {
@@ -49,6 +53,47 @@ func TestBloom(t *testing.T) {
 	}
 }
 
+func TestCreateBloom_Topics(t *testing.T) {
+	nameTopic := common.BytesToHash(common.Sha256([]byte("transfer")))
+	argTopic := common.BytesToHash(common.Sha256([]byte(`"zv01"`)))
+	log := &Log{Address: common.StringToAddress("zve75051bf0048decaffa55e3a9fa33e87ed802aaba5038b0fd7f49401f5d8b019"), Topic: nameTopic, Topics: []common.Hash{nameTopic, argTopic}}
+	bloom := CreateBloom(Receipts{{Logs: []*Log{log}}})
+
+	for _, b := range [][]byte{log.Address.Bytes(), nameTopic.Bytes(), argTopic.Bytes()} {
+		if !bloom.TestBytes(b) {
+			t.Fatalf("expected %x to test true", b)
+		}
+	}
+	if bloom.TestBytes(common.Sha256([]byte("approval"))) {
+		t.Fatalf("did not expect the other topic to test true")
+	}
+}
+
+func TestLog_EncodeWithoutTopics(t *testing.T) {
+	type legacyLog struct {
+		Address     common.Address
+		Topic       common.Hash
+		Data        []byte
+		BlockNumber uint64
+		TxHash      common.Hash
+		TxIndex     uint
+		Index       uint
+		Removed     bool
+	}
+	log := &Log{Topic: common.BytesToHash(common.Sha256([]byte("transfer"))), Data: []byte("[1]"), BlockNumber: 10}
+	b1, err := msgpack.Marshal(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b2, err := msgpack.Marshal(&legacyLog{Topic: log.Topic, Data: log.Data, BlockNumber: log.BlockNumber})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b1, b2) {
+		t.Fatalf("encoding of the log without topics changed")
+	}
+}
+
 /*
 import (
 	"testing"
@@ -59,6 +59,10 @@ type ConsensusHelper interface {
 	// VerifyBlockHeaders checks if the group is legal and the group signature is correct
 	VerifyBlockHeaders(pre, bh *BlockHeader) (ok bool, err error)
 
+	// VerifyBlockHeadersBatch checks the chained headers following pre with batch signature verification,
+	// returns the number of leading headers verified
+	VerifyBlockHeadersBatch(pre *BlockHeader, bhs []*BlockHeader) (verified int, err error)
+
 	GroupSkipCountsBetween(preBH *BlockHeader, h uint64) map[common.Hash]uint16
 
 	// return the min elapsed second for blocks
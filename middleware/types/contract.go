@@ -0,0 +1,56 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/storage/account"
+)
+
+const (
+	// ContractSaltFlag is the first byte of the extra data of the contract create transactions
+	// deploying to the salted address, followed by the salt
+	ContractSaltFlag   byte = 0x01
+	ContractSaltLength      = 32
+)
+
+// SaltExtraData returns the extra data of the contract create transaction deploying to the
+// address derived from the salt
+func SaltExtraData(salt common.Hash) []byte {
+	return append([]byte{ContractSaltFlag}, salt.Bytes()...)
+}
+
+// ContractSalt returns the salt if the extra data asks for the salted address
+func ContractSalt(extraData []byte) ([]byte, bool) {
+	if len(extraData) != 1+ContractSaltLength || extraData[0] != ContractSaltFlag {
+		return nil, false
+	}
+	return extraData[1:], true
+}
+
+// SaltedContractAddress returns the address of the contract deployed by the sender with the
+// salt, which is sha256(sender ‖ salt ‖ sha256(code)) and known before the deployment
+func SaltedContractAddress(sender common.Address, salt []byte, code []byte) common.Address {
+	return common.BytesToAddress(common.Sha256(common.BytesCombine(sender.Bytes(), salt, common.Sha256(code))))
+}
+
+// IsContractAddressFree returns whether the contract can be deployed at the salted address,
+// which is true if no code there. The account may exist already, funded before the deployment
+// as the address is known
+func IsContractAddressFree(db AccountDB, addr common.Address) bool {
+	codeHash := db.GetCodeHash(addr)
+	return codeHash == common.Hash{} || codeHash == account.EmptyCodeHash
+}
@@ -0,0 +1,49 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/darren0718/zvchain/common"
+)
+
+func TestContractSalt(t *testing.T) {
+	salt := common.BytesToHash(common.Sha256([]byte("salt")))
+	s, ok := ContractSalt(SaltExtraData(salt))
+	if !ok || !bytes.Equal(s, salt.Bytes()) {
+		t.Fatalf("salt error %x", s)
+	}
+	for _, extra := range [][]byte{nil, {ContractSaltFlag}, append([]byte{0x02}, salt.Bytes()...), append(SaltExtraData(salt), 0)} {
+		if _, ok := ContractSalt(extra); ok {
+			t.Fatalf("extra data %x shouldn't be salted", extra)
+		}
+	}
+}
+
+func TestSaltedContractAddress(t *testing.T) {
+	sender := common.StringToAddress("zv0000000000000000000000000000000000000000000000000000000000000001")
+	salt, code := []byte("salt"), []byte("code")
+	addr := SaltedContractAddress(sender, salt, code)
+	if addr != SaltedContractAddress(sender, salt, code) {
+		t.Fatalf("salted address should be deterministic")
+	}
+	other := common.StringToAddress("zv0000000000000000000000000000000000000000000000000000000000000002")
+	if addr == SaltedContractAddress(other, salt, code) || addr == SaltedContractAddress(sender, []byte("salt2"), code) || addr == SaltedContractAddress(sender, salt, []byte("code2")) {
+		t.Fatalf("salted address should depend on the sender, the salt and the code")
+	}
+}
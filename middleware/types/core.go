@@ -50,6 +50,7 @@ const (
 	TVMCheckABIError     = 1003
 	TVMCallMaxDeepError  = 1004
 	TVMNoCodeError       = 1005
+	TVMCallLimitError    = 1006 // The storage written or the events emitted exceed the limits of the call
 
 	txFixSize = 200 // Fixed size for each transaction
 )
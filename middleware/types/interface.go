@@ -182,6 +182,33 @@ type VMExecutor interface {
 	Execute(statedb AccountDB, block *Block) (Receipts, *common.Hash, uint64, error)
 }
 
+// MinerManager executes the miner operations called from the contracts
+type MinerManager interface {
+	ExecuteOperation(accountdb AccountDB, msg TxMessage, height uint64) (success bool, err error)
+}
+
+// ContractEngine runs the contracts of a transaction, created for each transaction executed
+type ContractEngine interface {
+	// HasCode returns whether the contract at the address has the code to run
+	HasCode(contract common.Address) bool
+
+	// Deploy runs the deployment of the contract whose code is already set at the address
+	Deploy(contract common.Address) *TransactionError
+
+	// Call calls the contract by the sender with the abi
+	Call(sender common.Address, contract common.Address, abiJSON string) *TransactionError
+
+	// GasLeft returns the gas left of the transaction
+	GasLeft() uint64
+
+	// Logs returns the logs generated by the last successful deployment or call
+	Logs() []*Log
+}
+
+// ContractEngineFactory creates the engine running the contracts of the transaction in the block,
+// with the gas already used by the transaction
+type ContractEngineFactory func(db AccountDB, reader ChainReader, bh *BlockHeader, msg TxMessage, gasUsed uint64, mm MinerManager) ContractEngine
+
 // AccountRepository contains account query interface
 type AccountRepository interface {
 	// GetBalance return the balance of specified address
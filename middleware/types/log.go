@@ -31,6 +31,9 @@ type Log struct {
 	Address common.Address `json:"address" gencodec:"required"`
 	// list of topics provided by the contract.
 	Topic common.Hash `json:"topic" gencodec:"required"`
+	// hash of the event name followed by the hashes of the indexed arguments, set since zip006.
+	// Omitted in the encoding if empty to keep the receipts before unchanged
+	Topics []common.Hash `json:"topics,omitempty" msgpack:"topics,omitempty"`
 	// supplied by the contract, usually ABI-encoded
 	Data []byte `json:"data" gencodec:"required"`
 
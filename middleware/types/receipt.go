@@ -62,6 +62,12 @@ type Receipt struct {
 	ContractAddress common.Address `json:"contractAddress"`
 	Height          uint64         `json:"height"`
 	TxIndex         uint16         `json:"tx_index"`
+
+	// Gas used by the transaction, the effective gas price and the fee charged, set since zip009.
+	// Omitted in the encoding if empty to keep the receipts before unchanged
+	GasUsed  uint64 `json:"gasUsed" msgpack:"gasUsed,omitempty"`
+	GasPrice uint64 `json:"effectiveGasPrice" msgpack:"gasPrice,omitempty"`
+	Fee      uint64 `json:"fee" msgpack:"fee,omitempty"`
 }
 
 func NewReceipt(root []byte, status ReceiptStatus, cumulativeGasUsed uint64) *Receipt {
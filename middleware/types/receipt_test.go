@@ -0,0 +1,81 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/vmihailenco/msgpack"
+)
+
+type legacyReceipt struct {
+	PostState         []byte
+	Status            ReceiptStatus
+	CumulativeGasUsed uint64
+	Bloom             Bloom
+	Logs              []*Log
+	TxHash            common.Hash
+	ContractAddress   common.Address
+	Height            uint64
+	TxIndex           uint16
+}
+
+func TestReceipt_EncodeWithoutFee(t *testing.T) {
+	receipt := NewReceipt(nil, RSSuccess, 1000)
+	receipt.TxHash = common.BytesToHash(common.Sha256([]byte("tx")))
+	receipt.Height = 10
+	receipt.TxIndex = 1
+
+	b1, err := msgpack.Marshal(receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := &legacyReceipt{Status: receipt.Status, CumulativeGasUsed: receipt.CumulativeGasUsed, TxHash: receipt.TxHash, Height: receipt.Height, TxIndex: receipt.TxIndex}
+	b2, err := msgpack.Marshal(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b1, b2) {
+		t.Fatalf("encoding of the receipt without fee changed")
+	}
+
+	decoded := &Receipt{}
+	if err := msgpack.Unmarshal(b2, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.CumulativeGasUsed != 1000 || decoded.GasUsed != 0 || decoded.Fee != 0 {
+		t.Fatalf("decode legacy receipt error: %+v", decoded)
+	}
+}
+
+func TestReceipt_EncodeWithFee(t *testing.T) {
+	receipt := NewReceipt(nil, RSSuccess, 1000)
+	receipt.GasUsed, receipt.GasPrice, receipt.Fee = 1000, 500, 500000
+
+	b, err := msgpack.Marshal(receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &Receipt{}
+	if err := msgpack.Unmarshal(b, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.GasUsed != 1000 || decoded.GasPrice != 500 || decoded.Fee != 500000 {
+		t.Fatalf("decode receipt error: %+v", decoded)
+	}
+}
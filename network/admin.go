@@ -0,0 +1,212 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PeerInfo is the status of a peer for the node operator
+type PeerInfo struct {
+	ID              string
+	IP              string
+	Port            int
+	Session         uint32
+	Source          PeerSource
+	Authed          bool
+	ChainID         uint16
+	BytesSend       int
+	BytesReceived   int
+	DisconnectCount int
+	SessionAge      time.Duration // Zero if not connected
+	ProtocolVersion uint16        // Negotiated in the handshake, zero before
+	Capabilities    []string
+}
+
+// NodeInfo is the status of the local node
+type NodeInfo struct {
+	ID                 string
+	IP                 string
+	Port               int
+	NetID              uint64
+	ChainID            uint16
+	ProtocolVersion    uint16
+	MinProtocolVersion uint16
+	Capabilities       []string
+	Transport          string
+	PeerCount          int
+	KadSize            int
+	StaticNodes        []string
+}
+
+// ParseStaticNode parses the node in the form of id@ip:port
+func ParseStaticNode(s string) (*Node, error) {
+	id, host, port, err := splitNodeURL(s)
+	if err != nil {
+		return nil, err
+	}
+	return newStaticNode(id, host, port)
+}
+
+// ParseBootNode parses the node in the form of id@host:port, the host is resolved if
+// it's a domain name
+func ParseBootNode(s string) (*Node, error) {
+	id, host, port, err := splitNodeURL(s)
+	if err != nil {
+		return nil, err
+	}
+	ip, err := getIPByAddress(host)
+	if err != nil || ip == nil {
+		return nil, fmt.Errorf("resolve boot node host %v error:%v", host, err)
+	}
+	return newStaticNode(id, ip.String(), port)
+}
+
+func splitNodeURL(s string) (id string, host string, port int, err error) {
+	s = strings.TrimSpace(s)
+	parts := strings.Split(s, "@")
+	if len(parts) != 2 {
+		return "", "", 0, fmt.Errorf("bad node %v, should be id@ip:port", s)
+	}
+	host, portStr, err := net.SplitHostPort(parts[1])
+	if err != nil {
+		return "", "", 0, fmt.Errorf("bad node address %v:%v", parts[1], err)
+	}
+	p, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || p == 0 {
+		return "", "", 0, fmt.Errorf("bad node port %v", portStr)
+	}
+	return parts[0], host, int(p), nil
+}
+
+func newStaticNode(id string, ip string, port int) (*Node, error) {
+	nID := NewNodeID(strings.TrimSpace(id))
+	if nID == nil || !nID.IsValid() {
+		return nil, fmt.Errorf("bad node id %v", id)
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil, fmt.Errorf("bad node ip %v", ip)
+	}
+	if port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("bad node port %v", port)
+	}
+	n := NewNode(*nID, addr, port)
+	if err := n.validateComplete(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// AddPeer adds the node as a static node, which is always kept connected until removed
+func AddPeer(id string, ip string, port int) error {
+	if netCore == nil {
+		return fmt.Errorf("network not initialized")
+	}
+	n, err := newStaticNode(id, ip, port)
+	if err != nil {
+		return err
+	}
+	if n.ID == netCore.ID {
+		return fmt.Errorf("can't add self")
+	}
+	netCore.peerManager.addStatic(n)
+	netCore.kad.addStatic(n)
+	go netCore.ping(n.ID, n.addr())
+	return nil
+}
+
+// RemovePeer removes the node from the static nodes and disconnects it
+func RemovePeer(id string) error {
+	if netCore == nil {
+		return fmt.Errorf("network not initialized")
+	}
+	nID := NewNodeID(strings.TrimSpace(id))
+	if nID == nil || !nID.IsValid() {
+		return fmt.Errorf("bad node id %v", id)
+	}
+	removed := netCore.peerManager.removeStatic(*nID)
+	netCore.kad.removeStatic(*nID)
+	if netCore.peerManager.peerByID(*nID) == nil {
+		if removed {
+			return nil
+		}
+		return fmt.Errorf("peer %v not found", id)
+	}
+	netCore.peerManager.disconnect(*nID)
+	return nil
+}
+
+// Peers returns the status of the peers, sorted by id
+func Peers() []*PeerInfo {
+	if netCore == nil {
+		return nil
+	}
+	pm := netCore.peerManager
+	pm.mutex.RLock()
+	peers := make(map[uint64]*Peer, len(pm.peers))
+	for netID, p := range pm.peers {
+		peers[netID] = p
+	}
+	pm.mutex.RUnlock()
+
+	infos := make([]*PeerInfo, 0, len(peers))
+	for netID, p := range peers {
+		info := p.info()
+		info.Source = pm.peerSource(netID, p)
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// GetNodeInfo returns the status of the local node
+func GetNodeInfo() *NodeInfo {
+	if netCore == nil || netServerInstance == nil {
+		return nil
+	}
+	self := netServerInstance.Self
+	info := &NodeInfo{
+		ID:                 netCore.ID.GetHexString(),
+		IP:                 self.IP.String(),
+		Port:               self.Port,
+		NetID:              netCore.netID,
+		ChainID:            netCore.chainID,
+		ProtocolVersion:    netCore.protocolVersion,
+		MinProtocolVersion: netCore.versions.min,
+		Capabilities:       CapabilityNames(netCore.capabilities),
+		Transport:          netServerInstance.config.Transport,
+		StaticNodes:        make([]string, 0),
+	}
+	netCore.peerManager.mutex.RLock()
+	info.PeerCount = len(netCore.peerManager.peers)
+	netCore.peerManager.mutex.RUnlock()
+	if netCore.kad != nil {
+		info.KadSize = netCore.kad.len()
+	}
+	for _, n := range netCore.peerManager.staticNodes() {
+		info.StaticNodes = append(info.StaticNodes, fmt.Sprintf("%v@%v", n.ID.GetHexString(), net.JoinHostPort(n.IP.String(), strconv.Itoa(n.Port))))
+	}
+	sort.Strings(info.StaticNodes)
+	return info
+}
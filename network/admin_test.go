@@ -0,0 +1,123 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/darren0718/zvchain/log"
+)
+
+func TestParseStaticNode(t *testing.T) {
+	n, err := ParseStaticNode(" zv0000000000000000000000000000000000000000000000000000000000001234@10.0.0.1:1122 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.ID.GetHexString() != "zv0000000000000000000000000000000000000000000000000000000000001234" || n.IP.String() != "10.0.0.1" || n.Port != 1122 {
+		t.Fatalf("parse error %+v", n)
+	}
+
+	bads := []string{
+		"",
+		"10.0.0.1:1122",
+		"zv1234@10.0.0.1",
+		"zv1234@10.0.0.1:0",
+		"zv1234@10.0.0.1:70000",
+		"zv1234@host:1122",
+		"zv0000@10.0.0.1:1122",
+	}
+	for _, s := range bads {
+		if _, err := ParseStaticNode(s); err == nil {
+			t.Fatalf("parse %v should fail", s)
+		}
+	}
+}
+
+func TestPeerManager_Static(t *testing.T) {
+	if Logger == nil {
+		Logger = log.P2PLogger
+	}
+	pm := newPeerManager()
+	pm.peerIPSet.Limit = 1
+
+	n, _ := newStaticNode("zv1234", "10.0.0.1", 1122)
+	netID := genNetID(n.ID)
+	pm.addStatic(n)
+	if !pm.isStatic(netID) || pm.isBanned(netID) {
+		t.Fatalf("static node error")
+	}
+
+	other, _ := newStaticNode("zv5678", "10.0.0.1", 1122)
+	p := newPeer(other.ID, 0)
+	p.IP = other.IP
+	if !pm.addPeer(genNetID(other.ID), p) {
+		t.Fatalf("add peer failed")
+	}
+	// Static node is not limited by ip
+	sp := newPeer(n.ID, 0)
+	sp.IP = n.IP
+	if !pm.addPeer(netID, sp) {
+		t.Fatalf("static node limited by ip")
+	}
+	if s := pm.peerSource(netID, sp); s != PeerSourceStatic {
+		t.Fatalf("static source error %v", s)
+	}
+	p.addGroup("g")
+	if s := pm.peerSource(genNetID(other.ID), p); s != PeerSourceGroup {
+		t.Fatalf("group source error %v", s)
+	}
+
+	if !pm.removeStatic(n.ID) || pm.isStatic(netID) || pm.removeStatic(n.ID) {
+		t.Fatalf("remove static error")
+	}
+}
+
+func TestKad_AddStatic(t *testing.T) {
+	if Logger == nil {
+		Logger = log.P2PLogger
+	}
+	self := NewNode(*NewNodeID("zv01"), net.ParseIP("127.0.0.1"), 1122)
+	kad := &Kad{self: self, static: make(map[NodeID]*Node)}
+	for i := range kad.buckets {
+		kad.buckets[i] = &bucket{}
+	}
+
+	// Fill the bucket of the static node first
+	static, _ := newStaticNode("zvffff", "10.0.0.1", 1122)
+	b := kad.bucket(static.sha)
+	for i := 0; len(b.entries) < bucketSize; i++ {
+		n := NewNode(*NewNodeID(fmt.Sprintf("zvff%04x", i)), net.ParseIP("10.0.0.2"), 1122)
+		if kad.bucket(n.sha) == b {
+			kad.add(n)
+		}
+	}
+	kad.addStatic(static)
+	if kad.find(static.ID) == nil || !kad.isStatic(static.ID) {
+		t.Fatalf("static node not added")
+	}
+	if len(b.entries) != bucketSize || len(b.replacements) != 1 {
+		t.Fatalf("bucket size error %v %v", len(b.entries), len(b.replacements))
+	}
+
+	// Lost entries are added back on refresh
+	b.entries = deleteNode(b.entries, static)
+	kad.loadStaticNodes()
+	if kad.find(static.ID) == nil {
+		t.Fatalf("static node not reloaded")
+	}
+}
@@ -0,0 +1,157 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// Capabilities advertised in the handshake by ping and pong. Nodes not advertising any
+// of them are sent the messages the same as before
+const (
+	CapCompression uint32 = 1 << iota // Accepts MsgData.Data compressed by snappy
+	capSnapSync                       // Reserved for the snap sync, never advertised as not implemented yet
+	CapHeaderSync                     // Serves the headers and the bodies for the header-first sync
+)
+
+// advertisableCapabilities are the ones implemented, which may be set by the config
+const advertisableCapabilities = CapHeaderSync
+
+var capabilityNames = []string{"compression", "snap_sync", "header_sync"}
+
+// legacyProtocolVersion is assumed for the peers not advertising the version range
+const legacyProtocolVersion uint16 = 1
+
+const msgDataInfoField = 10 // Field number of MessageInfo in MsgData
+
+var (
+	errIncompatibleVersion = errors.New("incompatible protocol version")
+	errPeerLacksCapability = errors.New("peer lacks the capability of the message")
+)
+
+// codeCapabilities are the capabilities required by the message codes, the peers
+// lacking any of them are skipped when sending
+var codeCapabilities = map[uint32]uint32{
+	ReqBlockHeaders:      CapHeaderSync,
+	BlockHeadersResponse: CapHeaderSync,
+	ReqBlockBodies:       CapHeaderSync,
+	BlockBodiesResponse:  CapHeaderSync,
+}
+
+func requiredCapabilities(code uint32) uint32 {
+	return codeCapabilities[code]
+}
+
+// CapabilityNames returns the names of the capabilities set
+func CapabilityNames(caps uint32) []string {
+	names := make([]string, 0)
+	for i, name := range capabilityNames {
+		if caps&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// versionRange is the range of the protocol versions supported by the node
+type versionRange struct {
+	min uint16
+	max uint16
+}
+
+func newVersionRange(min, max uint16) versionRange {
+	if min == 0 || min > max {
+		min = max
+	}
+	return versionRange{min: min, max: max}
+}
+
+// negotiate returns the highest version supported by both sides, false if the ranges
+// don't overlap. The peer not advertising the range is taken as the legacy version
+func (r versionRange) negotiate(remoteMin, remoteMax uint32) (uint16, bool) {
+	if remoteMax == 0 {
+		remoteMin, remoteMax = uint32(legacyProtocolVersion), uint32(legacyProtocolVersion)
+	}
+	if remoteMin > remoteMax {
+		return 0, false
+	}
+	v := uint32(r.max)
+	if remoteMax < v {
+		v = remoteMax
+	}
+	if v < uint32(r.min) || v < remoteMin {
+		return 0, false
+	}
+	return uint16(v), true
+}
+
+// handleHandshake negotiates the protocol version by the range the peer advertised in
+// ping or pong, the peer is disconnected if no version is supported by both
+func (nc *NetCore) handleHandshake(p *Peer, versionMin, versionMax, caps uint32) error {
+	v, ok := nc.versions.negotiate(versionMin, versionMax)
+	if !ok {
+		Logger.Infof("protocol version of peer %v is [%v, %v], ours is [%v, %v], disconnect",
+			p.ID.GetHexString(), versionMin, versionMax, nc.versions.min, nc.versions.max)
+		p.disconnect()
+		return errIncompatibleVersion
+	}
+	p.setHandshake(v, caps)
+	return nil
+}
+
+// setPacketProtocolVersion returns the encoded MsgData packet with the protocol version
+// in MessageInfo replaced, the packet itself if it isn't a data packet
+func setPacketProtocolVersion(packet []byte, version uint16) []byte {
+	if len(packet) < PacketHeadSize || MessageType(binary.BigEndian.Uint32(packet)) != MessageType_MessageData {
+		return packet
+	}
+	body := packet[PacketHeadSize:]
+	out := make([]byte, PacketHeadSize, len(packet)+8)
+	for len(body) > 0 {
+		field, wire, value, n := nextField(body)
+		if n == 0 {
+			return packet
+		}
+		if field == msgDataInfoField && wire == proto.WireVarint {
+			info, _ := proto.DecodeVarint(value)
+			chainID, _ := decodeMessageInfo(uint32(info))
+			out = append(out, proto.EncodeVarint(msgDataInfoField<<3|proto.WireVarint)...)
+			out = append(out, proto.EncodeVarint(uint64(encodeMessageInfo(chainID, version)))...)
+		} else {
+			out = append(out, body[:n]...)
+		}
+		body = body[n:]
+	}
+	binary.BigEndian.PutUint32(out, uint32(MessageType_MessageData))
+	binary.BigEndian.PutUint32(out[PacketTypeSize:], uint32(len(out)-PacketHeadSize))
+	return out
+}
+
+// PeerSupports returns whether the peer advertised all the capabilities in the handshake
+func PeerSupports(id string, caps uint32) bool {
+	if netCore == nil {
+		return false
+	}
+	nID := NewNodeID(id)
+	if nID == nil {
+		return false
+	}
+	p := netCore.peerManager.peerByID(*nID)
+	return p != nil && p.supports(caps)
+}
@@ -0,0 +1,111 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestVersionRange_Negotiate(t *testing.T) {
+	r := newVersionRange(2, 4)
+	cases := []struct {
+		min, max uint32
+		version  uint16
+		ok       bool
+	}{
+		{2, 4, 4, true},
+		{1, 3, 3, true},
+		{3, 9, 4, true},
+		{5, 9, 0, false},
+		{1, 1, 0, false},
+		{4, 3, 0, false},
+		{0, 0, 0, false}, // Legacy peer of version 1
+	}
+	for _, c := range cases {
+		v, ok := r.negotiate(c.min, c.max)
+		if v != c.version || ok != c.ok {
+			t.Fatalf("negotiate [%v, %v] got %v %v, expect %v %v", c.min, c.max, v, ok, c.version, c.ok)
+		}
+	}
+	if v, ok := newVersionRange(0, 1).negotiate(0, 0); !ok || v != legacyProtocolVersion {
+		t.Fatalf("legacy peer should be compatible, got %v %v", v, ok)
+	}
+}
+
+func TestCapabilityNames(t *testing.T) {
+	names := CapabilityNames(CapCompression | CapHeaderSync)
+	if !reflect.DeepEqual(names, []string{"compression", "header_sync"}) {
+		t.Fatalf("capability names error %v", names)
+	}
+	if len(CapabilityNames(0)) != 0 {
+		t.Fatalf("no capability expected")
+	}
+}
+
+func TestAdvertisableCapabilities(t *testing.T) {
+	// The snap sync isn't served, so never advertised even if configured
+	caps := (capSnapSync | CapHeaderSync) & advertisableCapabilities
+	if caps != CapHeaderSync {
+		t.Fatalf("advertised capabilities error %v", CapabilityNames(caps))
+	}
+}
+
+func TestSetPacketProtocolVersion(t *testing.T) {
+	data := bytes.Repeat([]byte{1}, 100)
+	packet := genDataPacket4Test(data)
+	out := setPacketProtocolVersion(packet, 300)
+
+	msg := decodeDataPacket4Test(t, out)
+	origin := decodeDataPacket4Test(t, packet)
+	chainID, version := decodeMessageInfo(msg.MessageInfo)
+	originChainID, _ := decodeMessageInfo(origin.MessageInfo)
+	if version != 300 || chainID != originChainID {
+		t.Fatalf("message info error %v %v", chainID, version)
+	}
+	if !bytes.Equal(msg.Data, data) || msg.MessageID != origin.MessageID || msg.MessageCode != origin.MessageCode {
+		t.Fatalf("fields changed %+v", msg)
+	}
+}
+
+func TestPeer_WriteCapability(t *testing.T) {
+	if !InitTestNetwork() {
+		t.Fatalf("init network failed")
+	}
+	code := ReqBlockHeaders
+	packet := bytes.NewBuffer(genDataPacket4Test([]byte{1, 2, 3}))
+	p := newPeer(netCore.ID, 0)
+	if err := netCore.handleHandshake(p, 1, uint32(netCore.protocolVersion), CapCompression); err != nil {
+		t.Fatal(err)
+	}
+	p.write(packet, code)
+	if p.bytesSend != 0 {
+		t.Fatalf("peer lacking capability shouldn't be sent")
+	}
+
+	if err := netCore.handleHandshake(p, 1, uint32(netCore.protocolVersion), CapCompression|CapHeaderSync); err != nil {
+		t.Fatal(err)
+	}
+	p.write(packet, code)
+	if p.bytesSend == 0 {
+		t.Fatalf("peer with capability should be sent")
+	}
+
+	if err := netCore.handleHandshake(p, uint32(netCore.protocolVersion)+1, uint32(netCore.protocolVersion)+2, 0); err != errIncompatibleVersion {
+		t.Fatalf("incompatible version expected, got %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+)
+
+// Compression algorithms of MsgData.Data
+const (
+	compressNone   uint32 = 0
+	compressSnappy uint32 = 1
+)
+
+const (
+	// Data smaller than it is sent uncompressed
+	compressThreshold = 1024
+
+	msgDataDataField        = 7  // Field number of Data in MsgData
+	msgDataCompressionField = 11 // Field number of Compression in MsgData
+)
+
+// compressor compresses the data packets sent to the peers accepting compression.
+// The packet broadcast to several peers is compressed only once
+type compressor struct {
+	enabled bool
+	mutex   sync.Mutex
+	lastSrc []byte // Copy of the last packet compressed
+	lastOut []byte // Compressed packet of lastSrc, nil if not compressed
+}
+
+func newCompressor(enabled bool) *compressor {
+	return &compressor{enabled: enabled}
+}
+
+// capabilities returns the capabilities advertised to the peers
+func (c *compressor) capabilities() uint32 {
+	if c == nil || !c.enabled {
+		return 0
+	}
+	return CapCompression
+}
+
+// compress returns the packet with the data compressed if the peer accepts it and the
+// compression pays off, otherwise the packet itself. The returned slice mustn't be modified
+func (c *compressor) compress(packet []byte, peerCaps uint32) []byte {
+	if c == nil || !c.enabled || peerCaps&CapCompression == 0 || len(packet) < PacketHeadSize+compressThreshold {
+		return packet
+	}
+	if MessageType(binary.BigEndian.Uint32(packet)) != MessageType_MessageData {
+		return packet
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.lastSrc != nil && bytes.Equal(c.lastSrc, packet) {
+		if c.lastOut == nil {
+			return packet
+		}
+		return c.lastOut
+	}
+	out, ok := compressDataPacket(packet)
+	c.lastSrc = append(c.lastSrc[:0], packet...)
+	c.lastOut = nil
+	if !ok {
+		return packet
+	}
+	c.lastOut = out
+	return out
+}
+
+// nextField splits the first field of the encoded protobuf message, returns the value
+// and the size of the whole field. The size is 0 if the message is malformed
+func nextField(data []byte) (field uint64, wire uint64, value []byte, n int) {
+	key, kn := proto.DecodeVarint(data)
+	if kn == 0 {
+		return 0, 0, nil, 0
+	}
+	field, wire = key>>3, key&7
+	rest := data[kn:]
+	switch wire {
+	case proto.WireVarint:
+		_, vn := proto.DecodeVarint(rest)
+		if vn == 0 {
+			return 0, 0, nil, 0
+		}
+		return field, wire, rest[:vn], kn + vn
+	case proto.WireFixed64:
+		if len(rest) < 8 {
+			return 0, 0, nil, 0
+		}
+		return field, wire, rest[:8], kn + 8
+	case proto.WireFixed32:
+		if len(rest) < 4 {
+			return 0, 0, nil, 0
+		}
+		return field, wire, rest[:4], kn + 4
+	case proto.WireBytes:
+		l, ln := proto.DecodeVarint(rest)
+		if ln == 0 || uint64(len(rest)-ln) < l {
+			return 0, 0, nil, 0
+		}
+		return field, wire, rest[ln : ln+int(l)], kn + ln + int(l)
+	}
+	return 0, 0, nil, 0
+}
+
+// compressDataPacket compresses the Data field of the encoded MsgData packet without
+// decoding the whole message, the other fields are copied as is
+func compressDataPacket(packet []byte) ([]byte, bool) {
+	body := packet[PacketHeadSize:]
+	others := make([]byte, 0, 256)
+	var data []byte
+	for len(body) > 0 {
+		field, wire, value, n := nextField(body)
+		if n == 0 {
+			return nil, false
+		}
+		switch {
+		case field == msgDataCompressionField:
+			return nil, false
+		case field == msgDataDataField && wire == proto.WireBytes:
+			data = value
+		default:
+			others = append(others, body[:n]...)
+		}
+		body = body[n:]
+	}
+	if len(data) < compressThreshold {
+		return nil, false
+	}
+	compressed := snappy.Encode(nil, data)
+	if len(compressed) >= len(data) {
+		return nil, false
+	}
+
+	out := make([]byte, PacketHeadSize, PacketHeadSize+len(others)+len(compressed)+16)
+	out = append(out, others...)
+	out = append(out, proto.EncodeVarint(msgDataDataField<<3|proto.WireBytes)...)
+	out = append(out, proto.EncodeVarint(uint64(len(compressed)))...)
+	out = append(out, compressed...)
+	out = append(out, proto.EncodeVarint(msgDataCompressionField<<3|proto.WireVarint)...)
+	out = append(out, proto.EncodeVarint(uint64(compressSnappy))...)
+
+	binary.BigEndian.PutUint32(out, uint32(MessageType_MessageData))
+	binary.BigEndian.PutUint32(out[PacketTypeSize:], uint32(len(out)-PacketHeadSize))
+	return out, true
+}
+
+// decompressData restores the data of the message compressed by the sender, the
+// decompressed data can't exceed maxSize
+func decompressData(msg *MsgData, maxSize int) error {
+	switch msg.Compression {
+	case compressNone:
+		return nil
+	case compressSnappy:
+		n, err := snappy.DecodedLen(msg.Data)
+		if err != nil {
+			return err
+		}
+		if n > maxSize {
+			return errMessageTooLarge
+		}
+		data, err := snappy.Decode(nil, msg.Data)
+		if err != nil {
+			return err
+		}
+		msg.Data = data
+		msg.Compression = compressNone
+		return nil
+	}
+	return fmt.Errorf("unknown compression %v", msg.Compression)
+}
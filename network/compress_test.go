@@ -0,0 +1,248 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/gogo/protobuf/proto"
+)
+
+// genBlockData4Test generates the encoded block with txCount transactions sent by a few accounts,
+// some of them calling contracts
+func genBlockData4Test(txCount int) []byte {
+	r := rand.New(rand.NewSource(int64(txCount)))
+	randBytes := func(n int) []byte {
+		b := make([]byte, n)
+		r.Read(b)
+		return b
+	}
+	addrs := make([]common.Address, 200)
+	for i := range addrs {
+		addrs[i] = common.BytesToAddress(randBytes(common.AddressLength))
+	}
+
+	txs := make([]*types.RawTransaction, 0, txCount)
+	for i := 0; i < txCount; i++ {
+		source := addrs[r.Intn(50)]
+		target := addrs[r.Intn(len(addrs))]
+		tx := &types.RawTransaction{
+			Value:    types.NewBigInt(uint64(r.Intn(100000)) * 1000000000),
+			Nonce:    uint64(i/50 + 1),
+			Target:   &target,
+			GasLimit: types.NewBigInt(3000),
+			GasPrice: types.NewBigInt(500),
+			Sign:     randBytes(65),
+			Source:   &source,
+		}
+		if i%10 == 0 {
+			tx.Type = 2
+			tx.Data = []byte(fmt.Sprintf(`{"func_name":"transfer","args":["%v",%v]}`, addrs[r.Intn(len(addrs))].AddrPrefixString(), r.Intn(1000000)))
+		}
+		txs = append(txs, tx)
+	}
+	header := &types.BlockHeader{
+		Hash:       common.BytesToHash(randBytes(common.HashLength)),
+		Height:     1000000,
+		PreHash:    common.BytesToHash(randBytes(common.HashLength)),
+		Elapsed:    3000,
+		ProveValue: randBytes(81),
+		TotalQN:    5000000,
+		Castor:     randBytes(32),
+		Group:      common.BytesToHash(randBytes(common.HashLength)),
+		Signature:  randBytes(65),
+		TxTree:     common.BytesToHash(randBytes(common.HashLength)),
+		StateTree:  common.BytesToHash(randBytes(common.HashLength)),
+		Random:     randBytes(65),
+	}
+	data, err := types.MarshalBlock(&types.Block{Header: header, Transactions: txs})
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func genDataPacket4Test(data []byte) []byte {
+	msg := &MsgData{
+		DataType:    DataType_DataGlobal,
+		MessageID:   12345,
+		SrcNodeID:   NewNodeID(peerID4Test).Bytes(),
+		Data:        data,
+		RelayCount:  1,
+		MessageCode: BlockResponseMsg,
+		MessageInfo: encodeMessageInfo(1, 1),
+		Expiration:  1000,
+	}
+	body, err := proto.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+	packet := make([]byte, PacketHeadSize, PacketHeadSize+len(body))
+	binary.BigEndian.PutUint32(packet, uint32(MessageType_MessageData))
+	binary.BigEndian.PutUint32(packet[PacketTypeSize:], uint32(len(body)))
+	return append(packet, body...)
+}
+
+func decodeDataPacket4Test(t *testing.T, packet []byte) *MsgData {
+	if int(binary.BigEndian.Uint32(packet[PacketTypeSize:])) != len(packet)-PacketHeadSize {
+		t.Fatalf("packet length error")
+	}
+	msg := new(MsgData)
+	if err := proto.Unmarshal(packet[PacketHeadSize:], msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestCompressor_Compress(t *testing.T) {
+	data := genBlockData4Test(1000)
+	packet := genDataPacket4Test(data)
+	c := newCompressor(true)
+
+	if out := c.compress(packet, 0); !bytes.Equal(out, packet) {
+		t.Fatalf("packet to the peer without compression shouldn't change")
+	}
+	out := c.compress(packet, CapCompression)
+	if len(out) >= len(packet) {
+		t.Fatalf("packet not compressed, %v >= %v", len(out), len(packet))
+	}
+	t.Logf("block of 1000 txs compressed %v -> %v", len(packet), len(out))
+	if again := c.compress(packet, CapCompression); &again[0] != &out[0] {
+		t.Fatalf("packet should be compressed only once")
+	}
+
+	msg := decodeDataPacket4Test(t, out)
+	if msg.Compression != compressSnappy {
+		t.Fatalf("compression error %v", msg.Compression)
+	}
+	if code, ok := peekMessageCode(out[PacketHeadSize:]); !ok || code != BlockResponseMsg {
+		t.Fatalf("peek code of the compressed packet error %v", code)
+	}
+	if err := decompressData(msg, len(data)-1); err != errMessageTooLarge {
+		t.Fatalf("decompressed size should be limited, got %v", err)
+	}
+	if err := decompressData(msg, len(data)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(msg.Data, data) || msg.Compression != compressNone {
+		t.Fatalf("decompressed data error")
+	}
+	origin := decodeDataPacket4Test(t, packet)
+	if msg.MessageID != origin.MessageID || msg.MessageCode != origin.MessageCode || msg.RelayCount != origin.RelayCount ||
+		msg.MessageInfo != origin.MessageInfo || !bytes.Equal(msg.SrcNodeID, origin.SrcNodeID) {
+		t.Fatalf("fields changed after compression %+v", msg)
+	}
+}
+
+func TestCompressor_Skip(t *testing.T) {
+	c := newCompressor(true)
+
+	small := genDataPacket4Test(bytes.Repeat([]byte{1}, compressThreshold-1))
+	if out := c.compress(small, CapCompression); !bytes.Equal(out, small) {
+		t.Fatalf("small data shouldn't be compressed")
+	}
+	random := make([]byte, 64*1024)
+	rand.Read(random)
+	incompressible := genDataPacket4Test(random)
+	if out := c.compress(incompressible, CapCompression); !bytes.Equal(out, incompressible) {
+		t.Fatalf("incompressible data shouldn't be compressed")
+	}
+	// The cached packet is changed in place
+	copy(incompressible[len(incompressible)/4:], make([]byte, len(random)/2))
+	if out := c.compress(incompressible, CapCompression); len(out) >= len(incompressible) {
+		t.Fatalf("changed packet should be compressed")
+	}
+
+	compressible := genDataPacket4Test(bytes.Repeat([]byte{1}, 64*1024))
+	if out := newCompressor(false).compress(compressible, CapCompression); !bytes.Equal(out, compressible) {
+		t.Fatalf("compression disabled")
+	}
+	if newCompressor(false).capabilities() != 0 || c.capabilities()&CapCompression == 0 {
+		t.Fatalf("capabilities error")
+	}
+	ping := genDataPacket4Test(bytes.Repeat([]byte{1}, 64*1024))
+	binary.BigEndian.PutUint32(ping, uint32(MessageType_MessagePing))
+	if out := c.compress(ping, CapCompression); !bytes.Equal(out, ping) {
+		t.Fatalf("only data packet should be compressed")
+	}
+
+	msg := &MsgData{Data: []byte{1, 2, 3}, Compression: 100}
+	if err := decompressData(msg, 1024); err == nil {
+		t.Fatalf("unknown compression should fail")
+	}
+}
+
+func benchmarkBlockSizes(b *testing.B, f func(b *testing.B, data []byte)) {
+	for _, n := range []int{100, 1000, 3000} {
+		data := genBlockData4Test(n)
+		b.Run(fmt.Sprintf("txs=%v/size=%vKB", n, len(data)/1024), func(b *testing.B) {
+			f(b, data)
+		})
+	}
+}
+
+func BenchmarkCompressDataPacket(b *testing.B) {
+	benchmarkBlockSizes(b, func(b *testing.B, data []byte) {
+		packet := genDataPacket4Test(data)
+		out, _ := compressDataPacket(packet)
+		b.Logf("compressed %v -> %v (%.1f%%)", len(packet), len(out), float64(len(out))*100/float64(len(packet)))
+		b.SetBytes(int64(len(packet)))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			compressDataPacket(packet)
+		}
+	})
+}
+
+func BenchmarkDecompressData(b *testing.B) {
+	benchmarkBlockSizes(b, func(b *testing.B, data []byte) {
+		out, _ := compressDataPacket(genDataPacket4Test(data))
+		msg := new(MsgData)
+		if err := proto.Unmarshal(out[PacketHeadSize:], msg); err != nil {
+			b.Fatal(err)
+		}
+		compressed := msg.Data
+		b.SetBytes(int64(len(data)))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			msg.Data, msg.Compression = compressed, compressSnappy
+			if err := decompressData(msg, len(data)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkCompressor_Broadcast compresses the packet broadcast to 20 peers
+func BenchmarkCompressor_Broadcast(b *testing.B) {
+	benchmarkBlockSizes(b, func(b *testing.B, data []byte) {
+		packet := genDataPacket4Test(data)
+		b.SetBytes(int64(len(packet)))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			c := newCompressor(true)
+			for j := 0; j < 20; j++ {
+				c.compress(packet, CapCompression)
+			}
+		}
+	})
+}
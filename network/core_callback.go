@@ -1,3 +1,5 @@
+// +build cgo,!gotcp
+
 //   Copyright (C) 2018 ZVChain
 //
 //   This program is free software: you can redistribute it and/or modify
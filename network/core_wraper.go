@@ -1,4 +1,5 @@
 // +build linux darwin
+// +build cgo,!gotcp
 
 //   Copyright (C) 2018 ZVChain
 //
@@ -124,32 +125,42 @@ import (
 	"unsafe"
 )
 
-func P2PConfig(id uint64) {
+// coreTransport is the transport implemented by the prebuilt libp2pcore.
+// The session events are reported to the global netCore through the exported callbacks
+type coreTransport struct{}
+
+const coreTransportAvailable = true
+
+func newCoreTransport() transport {
+	return &coreTransport{}
+}
+
+func (t *coreTransport) config(id uint64, handler transportHandler) {
 	C.wrap_p2p_config(C.uint64_t(id))
 	C.wrap_p2p_send_callback()
 }
 
-func P2PProxy(ip string, port uint16) {
+func (t *coreTransport) proxy(ip string, port uint16) {
 	C.p2p_proxy(C.CString(ip), C.ushort(port))
 }
 
-func P2PListen(ip string, port uint16) {
+func (t *coreTransport) listen(ip string, port uint16) {
 	C.p2p_listen(C.CString(ip), C.ushort(port))
 }
 
-func P2PClose() {
+func (t *coreTransport) close() {
 	C.p2p_close()
 }
 
-func P2PConnect(id uint64, ip string, port uint16) {
+func (t *coreTransport) connect(id uint64, ip string, port uint16) {
 	C.p2p_connect(C.uint64_t(id), C.CString(ip), C.ushort(port))
 }
 
-func P2PShutdown(session uint32) {
+func (t *coreTransport) shutdown(session uint32) {
 	C.p2p_shutdown(C.uint(session))
 }
 
-func P2PSend(session uint32, data []byte) {
+func (t *coreTransport) send(session uint32, data []byte) {
 
 	const maxSize = 64 * 1024
 	totalLen := len(data)
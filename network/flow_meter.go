@@ -16,7 +16,11 @@
 package network
 
 import (
+	"math"
+	"strconv"
 	"sync"
+
+	"github.com/darren0718/zvchain/middleware/metrics"
 )
 
 type FlowMeterItem struct {
@@ -39,14 +43,19 @@ type FlowMeter struct {
 	sendSize  int64
 	recvItems map[int64]*FlowMeterItem
 	recvSize  int64
-	mutex     sync.RWMutex
+	// Inbound messages dropped for exceeding the limits, key is the code
+	rateViolations map[int64]int64
+	sizeViolations map[int64]int64
+	mutex          sync.RWMutex
 }
 
 func newFlowMeter(name string) *FlowMeter {
 
 	return &FlowMeter{name: name,
-		sendItems: make(map[int64]*FlowMeterItem),
-		recvItems: make(map[int64]*FlowMeterItem)}
+		sendItems:      make(map[int64]*FlowMeterItem),
+		recvItems:      make(map[int64]*FlowMeterItem),
+		rateViolations: make(map[int64]int64),
+		sizeViolations: make(map[int64]int64)}
 
 }
 
@@ -61,6 +70,7 @@ func (fm *FlowMeter) send(code int64, size int64) {
 	item.count++
 	item.size += size
 	fm.sendSize += size
+	countMessage("out", code, size)
 }
 
 func (fm *FlowMeter) recv(code int64, size int64) {
@@ -74,6 +84,40 @@ func (fm *FlowMeter) recv(code int64, size int64) {
 	item.count++
 	item.size += size
 	fm.recvSize += size
+	countMessage("in", code, size)
+}
+
+// countMessage adds the message to the metrics, which are never reset unlike the meter
+func countMessage(direction string, code int64, size int64) {
+	label := messageCodeLabel(code)
+	metrics.P2PMessages.WithLabelValues(direction, label).Inc()
+	metrics.P2PBytes.WithLabelValues(direction, label).Add(float64(size))
+}
+
+// messageCodeLabel returns the metrics label of the code. Only the known codes are labeled by the value,
+// so that the label cardinality can't be blown up by the codes from peers
+func messageCodeLabel(code int64) string {
+	switch {
+	case code > P2PMessageCodeBase && code <= P2PMessageCodeBase+int64(MessageType_MessageData):
+	case code >= 0 && code <= math.MaxUint32 && isKnownMessageCode(uint32(code)):
+	default:
+		return "other"
+	}
+	return strconv.FormatInt(code, 10)
+}
+
+// rateViolate counts the message dropped for exceeding the rate limit
+func (fm *FlowMeter) rateViolate(code int64) {
+	fm.mutex.Lock()
+	defer fm.mutex.Unlock()
+	fm.rateViolations[code]++
+}
+
+// sizeViolate counts the message dropped for exceeding the size limit
+func (fm *FlowMeter) sizeViolate(code int64) {
+	fm.mutex.Lock()
+	defer fm.mutex.Unlock()
+	fm.sizeViolations[code]++
 }
 
 func (fm *FlowMeter) reset() {
@@ -81,6 +125,8 @@ func (fm *FlowMeter) reset() {
 	defer fm.mutex.Unlock()
 	fm.sendItems = make(map[int64]*FlowMeterItem)
 	fm.recvItems = make(map[int64]*FlowMeterItem)
+	fm.rateViolations = make(map[int64]int64)
+	fm.sizeViolations = make(map[int64]int64)
 
 	fm.sendSize = 0
 	fm.recvSize = 0
@@ -104,4 +150,11 @@ func (fm *FlowMeter) print() {
 			Logger.Infof("[FlowMeter][%v_recv] code:%v  count:%v  size:%v percentage：%v%%", fm.name, item.code, item.count, item.size, float64(item.size)/float64(fm.recvSize)*100.0)
 		}
 	}
+
+	for code, count := range fm.rateViolations {
+		Logger.Infof("[FlowMeter][%v_recv] code:%v  rate limit violations:%v", fm.name, code, count)
+	}
+	for code, count := range fm.sizeViolations {
+		Logger.Infof("[FlowMeter][%v_recv] code:%v  size limit violations:%v", fm.name, code, count)
+	}
 }
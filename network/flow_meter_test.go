@@ -0,0 +1,32 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import "testing"
+
+func TestMessageCodeLabel(t *testing.T) {
+	if l := messageCodeLabel(int64(TxSyncNotify)); l != "10010" {
+		t.Fatalf("known code label error %v", l)
+	}
+	if l := messageCodeLabel(P2PMessageCodeBase + int64(MessageType_MessagePing)); l != "10001" {
+		t.Fatalf("p2p code label error %v", l)
+	}
+	for _, code := range []int64{-1, 0, 9000, 20000, 1 << 40} {
+		if l := messageCodeLabel(code); l != "other" {
+			t.Fatalf("unknown code %v should be labeled other, got %v", code, l)
+		}
+	}
+}
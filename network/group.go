@@ -196,7 +196,7 @@ func (g *Group) onRemove() {
 			continue
 		}
 		p.removeGroup(g.ID)
-		if p.isGroupEmpty() {
+		if p.isGroupEmpty() && !netCore.peerManager.isStatic(genNetID(ID)) {
 			node := netCore.kad.find(ID)
 			if node == nil {
 				Logger.Infof("[group]group on remove, member ID: %v", ID)
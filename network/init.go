@@ -16,12 +16,15 @@
 // Package network module implements p2p network, It uses a Kademlia-like protocol to maintain and discover Nodes.
 // network transfer protocol use  KCP, a open source RUDP implementation,it provide NAT Traversal ability,let nodes
 // under NAT can be connecting with other.
+// A pure-Go TCP transport is also provided, which is selected by the p2p.transport config or built in alone with
+// the gotcp tag.
 package network
 
 import (
 	"math"
 	"math/rand"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/darren0718/zvchain/log"
@@ -44,12 +47,33 @@ type NetworkConfig struct {
 	SeedIDs         []string
 	PK              string
 	SK              string
+	Transport       string   // TransportCore or TransportTCP, read from the config if empty
+	BanListFile     string   // File persisting the ban list, read from the config if empty
+	StaticNodes     []string // Nodes always kept connected in the form of id@ip:port, read from the config if nil
+	NodeDBPath      string   // Database persisting the known-good nodes, read from the config if empty
+	// Boot nodes in the form of id@host:port, the host may be a domain name. Read from the config if nil
+	BootNodes []string
+	// Disable the compression of the data messages, also disabled if set false in the config
+	DisableCompression bool
+	// Lowest protocol version still supported, ProtocolVersion if 0
+	MinProtocolVersion uint16
+	// Capabilities advertised besides the compression, see CapHeaderSync
+	Capabilities uint32
 }
 
 const (
 	configMaxBroadcastCount = "max_broadcast_count"
 	maxBroadcastCount       = 256
+	configTransport         = "transport"
+	configBanListFile       = "ban_list"
+	configStaticNodes       = "static_nodes"
+	configCompression       = "compression"
+	defaultBanListFile      = "ban_list.json"
+	configNodeDB            = "node_db"
+	defaultNodeDB           = "d_nodes"
 	configSection           = "p2p"
+	configBootNodes         = "bootnodes"
+	configBootNodesSection  = "network"
 )
 
 var netServerInstance *Server
@@ -123,6 +147,20 @@ func Init(config *common.ConfManager, consensusHandler MsgHandler, networkConfig
 			}
 		}
 	}
+	if networkConfig.BootNodes == nil && common.GlobalConf != nil {
+		networkConfig.BootNodes = splitConfigList(common.GlobalConf.GetString(configBootNodesSection, configBootNodes, ""))
+	}
+	for _, s := range networkConfig.BootNodes {
+		n, err := ParseBootNode(s)
+		if err != nil {
+			Logger.Errorf("boot node %v error:%v", s, err)
+			continue
+		}
+		if n.ID != self.ID && !containsNode(seeds, n.ID) {
+			seeds = append(seeds, n)
+		}
+	}
+
 	natIP := ""
 	if len(networkConfig.NatAddr) > 0 {
 		IP, err := getIPByAddress(networkConfig.NatAddr)
@@ -133,6 +171,44 @@ func Init(config *common.ConfManager, consensusHandler MsgHandler, networkConfig
 		natIP = IP.String()
 	}
 
+	if networkConfig.Transport == "" {
+		networkConfig.Transport = TransportCore
+		if common.GlobalConf != nil {
+			networkConfig.Transport = common.GlobalConf.GetString(configSection, configTransport, TransportCore)
+		}
+	}
+
+	if networkConfig.BanListFile == "" {
+		networkConfig.BanListFile = defaultBanListFile
+		if common.GlobalConf != nil {
+			networkConfig.BanListFile = common.GlobalConf.GetString(configSection, configBanListFile, defaultBanListFile)
+		}
+	}
+
+	if networkConfig.NodeDBPath == "" {
+		networkConfig.NodeDBPath = defaultNodeDB
+		if common.GlobalConf != nil {
+			networkConfig.NodeDBPath = common.GlobalConf.GetString(configSection, configNodeDB, defaultNodeDB)
+		}
+	}
+
+	if networkConfig.StaticNodes == nil && common.GlobalConf != nil {
+		networkConfig.StaticNodes = splitConfigList(common.GlobalConf.GetString(configSection, configStaticNodes, ""))
+	}
+	if !networkConfig.DisableCompression && common.GlobalConf != nil {
+		networkConfig.DisableCompression = !common.GlobalConf.GetBool(configSection, configCompression, true)
+	}
+
+	staticNodes := make([]*Node, 0, len(networkConfig.StaticNodes))
+	for _, s := range networkConfig.StaticNodes {
+		n, err := ParseStaticNode(s)
+		if err != nil {
+			Logger.Errorf("static node %v error:%v", s, err)
+			return err
+		}
+		staticNodes = append(staticNodes, n)
+	}
+
 	netConfig := NetCoreConfig{ID: self.ID,
 		ListenAddr:         &listenAddr,
 		Seeds:              seeds,
@@ -140,7 +216,15 @@ func Init(config *common.ConfManager, consensusHandler MsgHandler, networkConfig
 		NatIP:              natIP,
 		NatPort:            networkConfig.NatPort,
 		ChainID:            networkConfig.ChainID,
-		ProtocolVersion:    networkConfig.ProtocolVersion}
+		ProtocolVersion:    networkConfig.ProtocolVersion,
+		Transport:          networkConfig.Transport,
+		BanListFile:        networkConfig.BanListFile,
+		NodeDBPath:         networkConfig.NodeDBPath,
+		StaticNodes:        staticNodes,
+		DisableCompression: networkConfig.DisableCompression,
+		MinProtocolVersion: networkConfig.MinProtocolVersion,
+		Capabilities:       networkConfig.Capabilities,
+		Key:                common.HexToSecKey(networkConfig.SK)}
 
 	var netCore NetCore
 	n, _ := netCore.InitNetCore(netConfig)
@@ -159,6 +243,26 @@ func Init(config *common.ConfManager, consensusHandler MsgHandler, networkConfig
 	return nil
 }
 
+// splitConfigList splits the comma separated list in the config
+func splitConfigList(s string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		if strings.TrimSpace(item) != "" {
+			list = append(list, strings.TrimSpace(item))
+		}
+	}
+	return list
+}
+
+func containsNode(nodes []*Node, id NodeID) bool {
+	for _, n := range nodes {
+		if n.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
 func genRandomSeeds(seeds []string) []string {
 	nodesSelect := make(map[int]bool)
 
@@ -49,8 +49,44 @@ const (
 	TxSyncNotify   uint32 = 10010
 	TxSyncReq      uint32 = 10011
 	TxSyncResponse uint32 = 10012
+
+	//The following four messages are used for header-first block sync, only sent to the peers of CapHeaderSync
+	ReqBlockHeaders      uint32 = 10015
+	BlockHeadersResponse uint32 = 10016
+	ReqBlockBodies       uint32 = 10017
+	BlockBodiesResponse  uint32 = 10018
 )
 
+// knownMessageCodes contains all the message codes defined above
+var knownMessageCodes = map[uint32]struct{}{
+	CastVerifyMsg:            {},
+	VerifiedCastMsg:          {},
+	CastRewardSignReq:        {},
+	CastRewardSignGot:        {},
+	ReqProposalBlock:         {},
+	ResponseProposalBlock:    {},
+	BlockInfoNotifyMsg:       {},
+	ReqBlock:                 {},
+	BlockResponseMsg:         {},
+	NewBlockMsg:              {},
+	ForkFindAncestorResponse: {},
+	ForkFindAncestorReq:      {},
+	ForkChainSliceReq:        {},
+	ForkChainSliceResponse:   {},
+	TxSyncNotify:             {},
+	TxSyncReq:                {},
+	TxSyncResponse:           {},
+	ReqBlockHeaders:          {},
+	BlockHeadersResponse:     {},
+	ReqBlockBodies:           {},
+	BlockBodiesResponse:      {},
+}
+
+func isKnownMessageCode(code uint32) bool {
+	_, ok := knownMessageCodes[code]
+	return ok
+}
+
 type Message struct {
 	ChainID uint16
 
@@ -58,6 +58,8 @@ type Kad struct {
 	mutex   sync.Mutex        // Protected members: buckets, bucket content, nursery, rand
 	buckets [nBuckets]*bucket // Index of nodes sorted by node distance
 	seeds   []*Node           // Start node list
+	static  map[NodeID]*Node  // Static nodes never replaced in the buckets
+	db      *nodeDB           // Known-good nodes persisted, nil if not persisted
 	rand    *mrand.Rand       // Random number generator
 
 	refreshReq chan chan struct{}
@@ -82,7 +84,7 @@ type bucket struct {
 	replacements []*Node // Standby supplementary node
 }
 
-func newKad(t NetInterface, ourID NodeID, ourAddr *nnet.UDPAddr, seeds []*Node) (*Kad, error) {
+func newKad(t NetInterface, ourID NodeID, ourAddr *nnet.UDPAddr, seeds []*Node, db *nodeDB) (*Kad, error) {
 	kad := &Kad{
 		net:        t,
 		self:       NewNode(ourID, ourAddr.IP, ourAddr.Port),
@@ -91,6 +93,8 @@ func newKad(t NetInterface, ourID NodeID, ourAddr *nnet.UDPAddr, seeds []*Node)
 		closeReq:   make(chan struct{}),
 		closed:     make(chan struct{}),
 		rand:       mrand.New(mrand.NewSource(0)),
+		static:     make(map[NodeID]*Node),
+		db:         db,
 	}
 	if err := kad.setFallbackNodes(seeds); err != nil {
 		return nil, err
@@ -99,6 +103,7 @@ func newKad(t NetInterface, ourID NodeID, ourAddr *nnet.UDPAddr, seeds []*Node)
 		kad.buckets[i] = &bucket{}
 	}
 	kad.seedRand()
+	kad.db.expire()
 	kad.loadSeedNodes(false)
 	go kad.loop()
 	return kad, nil
@@ -287,11 +292,13 @@ loop:
 	for _, ch := range waiting {
 		close(ch)
 	}
+	kad.db.close()
 	close(kad.closed)
 }
 
 func (kad *Kad) doRefresh(done chan struct{}) {
 	defer close(done)
+	kad.db.expire()
 	kad.loadSeedNodes(true)
 
 	kad.lookup(kad.self.ID, false)
@@ -314,15 +321,73 @@ func (kad *Kad) doCheck() {
 	}
 }
 
+// loadSeedNodes adds the seeds and the nodes persisted to the buckets, so that the
+// node can join the network even if the seeds are down
 func (kad *Kad) loadSeedNodes(bond bool) {
+	nodes := append([]*Node{}, kad.seeds...)
+	for _, n := range kad.db.nodes(nodeDBLoadCount) {
+		if n.ID != kad.self.ID {
+			nodes = append(nodes, n)
+		}
+	}
 
 	if bond {
-		kad.pingAll(kad.seeds)
+		kad.pingAll(nodes)
+	}
+
+	for i := range nodes {
+		kad.add(nodes[i])
+	}
+	kad.loadStaticNodes()
+}
+
+// addStatic adds the static node to the buckets, which won't be replaced by other nodes
+func (kad *Kad) addStatic(n *Node) {
+	kad.mutex.Lock()
+	defer kad.mutex.Unlock()
+	kad.static[n.ID] = n
+	kad.addStaticLocked(n)
+}
+
+func (kad *Kad) removeStatic(id NodeID) {
+	kad.mutex.Lock()
+	defer kad.mutex.Unlock()
+	delete(kad.static, id)
+}
+
+func (kad *Kad) isStatic(id NodeID) bool {
+	kad.mutex.Lock()
+	defer kad.mutex.Unlock()
+	_, ok := kad.static[id]
+	return ok
+}
+
+// loadStaticNodes adds the static nodes again in case they are missing from the buckets
+func (kad *Kad) loadStaticNodes() {
+	kad.mutex.Lock()
+	defer kad.mutex.Unlock()
+	for _, n := range kad.static {
+		kad.addStaticLocked(n)
 	}
+}
 
-	for i := range kad.seeds {
-		kad.add(kad.seeds[i])
+// addStaticLocked adds the node to the bucket. The last non-static entry is moved
+// to the replacements if the bucket is full
+func (kad *Kad) addStaticLocked(n *Node) {
+	b := kad.bucket(n.sha)
+	if kad.bumpOrAdd(b, n) {
+		return
 	}
+	for i := len(b.entries) - 1; i >= 0; i-- {
+		if _, ok := kad.static[b.entries[i].ID]; !ok {
+			kad.addReplacement(b, b.entries[i])
+			copy(b.entries[1:i+1], b.entries[:i])
+			b.entries[0] = n
+			n.addedAt = time.Now()
+			return
+		}
+	}
+	Logger.Warnf("[kad] bucket full of static nodes, static node %v not added", n.ID.GetHexString())
 }
 
 func (kad *Kad) closest(target []byte, nresults int) *nodesByDistance {
@@ -398,9 +463,15 @@ func (kad *Kad) onPingNode(id NodeID, addr *nnet.UDPAddr) (*Node, error) {
 
 	}
 	node.pinged = true
+	kad.db.seen(node)
 	return node, nil
 }
 
+// onNodeFailed counts the failure of connecting to the node in the database
+func (kad *Kad) onNodeFailed(id NodeID) {
+	kad.db.failed(id)
+}
+
 func (kad *Kad) bucket(sha []byte) *bucket {
 	d := logDistance(kad.self.sha, sha)
 	if d <= bucketMinDistance {
@@ -26,6 +26,8 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/middleware/metrics"
 	"github.com/darren0718/zvchain/middleware/statistics"
 	zvTime "github.com/darren0718/zvchain/middleware/time"
 	"github.com/gogo/protobuf/proto"
@@ -48,6 +50,9 @@ var (
 	errBadPacket        = errors.New("bad Packet")
 	errUnknownMsg       = errors.New("unknown msg")
 	errBadPeer          = errors.New("bad Peer")
+	errBannedPeer       = errors.New("banned Peer")
+	errMessageTooLarge  = errors.New("message too large")
+	errRateLimited      = errors.New("rate limited")
 	errExpired          = errors.New("expired")
 	errUnsolicitedReply = errors.New("unsolicited reply")
 	//errGroupEmpty       = errors.New("group empty")
@@ -86,8 +91,13 @@ type NetCore struct {
 	flowMeter       *FlowMeter
 	bufferPool      *BufferPool
 	proposerManager *ProposerManager
+	scorer          *peerScorer
+	rateLimiter     *rateLimiter
+	compressor      *compressor
 	chainID         uint16 // Chain ID
 	protocolVersion uint16 // Protocol ID
+	versions        versionRange
+	capabilities    uint32 // Capabilities advertised to the peers
 }
 
 type pending struct {
@@ -142,6 +152,18 @@ type NetCoreConfig struct {
 	NatIP           string
 	ChainID         uint16
 	ProtocolVersion uint16
+	Transport       string // TransportCore or TransportTCP
+	BanListFile     string // File persisting the ban list, not persisted if empty
+	NodeDBPath      string // Database persisting the known-good nodes, not persisted if empty
+	StaticNodes     []*Node
+	// Don't compress the data messages and don't advertise it to the peers
+	DisableCompression bool
+	// Lowest protocol version still supported, ProtocolVersion if 0
+	MinProtocolVersion uint16
+	// Capabilities advertised besides the compression
+	Capabilities uint32
+	// Key of the node authenticating it to the peers in the TCP transport
+	Key *common.PrivateKey
 }
 
 // MakeEndPoint create the node description object
@@ -177,6 +199,17 @@ func (nc *NetCore) InitNetCore(cfg NetCoreConfig) (*NetCore, error) {
 	nc.proposerManager = newProposerManager()
 	nc.flowMeter = newFlowMeter("p2p")
 	nc.bufferPool = newBufferPool()
+	nc.scorer = newPeerScorer(cfg.BanListFile, nc.peerManager.disconnectUntrusted)
+	nc.rateLimiter = newRateLimiter()
+	nc.compressor = newCompressor(!cfg.DisableCompression)
+	nc.versions = newVersionRange(cfg.MinProtocolVersion, cfg.ProtocolVersion)
+	nc.capabilities = cfg.Capabilities&advertisableCapabilities | nc.compressor.capabilities()
+	metrics.RegisterGauge("p2p", "peers", "Connected and authenticated peers", func() float64 {
+		return float64(len(nc.peerManager.ConnInfo()))
+	})
+	for _, n := range cfg.StaticNodes {
+		nc.peerManager.addStatic(n)
+	}
 	realAddr := cfg.ListenAddr
 
 	Logger.Infof("kad ID: %v ", nc.ID.GetHexString())
@@ -185,6 +218,18 @@ func (nc *NetCore) InitNetCore(cfg NetCoreConfig) (*NetCore, error) {
 	Logger.Infof("P2PConfig: %v ", nc.netID)
 	Logger.Infof("local addr: %v %v", realAddr.IP.String(), uint16(realAddr.Port))
 	nc.ourEndPoint = MakeEndPoint(realAddr, int32(realAddr.Port))
+
+	p2pTransport = newTransport(cfg.Transport)
+	if t, ok := p2pTransport.(*tcpTransport); ok {
+		t.setKey(cfg.Key)
+		if cfg.NatTraversalEnable {
+			Logger.Warnf("tcp transport doesn't support nat traversal, listen directly")
+			cfg.NatTraversalEnable = false
+			nc.peerManager.natTraversalEnable = false
+		}
+	}
+	// The transport may report events once listening
+	netCore = nc
 	P2PConfig(nc.netID)
 
 	if cfg.NatTraversalEnable {
@@ -195,12 +240,24 @@ func (nc *NetCore) InitNetCore(cfg NetCoreConfig) (*NetCore, error) {
 		P2PListen(realAddr.IP.String(), uint16(realAddr.Port))
 	}
 
-	kad, err := newKad(nc, cfg.ID, realAddr, cfg.Seeds)
+	var ndb *nodeDB
+	if cfg.NodeDBPath != "" {
+		db, err := newNodeDB(cfg.NodeDBPath)
+		if err != nil {
+			Logger.Errorf("open node database %v error:%v", cfg.NodeDBPath, err)
+		} else {
+			ndb = db
+		}
+	}
+	kad, err := newKad(nc, cfg.ID, realAddr, cfg.Seeds, ndb)
 	if err != nil {
+		ndb.close()
 		return nil, err
 	}
 	nc.kad = kad
-	netCore = nc
+	for _, n := range cfg.StaticNodes {
+		kad.addStatic(n)
+	}
 	go nc.loop()
 	go nc.decodeLoop()
 
@@ -232,11 +289,14 @@ func (nc *NetCore) ping(toID NodeID, toAddr *net.UDPAddr) {
 		to = MakeEndPoint(toAddr, 0)
 	}
 	req := &MsgPing{
-		Version:    Version,
-		From:       &nc.ourEndPoint,
-		To:         &to,
-		ChainID:    uint32(nc.chainID),
-		Expiration: nc.expirationTime(),
+		Version:            Version,
+		From:               &nc.ourEndPoint,
+		To:                 &to,
+		ChainID:            uint32(nc.chainID),
+		Expiration:         nc.expirationTime(),
+		Capabilities:       nc.capabilities,
+		ProtocolVersionMin: uint32(nc.versions.min),
+		ProtocolVersionMax: uint32(nc.versions.max),
 	}
 	if p != nil && !p.isAuthSucceed {
 		authContext := p.AuthContext()
@@ -401,6 +461,7 @@ func (nc *NetCore) loop() {
 			nc.messageManager.clear()
 		case <-peerCheck.C:
 			nc.peerManager.checkPeers()
+			nc.peerManager.checkStatic()
 		case <-flowMeter.C:
 			nc.flowMeter.print()
 			nc.flowMeter.reset()
@@ -639,6 +700,17 @@ func (nc *NetCore) decodeMessage(p *Peer) (MessageType, int, proto.Message, *byt
 		return msgType, packetSize, nil, packetBuffer, err
 	}
 
+	// Drop the oversize message before decoding it
+	if code, ok := checkMessageSize(msgType, data); !ok {
+		Logger.Infof("message oversize from %v, type:%v, code:%v, size:%v", p.ID.GetHexString(), msgType, code, len(data))
+		if msgType != MessageType_MessageData {
+			code = P2PMessageCodeBase + uint32(msgType)
+		}
+		nc.flowMeter.sizeViolate(int64(code))
+		nc.scorer.report(p.ID.GetHexString(), PeerEventOversize)
+		return msgType, packetSize, nil, packetBuffer, errMessageTooLarge
+	}
+
 	var req proto.Message
 	switch msgType {
 	case MessageType_MessagePing:
@@ -677,6 +749,9 @@ func (nc *NetCore) handlePing(req *MsgPing, p *Peer) error {
 		p.Port = port
 	}
 	p.chainID = uint16(req.ChainID)
+	if err := nc.handleHandshake(p, req.ProtocolVersionMin, req.ProtocolVersionMax, req.Capabilities); err != nil {
+		return err
+	}
 
 	from := net.UDPAddr{IP: net.ParseIP(req.From.IP), Port: int(req.From.Port)}
 
@@ -691,8 +766,14 @@ func (nc *NetCore) handlePing(req *MsgPing, p *Peer) error {
 		pac := &PeerAuthContext{PK: req.PK, Sign: req.Sign, CurTime: req.CurTime}
 		p.verify(pac)
 	}
+	// Node id of the accepted peer is known after verified
+	if p.ID.IsValid() && nc.peerManager.isBanned(genNetID(p.ID)) {
+		Logger.Infof("ping from banned peer %v, disconnect", p.ID.GetHexString())
+		nc.peerManager.disconnect(p.ID)
+		return errBannedPeer
+	}
 
-	pongMsg := MsgPong{Version: 0, VerifyResult: p.verifyResult}
+	pongMsg := nc.pong(p)
 
 	nc.sendMessageToNode(p.ID, nil, MessageType_MessagePong, &pongMsg, P2PMessageCodeBase+uint32(MessageType_MessagePong))
 
@@ -705,8 +786,22 @@ func (nc *NetCore) handlePing(req *MsgPing, p *Peer) error {
 	return nil
 }
 
+// pong returns the pong replying the ping of the peer
+func (nc *NetCore) pong(p *Peer) MsgPong {
+	return MsgPong{
+		Version:            0,
+		VerifyResult:       p.verifyResult,
+		Capabilities:       nc.capabilities,
+		ProtocolVersionMin: uint32(nc.versions.min),
+		ProtocolVersionMax: uint32(nc.versions.max),
+	}
+}
+
 func (nc *NetCore) handlePong(req *MsgPong, p *Peer) error {
 
+	if err := nc.handleHandshake(p, req.ProtocolVersionMin, req.ProtocolVersionMax, req.Capabilities); err != nil {
+		return err
+	}
 	p.setRemoteVerifyResult(req.VerifyResult)
 	Logger.Debugf("Pong from:%v, VerifyResult:%v, RemoteVerifyResult:%v,isAuthSucceed:%v",
 		p.ID.GetHexString(), p.verifyResult, p.remoteVerifyResult, p.isAuthSucceed)
@@ -758,6 +853,23 @@ func (nc *NetCore) handleData(req *MsgData, packet []byte, p *Peer) error {
 		Logger.Infof("message expired!")
 		return errExpired
 	}
+	if !nc.rateLimiter.allow(p.ID, req.MessageCode) {
+		Logger.Debugf("message rate limited from %v, code:%v", p.ID.GetHexString(), req.MessageCode)
+		nc.flowMeter.rateViolate(int64(req.MessageCode))
+		nc.scorer.report(p.ID.GetHexString(), PeerEventRateLimited)
+		return errRateLimited
+	}
+	compressed := req.Compression != compressNone
+	if err := decompressData(req, messageLimitOf(req.MessageCode).maxSize); err != nil {
+		Logger.Infof("decompress message from %v error, code:%v, err:%v", p.ID.GetHexString(), req.MessageCode, err)
+		if err == errMessageTooLarge {
+			nc.flowMeter.sizeViolate(int64(req.MessageCode))
+			nc.scorer.report(p.ID.GetHexString(), PeerEventOversize)
+		} else {
+			nc.scorer.report(p.ID.GetHexString(), PeerEventBadMessage)
+		}
+		return err
+	}
 	srcNodeID := NodeID{}
 	srcNodeID.SetBytes(req.SrcNodeID)
 
@@ -811,8 +923,12 @@ func (nc *NetCore) handleData(req *MsgData, packet []byte, p *Peer) error {
 	}
 	if broadcast {
 		var dataBuffer *bytes.Buffer
-		if req.RelayCount > 0 {
-			req.RelayCount = req.RelayCount - 1
+		if req.RelayCount > 0 || compressed {
+			// The packet received compressed is encoded again with the data decompressed,
+			// it's compressed on sending to the peers accepting compression
+			if req.RelayCount > 0 {
+				req.RelayCount = req.RelayCount - 1
+			}
 			dataBuffer, _, _ = nc.encodePacket(MessageType_MessageData, req)
 		} else {
 			dataBuffer = nc.bufferPool.getBuffer(len(packet))
@@ -0,0 +1,189 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"encoding/json"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/darren0718/zvchain/storage/tasdb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+const (
+	nodeDBExpiration     = 24 * time.Hour  // Nodes not seen for the duration are removed
+	nodeDBUpdateInterval = 5 * time.Minute // Interval of updating the last seen time of a node
+	nodeDBMaxFails       = 5               // Nodes failed for the times in a row are removed
+	nodeDBLoadCount      = 64              // Max nodes loaded into the buckets on start
+)
+
+var nodeDBPrefix = []byte("n:")
+
+// nodeRecord is a known-good node persisted in the node database
+type nodeRecord struct {
+	ID       string    `json:"id"`
+	IP       string    `json:"ip"`
+	Port     int       `json:"port"`
+	LastSeen time.Time `json:"last_seen"`
+	Fails    int       `json:"fails"` // Times failed to connect in a row since last seen
+}
+
+// nodeDB persists the nodes found alive, which are reloaded into the kad buckets on restart
+// so that the node won't depend on the seeds only. A nil nodeDB persists nothing
+type nodeDB struct {
+	db    *tasdb.LDBDatabase
+	mutex sync.Mutex
+	now   func() time.Time
+}
+
+func newNodeDB(path string) (*nodeDB, error) {
+	options := &opt.Options{
+		OpenFilesCacheCapacity: 8,
+		WriteBuffer:            opt.MiB,
+		BlockCacheCapacity:     opt.MiB,
+	}
+	db, err := tasdb.NewLDBDatabase(path, options)
+	if err != nil {
+		return nil, err
+	}
+	return &nodeDB{db: db, now: time.Now}, nil
+}
+
+func nodeDBKey(id NodeID) []byte {
+	return append(append([]byte{}, nodeDBPrefix...), id[:]...)
+}
+
+func (ndb *nodeDB) get(id NodeID) *nodeRecord {
+	data, err := ndb.db.Get(nodeDBKey(id))
+	if err != nil || data == nil {
+		return nil
+	}
+	r := new(nodeRecord)
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil
+	}
+	return r
+}
+
+func (ndb *nodeDB) put(id NodeID, r *nodeRecord) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	if err := ndb.db.Put(nodeDBKey(id), data); err != nil {
+		Logger.Errorf("[kad] save node %v error:%v", r.ID, err)
+	}
+}
+
+// seen records the node alive. The record isn't rewritten unless the address changed, it
+// failed before or the last update is older than nodeDBUpdateInterval
+func (ndb *nodeDB) seen(n *Node) {
+	if ndb == nil || n.Incomplete() || n.Port == 0 {
+		return
+	}
+	ndb.mutex.Lock()
+	defer ndb.mutex.Unlock()
+	now := ndb.now()
+	r := ndb.get(n.ID)
+	if r != nil && r.IP == n.IP.String() && r.Port == n.Port && r.Fails == 0 && now.Sub(r.LastSeen) < nodeDBUpdateInterval {
+		return
+	}
+	ndb.put(n.ID, &nodeRecord{ID: n.ID.GetHexString(), IP: n.IP.String(), Port: n.Port, LastSeen: now})
+}
+
+// failed counts the failure of the node, which is removed after failed nodeDBMaxFails times
+func (ndb *nodeDB) failed(id NodeID) {
+	if ndb == nil {
+		return
+	}
+	ndb.mutex.Lock()
+	defer ndb.mutex.Unlock()
+	r := ndb.get(id)
+	if r == nil {
+		return
+	}
+	r.Fails++
+	if r.Fails >= nodeDBMaxFails {
+		ndb.db.Delete(nodeDBKey(id))
+		return
+	}
+	ndb.put(id, r)
+}
+
+// expire removes the nodes not seen for nodeDBExpiration and the nodes failed too many times
+func (ndb *nodeDB) expire() {
+	if ndb == nil {
+		return
+	}
+	ndb.mutex.Lock()
+	defer ndb.mutex.Unlock()
+	now := ndb.now()
+	iter := ndb.db.NewIteratorWithPrefix(nodeDBPrefix)
+	defer iter.Release()
+	for iter.Next() {
+		r := new(nodeRecord)
+		if err := json.Unmarshal(iter.Value(), r); err != nil || now.Sub(r.LastSeen) > nodeDBExpiration || r.Fails >= nodeDBMaxFails {
+			ndb.db.Delete(append([]byte{}, iter.Key()...))
+		}
+	}
+}
+
+// nodes returns at most max nodes persisted, the latest seen first
+func (ndb *nodeDB) nodes(max int) []*Node {
+	if ndb == nil {
+		return nil
+	}
+	ndb.mutex.Lock()
+	records := make([]*nodeRecord, 0)
+	iter := ndb.db.NewIteratorWithPrefix(nodeDBPrefix)
+	for iter.Next() {
+		r := new(nodeRecord)
+		if err := json.Unmarshal(iter.Value(), r); err == nil {
+			records = append(records, r)
+		}
+	}
+	iter.Release()
+	ndb.mutex.Unlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].LastSeen.After(records[j].LastSeen)
+	})
+	nodes := make([]*Node, 0, max)
+	for _, r := range records {
+		if len(nodes) >= max {
+			break
+		}
+		id := NewNodeID(r.ID)
+		ip := net.ParseIP(r.IP)
+		if id == nil || ip == nil {
+			continue
+		}
+		n := NewNode(*id, ip, r.Port)
+		if n.validateComplete() == nil {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+func (ndb *nodeDB) close() {
+	if ndb != nil {
+		ndb.db.Close()
+	}
+}
@@ -0,0 +1,163 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/darren0718/zvchain/log"
+)
+
+func newNodeDB4Test(t *testing.T) (*nodeDB, string) {
+	if Logger == nil {
+		Logger = log.P2PLogger
+	}
+	dir, err := ioutil.TempDir("", "node_db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "d_nodes")
+	ndb, err := newNodeDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ndb, dir
+}
+
+func nodes4Test(count int) []*Node {
+	nodes := make([]*Node, 0, count)
+	for i := 0; i < count; i++ {
+		nodes = append(nodes, NewNode(*NewNodeID(fmt.Sprintf("zvee%04x", i)), net.ParseIP("10.0.0.1"), 1122+i))
+	}
+	return nodes
+}
+
+func TestNodeDB_SeenAndReload(t *testing.T) {
+	ndb, dir := newNodeDB4Test(t)
+	defer os.RemoveAll(dir)
+
+	clock := time.Now()
+	ndb.now = func() time.Time { return clock }
+	nodes := nodes4Test(3)
+	for _, n := range nodes {
+		ndb.seen(n)
+		clock = clock.Add(time.Minute)
+	}
+	// Incomplete node not saved
+	ndb.seen(&Node{})
+	ndb.close()
+
+	ndb, err := newNodeDB(filepath.Join(dir, "d_nodes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ndb.close()
+	loaded := ndb.nodes(2)
+	if len(loaded) != 2 || loaded[0].ID != nodes[2].ID || loaded[1].ID != nodes[1].ID {
+		t.Fatalf("nodes should be loaded latest seen first, got %v", loaded)
+	}
+	if loaded[0].Port != nodes[2].Port || !loaded[0].IP.Equal(nodes[2].IP) {
+		t.Fatalf("address error %v:%v", loaded[0].IP, loaded[0].Port)
+	}
+}
+
+func TestNodeDB_ExpireAndFailed(t *testing.T) {
+	ndb, dir := newNodeDB4Test(t)
+	defer os.RemoveAll(dir)
+	defer ndb.close()
+
+	clock := time.Now()
+	ndb.now = func() time.Time { return clock }
+	nodes := nodes4Test(3)
+	ndb.seen(nodes[0])
+	clock = clock.Add(nodeDBExpiration / 2)
+	ndb.seen(nodes[1])
+	ndb.seen(nodes[2])
+
+	for i := 0; i < nodeDBMaxFails; i++ {
+		ndb.failed(nodes[2].ID)
+	}
+	if r := ndb.get(nodes[2].ID); r != nil {
+		t.Fatalf("node failed too many times should be removed")
+	}
+	ndb.failed(nodes[1].ID)
+	if r := ndb.get(nodes[1].ID); r == nil || r.Fails != 1 {
+		t.Fatalf("fails should be counted, got %v", r)
+	}
+	// Seen again resets the fails
+	ndb.seen(nodes[1])
+	if r := ndb.get(nodes[1].ID); r == nil || r.Fails != 0 {
+		t.Fatalf("fails should be reset, got %v", r)
+	}
+
+	clock = clock.Add(nodeDBExpiration/2 + time.Minute)
+	ndb.expire()
+	loaded := ndb.nodes(nodeDBLoadCount)
+	if len(loaded) != 1 || loaded[0].ID != nodes[1].ID {
+		t.Fatalf("stale node should be expired, got %v", loaded)
+	}
+}
+
+func TestKad_LoadSeedNodesFromDB(t *testing.T) {
+	ndb, dir := newNodeDB4Test(t)
+	defer os.RemoveAll(dir)
+	defer ndb.close()
+
+	self := NewNode(*NewNodeID("zv01"), net.ParseIP("127.0.0.1"), 1122)
+	ndb.seen(self)
+	nodes := nodes4Test(5)
+	for _, n := range nodes {
+		ndb.seen(n)
+	}
+	kad := &Kad{self: self, static: make(map[NodeID]*Node), db: ndb}
+	for i := range kad.buckets {
+		kad.buckets[i] = &bucket{}
+	}
+	kad.loadSeedNodes(false)
+	for _, n := range nodes {
+		if kad.find(n.ID) == nil {
+			t.Fatalf("node %v not loaded", n.ID.GetHexString())
+		}
+	}
+	if kad.len() != len(nodes) {
+		t.Fatalf("self shouldn't be loaded, kad size %v", kad.len())
+	}
+}
+
+func TestParseBootNode(t *testing.T) {
+	if Logger == nil {
+		Logger = log.P2PLogger
+	}
+	n, err := ParseBootNode("zv01@localhost:1122")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !n.IP.IsLoopback() || n.Port != 1122 {
+		t.Fatalf("boot node address error %v:%v", n.IP, n.Port)
+	}
+	if _, err := ParseBootNode("zv01@localhost"); err == nil {
+		t.Fatalf("should be error without port")
+	}
+	if list := splitConfigList(" a@b:1, ,c@d:2"); len(list) != 2 || list[1] != "c@d:2" {
+		t.Fatalf("split error %v", list)
+	}
+}
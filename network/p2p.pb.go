@@ -223,6 +223,9 @@ type MsgPing struct {
 	PK                   []byte       `protobuf:"bytes,6,opt,name=PK,proto3" json:"PK,omitempty"`
 	Sign                 []byte       `protobuf:"bytes,7,opt,name=Sign,proto3" json:"Sign,omitempty"`
 	CurTime              uint64       `protobuf:"varint,8,opt,name=CurTime,proto3" json:"CurTime,omitempty"`
+	Capabilities         uint32       `protobuf:"varint,9,opt,name=Capabilities,proto3" json:"Capabilities,omitempty"`
+	ProtocolVersionMin   uint32       `protobuf:"varint,10,opt,name=ProtocolVersionMin,proto3" json:"ProtocolVersionMin,omitempty"`
+	ProtocolVersionMax   uint32       `protobuf:"varint,11,opt,name=ProtocolVersionMax,proto3" json:"ProtocolVersionMax,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
 	XXX_unrecognized     []byte       `json:"-"`
 	XXX_sizecache        int32        `json:"-"`
@@ -317,9 +320,33 @@ func (m *MsgPing) GetCurTime() uint64 {
 	return 0
 }
 
+func (m *MsgPing) GetCapabilities() uint32 {
+	if m != nil {
+		return m.Capabilities
+	}
+	return 0
+}
+
+func (m *MsgPing) GetProtocolVersionMin() uint32 {
+	if m != nil {
+		return m.ProtocolVersionMin
+	}
+	return 0
+}
+
+func (m *MsgPing) GetProtocolVersionMax() uint32 {
+	if m != nil {
+		return m.ProtocolVersionMax
+	}
+	return 0
+}
+
 type MsgPong struct {
 	Version              int32    `protobuf:"varint,1,opt,name=Version,proto3" json:"Version,omitempty"`
 	VerifyResult         bool     `protobuf:"varint,2,opt,name=VerifyResult,proto3" json:"VerifyResult,omitempty"`
+	Capabilities         uint32   `protobuf:"varint,3,opt,name=Capabilities,proto3" json:"Capabilities,omitempty"`
+	ProtocolVersionMin   uint32   `protobuf:"varint,4,opt,name=ProtocolVersionMin,proto3" json:"ProtocolVersionMin,omitempty"`
+	ProtocolVersionMax   uint32   `protobuf:"varint,5,opt,name=ProtocolVersionMax,proto3" json:"ProtocolVersionMax,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -372,6 +399,27 @@ func (m *MsgPong) GetVerifyResult() bool {
 	return false
 }
 
+func (m *MsgPong) GetCapabilities() uint32 {
+	if m != nil {
+		return m.Capabilities
+	}
+	return 0
+}
+
+func (m *MsgPong) GetProtocolVersionMin() uint32 {
+	if m != nil {
+		return m.ProtocolVersionMin
+	}
+	return 0
+}
+
+func (m *MsgPong) GetProtocolVersionMax() uint32 {
+	if m != nil {
+		return m.ProtocolVersionMax
+	}
+	return 0
+}
+
 type MsgFindNode struct {
 	Target               []byte   `protobuf:"bytes,1,opt,name=Target,proto3" json:"Target,omitempty"`
 	Expiration           uint64   `protobuf:"varint,2,opt,name=Expiration,proto3" json:"Expiration,omitempty"`
@@ -493,6 +541,7 @@ type MsgData struct {
 	RelayCount           int32    `protobuf:"varint,8,opt,name=RelayCount,proto3" json:"RelayCount,omitempty"`
 	MessageCode          uint32   `protobuf:"varint,9,opt,name=MessageCode,proto3" json:"MessageCode,omitempty"`
 	MessageInfo          uint32   `protobuf:"varint,10,opt,name=MessageInfo,proto3" json:"MessageInfo,omitempty"`
+	Compression          uint32   `protobuf:"varint,11,opt,name=Compression,proto3" json:"Compression,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -601,6 +650,13 @@ func (m *MsgData) GetMessageInfo() uint32 {
 	return 0
 }
 
+func (m *MsgData) GetCompression() uint32 {
+	if m != nil {
+		return m.Compression
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterEnum("network.MessageType", MessageType_name, MessageType_value)
 	proto.RegisterEnum("network.DataType", DataType_name, DataType_value)
@@ -616,45 +672,50 @@ func init() {
 func init() { proto.RegisterFile("p2p.proto", fileDescriptor_e7fdddb109e6467a) }
 
 var fileDescriptor_e7fdddb109e6467a = []byte{
-	// 607 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0x5d, 0x4f, 0xdb, 0x30,
-	0x14, 0xc5, 0x69, 0x42, 0xe9, 0x6d, 0x01, 0xcf, 0x43, 0x53, 0x1e, 0xa6, 0x2a, 0x8a, 0xa6, 0x29,
-	0x42, 0x1a, 0xd2, 0xba, 0xe7, 0xbd, 0xd0, 0x02, 0xaa, 0x50, 0xab, 0xc8, 0x54, 0xbc, 0x07, 0x62,
-	0x42, 0xb4, 0xd4, 0x37, 0x72, 0xd2, 0x31, 0xa6, 0xfd, 0x90, 0xfd, 0xa4, 0x69, 0x4f, 0xfb, 0x09,
-	0x13, 0x7b, 0xdc, 0x9f, 0x98, 0x6c, 0x92, 0x34, 0x30, 0xed, 0xe3, 0xa9, 0xbe, 0xc7, 0xc7, 0xc7,
-	0xf7, 0xdc, 0xe3, 0x06, 0x7a, 0xf9, 0x28, 0x3f, 0xc8, 0x15, 0x96, 0xc8, 0xba, 0x52, 0x94, 0x37,
-	0xa8, 0xde, 0xf9, 0x6f, 0xa1, 0xcb, 0xf3, 0xcb, 0x39, 0xc6, 0x82, 0xed, 0x80, 0x35, 0x0d, 0x5d,
-	0xe2, 0x91, 0xa0, 0xc7, 0xad, 0x69, 0xc8, 0x18, 0xd8, 0x21, 0xaa, 0xd2, 0xb5, 0x3c, 0x12, 0x38,
-	0xdc, 0xac, 0x0d, 0x67, 0xe2, 0x76, 0x2a, 0xce, 0xc4, 0x7f, 0x0d, 0x7d, 0x9e, 0x5f, 0x1e, 0xc9,
-	0x38, 0xc4, 0x54, 0x96, 0xff, 0x23, 0xe1, 0xff, 0x24, 0xd0, 0x9d, 0x15, 0x49, 0x98, 0xca, 0x84,
-	0xb9, 0xd0, 0x3d, 0x17, 0xaa, 0x48, 0x51, 0x9a, 0x43, 0x0e, 0xaf, 0x4b, 0x16, 0x80, 0x7d, 0xac,
-	0x70, 0x69, 0x4e, 0xf6, 0x47, 0x7b, 0x07, 0x55, 0xbf, 0x07, 0xad, 0xdb, 0xb8, 0x61, 0xb0, 0x17,
-	0x60, 0x2d, 0xd0, 0xb4, 0xf4, 0x27, 0x9e, 0xb5, 0x40, 0x7d, 0xd3, 0xe5, 0x75, 0x94, 0xca, 0xe9,
-	0xc4, 0xb5, 0x3d, 0x12, 0x6c, 0xf3, 0xba, 0x64, 0x43, 0x80, 0xa3, 0x0f, 0x79, 0xaa, 0xa2, 0x52,
-	0xb7, 0xe1, 0x78, 0x24, 0xb0, 0x79, 0x0b, 0xd1, 0x9e, 0xc2, 0x53, 0x77, 0xd3, 0x23, 0xc1, 0x80,
-	0x5b, 0xe1, 0xa9, 0xf6, 0x74, 0x96, 0x26, 0xd2, 0xed, 0x1a, 0xc4, 0xac, 0xb5, 0xfa, 0x78, 0xa5,
-	0x16, 0xe9, 0x52, 0xb8, 0x5b, 0x46, 0xa0, 0x2e, 0xfd, 0x93, 0x7b, 0xb3, 0xf8, 0x57, 0xb3, 0x3e,
-	0x0c, 0xce, 0x85, 0x4a, 0xaf, 0x6e, 0xb9, 0x28, 0x56, 0xd9, 0xfd, 0xb8, 0xb6, 0xf8, 0x03, 0xcc,
-	0x3f, 0x82, 0xfe, 0xac, 0x48, 0x8e, 0x53, 0x19, 0x9b, 0xb0, 0x9e, 0xc1, 0xe6, 0x22, 0x52, 0x89,
-	0x28, 0x8d, 0xd6, 0x80, 0x57, 0xd5, 0x23, 0x37, 0xd6, 0x63, 0x37, 0xfe, 0x39, 0x0c, 0x66, 0x45,
-	0x32, 0x17, 0x69, 0x72, 0x7d, 0x81, 0xaa, 0x60, 0x2f, 0xc1, 0xd1, 0x7a, 0x85, 0x4b, 0xbc, 0x4e,
-	0xd0, 0x1f, 0xd1, 0xf6, 0x00, 0xf5, 0x06, 0xbf, 0xdf, 0xfe, 0xa7, 0xee, 0x57, 0xcb, 0x18, 0x9d,
-	0x44, 0x65, 0xc4, 0x5e, 0xc1, 0x96, 0xfe, 0x5d, 0xdc, 0xe6, 0xc2, 0x74, 0xb7, 0x33, 0x7a, 0xd2,
-	0xc8, 0xd6, 0x1b, 0xbc, 0xa1, 0xe8, 0xb9, 0x9c, 0x28, 0x5c, 0xe5, 0xd3, 0x89, 0xd1, 0xed, 0xf1,
-	0xba, 0x7c, 0x74, 0x69, 0xe7, 0xb7, 0x68, 0x9e, 0x43, 0x6f, 0x26, 0x8a, 0x22, 0x4a, 0x44, 0x15,
-	0xab, 0xcd, 0xd7, 0x80, 0x9e, 0xea, 0x61, 0xfa, 0x71, 0x4d, 0x70, 0xcc, 0xa0, 0x1e, 0x60, 0x5a,
-	0xe1, 0x4c, 0x19, 0xa3, 0xd3, 0x49, 0x95, 0xf1, 0x1a, 0xd0, 0x51, 0xeb, 0x2e, 0xeb, 0xa8, 0x8d,
-	0xb9, 0x21, 0x00, 0x17, 0x59, 0x74, 0x3b, 0xc6, 0x95, 0x2c, 0x4d, 0xda, 0x0e, 0x6f, 0x21, 0xcc,
-	0x83, 0x7e, 0x25, 0x3f, 0xc6, 0x58, 0xb8, 0x3d, 0xf3, 0xd8, 0xda, 0x50, 0x8b, 0x31, 0x95, 0x57,
-	0xe8, 0xc2, 0x03, 0x86, 0x86, 0xf6, 0x3f, 0x35, 0x0c, 0x33, 0xa0, 0xdd, 0xa6, 0x9c, 0xa3, 0x14,
-	0x74, 0xa3, 0x05, 0xe8, 0x7f, 0x11, 0x25, 0x6d, 0x00, 0x65, 0x42, 0x2d, 0xf6, 0x14, 0x76, 0x2b,
-	0x40, 0xbf, 0x18, 0x89, 0xb1, 0xa0, 0x1d, 0xb6, 0x07, 0xb4, 0xd6, 0xa9, 0xf3, 0xa7, 0x76, 0xeb,
-	0xac, 0xf6, 0x47, 0x9d, 0xfd, 0xf7, 0xeb, 0xf8, 0xd8, 0x0e, 0x80, 0x5e, 0xcf, 0x51, 0x2d, 0xa3,
-	0x8c, 0x6e, 0xd4, 0xf5, 0x49, 0x86, 0x17, 0x51, 0x46, 0x89, 0x96, 0x5c, 0xd7, 0x3c, 0x92, 0x31,
-	0x2e, 0xa9, 0xc5, 0xb6, 0xa1, 0x67, 0x50, 0x1d, 0x23, 0xed, 0xe8, 0x66, 0x9a, 0x72, 0x8c, 0xd9,
-	0x6a, 0x29, 0xa9, 0xcd, 0x28, 0x0c, 0x1a, 0x90, 0xe3, 0x0d, 0x75, 0x0e, 0xe9, 0x97, 0xbb, 0x21,
-	0xf9, 0x76, 0x37, 0x24, 0xdf, 0xef, 0x86, 0xe4, 0xf3, 0x8f, 0xe1, 0xc6, 0xc5, 0xa6, 0xf9, 0x58,
-	0xbd, 0xf9, 0x15, 0x00, 0x00, 0xff, 0xff, 0x45, 0xc0, 0xdd, 0xe7, 0xb9, 0x04, 0x00, 0x00,
+	// 674 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0xdd, 0x6a, 0xdb, 0x30,
+	0x18, 0xad, 0x1d, 0xbb, 0x69, 0xbe, 0xa4, 0xad, 0xa6, 0x95, 0xe1, 0x8b, 0x11, 0x8c, 0x19, 0xc3,
+	0x14, 0x16, 0x58, 0x76, 0xbd, 0x9b, 0x26, 0x6d, 0x09, 0x25, 0xc1, 0xa8, 0xa1, 0xf7, 0x4a, 0xa2,
+	0xba, 0x62, 0x8e, 0x64, 0x64, 0x67, 0x6d, 0xc7, 0x1e, 0x64, 0x8f, 0x34, 0xd8, 0xcd, 0x1e, 0x61,
+	0x74, 0xb7, 0x83, 0xbd, 0xc2, 0x90, 0x62, 0x27, 0xee, 0xcf, 0xba, 0xee, 0xca, 0xfa, 0x8e, 0x8e,
+	0x8e, 0x74, 0xbe, 0xef, 0x60, 0x68, 0xa4, 0xdd, 0xb4, 0x93, 0x2a, 0x99, 0x4b, 0x5c, 0x17, 0x2c,
+	0xbf, 0x94, 0xea, 0x43, 0xf0, 0x1e, 0xea, 0x24, 0x9d, 0x8e, 0xe4, 0x8c, 0xe1, 0x1d, 0xb0, 0x07,
+	0x91, 0x67, 0xf9, 0x56, 0xd8, 0x20, 0xf6, 0x20, 0xc2, 0x18, 0x9c, 0x48, 0xaa, 0xdc, 0xb3, 0x7d,
+	0x2b, 0x74, 0x89, 0x59, 0x1b, 0x4e, 0xdf, 0xab, 0x15, 0x9c, 0x7e, 0xf0, 0x16, 0x9a, 0x24, 0x9d,
+	0x1e, 0x8a, 0x59, 0x24, 0xb9, 0xc8, 0x9f, 0x22, 0x11, 0xfc, 0xb6, 0xa1, 0x3e, 0xcc, 0xe2, 0x88,
+	0x8b, 0x18, 0x7b, 0x50, 0x3f, 0x63, 0x2a, 0xe3, 0x52, 0x98, 0x43, 0x2e, 0x29, 0x4b, 0x1c, 0x82,
+	0x73, 0xa4, 0xe4, 0xdc, 0x9c, 0x6c, 0x76, 0xf7, 0x3a, 0xc5, 0x7b, 0x3b, 0x95, 0xdb, 0x88, 0x61,
+	0xe0, 0x57, 0x60, 0x8f, 0xa5, 0x57, 0x7b, 0x84, 0x67, 0x8f, 0xa5, 0xbe, 0x69, 0x7a, 0x41, 0xb9,
+	0x18, 0xf4, 0x3d, 0xc7, 0xb7, 0xc2, 0x6d, 0x52, 0x96, 0xb8, 0x0d, 0x70, 0x78, 0x95, 0x72, 0x45,
+	0x73, 0xfd, 0x0c, 0xd7, 0xb7, 0x42, 0x87, 0x54, 0x10, 0xed, 0x29, 0x3a, 0xf1, 0x36, 0x7d, 0x2b,
+	0x6c, 0x11, 0x3b, 0x3a, 0xd1, 0x9e, 0x4e, 0x79, 0x2c, 0xbc, 0xba, 0x41, 0xcc, 0x5a, 0xab, 0xf7,
+	0x16, 0x6a, 0xcc, 0xe7, 0xcc, 0xdb, 0x32, 0x02, 0x65, 0x89, 0x03, 0x68, 0xf5, 0x68, 0x4a, 0x27,
+	0x3c, 0xe1, 0x39, 0x67, 0x99, 0xd7, 0x30, 0x97, 0xdf, 0xc2, 0x70, 0x07, 0x70, 0xa4, 0xa7, 0x32,
+	0x95, 0x49, 0x61, 0x7f, 0xc8, 0x85, 0x07, 0x86, 0xf9, 0xc0, 0xce, 0x43, 0x7c, 0x7a, 0xe5, 0x35,
+	0x1f, 0xe6, 0xd3, 0xab, 0xe0, 0x9b, 0xb5, 0xec, 0xb8, 0x7c, 0xb4, 0xe3, 0x01, 0xb4, 0xce, 0x98,
+	0xe2, 0xe7, 0xd7, 0x84, 0x65, 0x8b, 0x64, 0x39, 0xb3, 0x2d, 0x72, 0x0b, 0xbb, 0xe7, 0xa6, 0xf6,
+	0x64, 0x37, 0xce, 0x7f, 0xba, 0x71, 0xff, 0xea, 0xe6, 0x10, 0x9a, 0xc3, 0x2c, 0x3e, 0xe2, 0x62,
+	0x66, 0x52, 0xfb, 0x02, 0x36, 0xc7, 0x54, 0xc5, 0x2c, 0x37, 0x7e, 0x5a, 0xa4, 0xa8, 0xee, 0x8c,
+	0xd5, 0xbe, 0x3b, 0xd6, 0xe0, 0x0c, 0x5a, 0xc3, 0x2c, 0x1e, 0x31, 0x1e, 0x5f, 0x4c, 0xa4, 0xca,
+	0xf0, 0x6b, 0x70, 0xb5, 0x5e, 0xe6, 0x59, 0x7e, 0x2d, 0x6c, 0x76, 0x51, 0x35, 0x49, 0x7a, 0x83,
+	0x2c, 0xb7, 0xff, 0xa9, 0xfb, 0x6b, 0x19, 0xef, 0x3e, 0xcd, 0x29, 0x7e, 0x03, 0x5b, 0xfa, 0x3b,
+	0xbe, 0x4e, 0x99, 0x79, 0xdd, 0x4e, 0xf7, 0xd9, 0x4a, 0xb6, 0xdc, 0x20, 0x2b, 0x8a, 0x9e, 0xcd,
+	0xb1, 0x92, 0x8b, 0x74, 0xd0, 0x37, 0xba, 0x0d, 0x52, 0x96, 0x77, 0x2e, 0xad, 0xdd, 0xcb, 0xe8,
+	0x4b, 0x68, 0x0c, 0x59, 0x96, 0xd1, 0x98, 0x15, 0xf9, 0x76, 0xc8, 0x1a, 0xd0, 0x53, 0x3b, 0xe0,
+	0x9f, 0xd6, 0x04, 0xd7, 0x34, 0xea, 0x16, 0xa6, 0x15, 0x4e, 0x95, 0x31, 0x3a, 0xe8, 0x17, 0x61,
+	0x5f, 0x03, 0x3a, 0xf3, 0xfa, 0x95, 0x65, 0xe6, 0x8d, 0xb9, 0x36, 0x00, 0x61, 0x09, 0xbd, 0xee,
+	0xc9, 0x85, 0xc8, 0x4d, 0xec, 0x5d, 0x52, 0x41, 0xb0, 0x0f, 0xcd, 0x42, 0xbe, 0x27, 0x67, 0xac,
+	0x08, 0x7e, 0x15, 0xaa, 0x30, 0x06, 0xe2, 0x5c, 0x16, 0x81, 0xaf, 0x42, 0x9a, 0xd1, 0x93, 0xf3,
+	0x54, 0xb1, 0xcc, 0x24, 0x76, 0x19, 0xf1, 0x2a, 0xb4, 0xff, 0x79, 0xa5, 0x61, 0x5a, 0xb8, 0xbb,
+	0x2a, 0x47, 0x52, 0x30, 0xb4, 0x51, 0x01, 0xf4, 0x0f, 0x07, 0x59, 0x55, 0x40, 0x8a, 0x18, 0xd9,
+	0xf8, 0x39, 0xec, 0x16, 0x80, 0xce, 0x94, 0x90, 0x33, 0x86, 0x6a, 0x78, 0x0f, 0x50, 0xa9, 0x53,
+	0x26, 0x04, 0x39, 0x95, 0xb3, 0xba, 0x03, 0xc8, 0xdd, 0xff, 0xb8, 0x1e, 0x30, 0xde, 0x01, 0xd0,
+	0xeb, 0x91, 0x54, 0x73, 0x9a, 0xa0, 0x8d, 0xb2, 0x3e, 0x4e, 0xe4, 0x84, 0x26, 0xc8, 0xd2, 0x92,
+	0xeb, 0x9a, 0x50, 0x31, 0x93, 0x73, 0x64, 0xe3, 0x6d, 0x68, 0x18, 0x54, 0x0f, 0x1a, 0xd5, 0xf4,
+	0x63, 0x56, 0x65, 0x4f, 0x26, 0x8b, 0xb9, 0x40, 0x0e, 0x46, 0xd0, 0x5a, 0x81, 0x44, 0x5e, 0x22,
+	0xf7, 0x00, 0x7d, 0xbd, 0x69, 0x5b, 0xdf, 0x6f, 0xda, 0xd6, 0x8f, 0x9b, 0xb6, 0xf5, 0xe5, 0x67,
+	0x7b, 0x63, 0xb2, 0x69, 0xfe, 0xeb, 0xef, 0xfe, 0x0c, 0x00, 0x1f, 0x3c, 0xd9, 0x42, 0xe4, 0x05,
+	0x00, 0x00,
 }
 
 func (m *RpcNode) Marshal() (dAtA []byte, err error) {
@@ -794,6 +855,21 @@ func (m *MsgPing) MarshalTo(dAtA []byte) (int, error) {
 		i++
 		i = encodeVarintP2P(dAtA, i, uint64(m.CurTime))
 	}
+	if m.Capabilities != 0 {
+		dAtA[i] = 0x48
+		i++
+		i = encodeVarintP2P(dAtA, i, uint64(m.Capabilities))
+	}
+	if m.ProtocolVersionMin != 0 {
+		dAtA[i] = 0x50
+		i++
+		i = encodeVarintP2P(dAtA, i, uint64(m.ProtocolVersionMin))
+	}
+	if m.ProtocolVersionMax != 0 {
+		dAtA[i] = 0x58
+		i++
+		i = encodeVarintP2P(dAtA, i, uint64(m.ProtocolVersionMax))
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
@@ -830,6 +906,21 @@ func (m *MsgPong) MarshalTo(dAtA []byte) (int, error) {
 		}
 		i++
 	}
+	if m.Capabilities != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintP2P(dAtA, i, uint64(m.Capabilities))
+	}
+	if m.ProtocolVersionMin != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintP2P(dAtA, i, uint64(m.ProtocolVersionMin))
+	}
+	if m.ProtocolVersionMax != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintP2P(dAtA, i, uint64(m.ProtocolVersionMax))
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
@@ -975,6 +1066,11 @@ func (m *MsgData) MarshalTo(dAtA []byte) (int, error) {
 		i++
 		i = encodeVarintP2P(dAtA, i, uint64(m.MessageInfo))
 	}
+	if m.Compression != 0 {
+		dAtA[i] = 0x58
+		i++
+		i = encodeVarintP2P(dAtA, i, uint64(m.Compression))
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
@@ -1066,6 +1162,15 @@ func (m *MsgPing) Size() (n int) {
 	if m.CurTime != 0 {
 		n += 1 + sovP2P(uint64(m.CurTime))
 	}
+	if m.Capabilities != 0 {
+		n += 1 + sovP2P(uint64(m.Capabilities))
+	}
+	if m.ProtocolVersionMin != 0 {
+		n += 1 + sovP2P(uint64(m.ProtocolVersionMin))
+	}
+	if m.ProtocolVersionMax != 0 {
+		n += 1 + sovP2P(uint64(m.ProtocolVersionMax))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -1084,6 +1189,15 @@ func (m *MsgPong) Size() (n int) {
 	if m.VerifyResult {
 		n += 2
 	}
+	if m.Capabilities != 0 {
+		n += 1 + sovP2P(uint64(m.Capabilities))
+	}
+	if m.ProtocolVersionMin != 0 {
+		n += 1 + sovP2P(uint64(m.ProtocolVersionMin))
+	}
+	if m.ProtocolVersionMax != 0 {
+		n += 1 + sovP2P(uint64(m.ProtocolVersionMax))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -1170,6 +1284,9 @@ func (m *MsgData) Size() (n int) {
 	if m.MessageInfo != 0 {
 		n += 1 + sovP2P(uint64(m.MessageInfo))
 	}
+	if m.Compression != 0 {
+		n += 1 + sovP2P(uint64(m.Compression))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -1669,6 +1786,63 @@ func (m *MsgPing) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Capabilities", wireType)
+			}
+			m.Capabilities = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowP2P
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Capabilities |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ProtocolVersionMin", wireType)
+			}
+			m.ProtocolVersionMin = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowP2P
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ProtocolVersionMin |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ProtocolVersionMax", wireType)
+			}
+			m.ProtocolVersionMax = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowP2P
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ProtocolVersionMax |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipP2P(dAtA[iNdEx:])
@@ -1762,6 +1936,63 @@ func (m *MsgPong) Unmarshal(dAtA []byte) error {
 				}
 			}
 			m.VerifyResult = bool(v != 0)
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Capabilities", wireType)
+			}
+			m.Capabilities = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowP2P
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Capabilities |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ProtocolVersionMin", wireType)
+			}
+			m.ProtocolVersionMin = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowP2P
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ProtocolVersionMin |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ProtocolVersionMax", wireType)
+			}
+			m.ProtocolVersionMax = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowP2P
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ProtocolVersionMax |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipP2P(dAtA[iNdEx:])
@@ -2278,6 +2509,25 @@ func (m *MsgData) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Compression", wireType)
+			}
+			m.Compression = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowP2P
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Compression |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipP2P(dAtA[iNdEx:])
@@ -34,8 +34,21 @@ const (
 	PeerSourceUnkown PeerSource = 0
 	PeerSourceKad    PeerSource = 1
 	PeerSourceGroup  PeerSource = 2
+	PeerSourceStatic PeerSource = 3
 )
 
+func (s PeerSource) String() string {
+	switch s {
+	case PeerSourceKad:
+		return "kad"
+	case PeerSourceGroup:
+		return "group"
+	case PeerSourceStatic:
+		return "static"
+	}
+	return "unknown"
+}
+
 type PeerAuthContext struct {
 	PK      []byte
 	Sign    []byte
@@ -118,6 +131,8 @@ type Peer struct {
 	sendWaitCount   int
 	disconnectCount int
 	chainID         uint16
+	capabilities    uint32 // Capabilities advertised by the peer in ping or pong
+	protocolVersion uint16 // Protocol version negotiated in the handshake, 0 before
 
 	connectTime        time.Time
 	authContext        *PeerAuthContext
@@ -353,6 +368,8 @@ func (p *Peer) onDisonnect(id uint64, session uint32, p2pCode uint32) {
 		p.resetData()
 		p.sessionID = 0
 		p.sendList.pendingSend = 0
+		// The peer may restart with another version, wait for its handshake again
+		p.setHandshake(0, 0)
 	}
 
 }
@@ -377,17 +394,50 @@ func (p *Peer) verify(pac *PeerAuthContext) bool {
 }
 
 func (p *Peer) write(packet *bytes.Buffer, code uint32) {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
 	if packet == nil {
 		return
 	}
-	b := netCore.bufferPool.getBuffer(packet.Len())
-	b.Write(packet.Bytes())
+	version, caps := p.handshake()
+	if required := requiredCapabilities(code); caps&required != required {
+		Logger.Debugf("peer %v lacks capabilities %v of code %v, skipped", p.ID.GetHexString(), required, code)
+		return
+	}
+	data := packet.Bytes()
+	if version != 0 && version != netCore.protocolVersion {
+		data = setPacketProtocolVersion(data, version)
+	}
+	data = netCore.compressor.compress(data, caps)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	b := netCore.bufferPool.getBuffer(len(data))
+	b.Write(data)
+	p.bytesSend += len(data)
 
 	p.sendList.send(p, b, int(code))
 }
 
+// handshake returns the protocol version and capabilities negotiated with the peer
+func (p *Peer) handshake() (uint16, uint32) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.protocolVersion, p.capabilities
+}
+
+func (p *Peer) setHandshake(version uint16, caps uint32) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.protocolVersion = version
+	p.capabilities = caps
+}
+
+// supports returns whether the peer advertised all the capabilities
+func (p *Peer) supports(caps uint32) bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.capabilities&caps == caps
+}
+
 func (p *Peer) getDataSize() int {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
@@ -400,6 +450,31 @@ func (p *Peer) getDataSize() int {
 	return size
 }
 
+// info returns the status of the peer, the source is left to the peer manager
+func (p *Peer) info() *PeerInfo {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	info := &PeerInfo{
+		ID:              p.ID.GetHexString(),
+		Port:            p.Port,
+		Session:         p.sessionID,
+		Authed:          p.isAuthSucceed,
+		ChainID:         p.chainID,
+		BytesSend:       p.bytesSend,
+		BytesReceived:   p.bytesReceived,
+		DisconnectCount: p.disconnectCount,
+		ProtocolVersion: p.protocolVersion,
+		Capabilities:    CapabilityNames(p.capabilities),
+	}
+	if p.IP != nil {
+		info.IP = p.IP.String()
+	}
+	if p.sessionID > 0 && !p.connectTime.IsZero() {
+		info.SessionAge = time.Since(p.connectTime)
+	}
+	return info
+}
+
 func (p *Peer) IsCompatible() bool {
 	return netCore.chainID == p.chainID
 }
@@ -27,6 +27,15 @@ import (
 
 const DEFAULT_MAX_PEER_SIZE_PER_IP = 16
 
+// Interval of redialing the disconnected static node
+const staticDialInterval = 10 * time.Second
+
+// staticNode is the node always kept connected, it is exempted from the ip limit and the ban list
+type staticNode struct {
+	node   *Node
+	dialAt time.Time
+}
+
 // PeerManager is node connection management
 type PeerManager struct {
 	peers              map[uint64]*Peer // Key is the network ID
@@ -35,12 +44,14 @@ type PeerManager struct {
 	natPort            uint16
 	natIP              string
 	peerIPSet          PeerIPSet
+	static             map[uint64]*staticNode // Key is the network ID
 }
 
 func newPeerManager() *PeerManager {
 	pm := &PeerManager{
 		peers:     make(map[uint64]*Peer),
 		peerIPSet: PeerIPSet{Limit: DEFAULT_MAX_PEER_SIZE_PER_IP, members: make(map[string]uint)},
+		static:    make(map[uint64]*staticNode),
 	}
 	priorityTable = map[uint32]SendPriorityType{
 		BlockInfoNotifyMsg:       SendPriorityHigh,
@@ -66,6 +77,9 @@ func (pm *PeerManager) write(toid NodeID, toaddr *net.UDPAddr, packet *bytes.Buf
 		return
 	}
 	netID := genNetID(toid)
+	if pm.isBanned(netID) {
+		return
+	}
 	p := pm.peerByNetID(netID)
 
 	if p == nil {
@@ -81,7 +95,6 @@ func (pm *PeerManager) write(toid NodeID, toaddr *net.UDPAddr, packet *bytes.Buf
 		}
 	}
 	p.write(packet, code)
-	p.bytesSend += packet.Len()
 
 	if p.sessionID != 0 {
 		return
@@ -108,6 +121,11 @@ func (pm *PeerManager) write(toid NodeID, toaddr *net.UDPAddr, packet *bytes.Buf
 
 // newConnection handling callbacks for successful connections
 func (pm *PeerManager) newConnection(id uint64, session uint32, p2pType uint32, isAccepted bool, ip string, port uint16) {
+	if pm.isBanned(id) {
+		Logger.Infof("connection of banned peer, netid:%v ip:%v port:%v", id, ip, port)
+		P2PShutdown(session)
+		return
+	}
 
 	p := pm.peerByNetID(id)
 	if p == nil {
@@ -140,6 +158,10 @@ func (pm *PeerManager) onDisconnected(id uint64, session uint32, p2pCode uint32)
 	if p != nil {
 
 		Logger.Infof("OnDisconnected id：%v  session:%v ip:%v port:%v ", p.ID.GetHexString(), session, p.IP, p.Port)
+		if !p.isAuthSucceed && netCore != nil && netCore.kad != nil {
+			// Disconnected before authenticated, failed to connect the node
+			netCore.kad.onNodeFailed(p.ID)
+		}
 		p.onDisonnect(id, session, p2pCode)
 
 		delete(pm.peers, genNetID(p.ID))
@@ -150,6 +172,90 @@ func (pm *PeerManager) onDisconnected(id uint64, session uint32, p2pCode uint32)
 	}
 }
 
+// isBanned returns whether the peer of the net id is in the ban list. Static nodes are never banned
+func (pm *PeerManager) isBanned(netID uint64) bool {
+	if pm.isStatic(netID) {
+		return false
+	}
+	return netCore != nil && netCore.scorer != nil && netCore.scorer.isNetIDBanned(netID)
+}
+
+// disconnectUntrusted disconnects the peer unless it is a static node
+func (pm *PeerManager) disconnectUntrusted(id NodeID) {
+	if pm.isStatic(genNetID(id)) {
+		Logger.Infof("static node %v not disconnected", id.GetHexString())
+		return
+	}
+	pm.disconnect(id)
+}
+
+func (pm *PeerManager) addStatic(n *Node) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+	pm.static[genNetID(n.ID)] = &staticNode{node: n}
+}
+
+func (pm *PeerManager) removeStatic(id NodeID) bool {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+	netID := genNetID(id)
+	_, ok := pm.static[netID]
+	delete(pm.static, netID)
+	return ok
+}
+
+func (pm *PeerManager) isStatic(netID uint64) bool {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+	_, ok := pm.static[netID]
+	return ok
+}
+
+func (pm *PeerManager) staticNodes() []*Node {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+	nodes := make([]*Node, 0, len(pm.static))
+	for _, sn := range pm.static {
+		nodes = append(nodes, sn.node)
+	}
+	return nodes
+}
+
+// checkStatic redials the static nodes not connected
+func (pm *PeerManager) checkStatic() {
+	now := time.Now()
+	dials := make([]*Node, 0)
+	pm.mutex.Lock()
+	for netID, sn := range pm.static {
+		p := pm.peers[netID]
+		if p != nil && (p.sessionID > 0 || p.connecting) {
+			continue
+		}
+		if now.Sub(sn.dialAt) < staticDialInterval {
+			continue
+		}
+		sn.dialAt = now
+		dials = append(dials, sn.node)
+	}
+	pm.mutex.Unlock()
+
+	for _, n := range dials {
+		Logger.Infof("dial static node %v %v:%v", n.ID.GetHexString(), n.IP, n.Port)
+		go netCore.ping(n.ID, n.addr())
+	}
+}
+
+// peerSource returns where the peer comes from
+func (pm *PeerManager) peerSource(netID uint64, p *Peer) PeerSource {
+	if pm.isStatic(netID) {
+		return PeerSourceStatic
+	}
+	if !p.isGroupEmpty() {
+		return PeerSourceGroup
+	}
+	return PeerSourceKad
+}
+
 func (pm *PeerManager) disconnect(id NodeID) {
 	netID := genNetID(id)
 
@@ -180,7 +286,7 @@ func (pm *PeerManager) checkPeers() {
 				go netServerInstance.netCore.ping(p.ID, nil)
 			}
 			if !p.verifyResult && p.sessionID > 0 {
-				pongMsg := MsgPong{Version: 0, VerifyResult: p.verifyResult}
+				pongMsg := netServerInstance.netCore.pong(p)
 
 				packet, _, err := netServerInstance.netCore.encodePacket(MessageType_MessagePong, &pongMsg)
 				if err != nil {
@@ -221,8 +327,9 @@ func (pm *PeerManager) broadcastRandom(packet *bytes.Buffer, code uint32, maxCou
 	availablePeers := make([]*Peer, 0)
 	sendPeers := make([]*Peer, 0)
 
+	caps := requiredCapabilities(code)
 	for _, p := range pm.peers {
-		if p.sessionID > 0 && p.IsCompatible() {
+		if p.sessionID > 0 && p.IsCompatible() && p.supports(caps) {
 			availablePeers = append(availablePeers, p)
 		}
 	}
@@ -271,7 +378,10 @@ func (pm *PeerManager) addPeer(netID uint64, peer *Peer) bool {
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
-	if peer.IP != nil && len(peer.IP.String()) > 0 && !pm.peerIPSet.Add(peer.IP.String()) {
+	if _, ok := pm.static[netID]; ok && peer.IP != nil && len(peer.IP.String()) > 0 {
+		// Static nodes are not limited by ip
+		pm.peerIPSet.members[peer.IP.String()]++
+	} else if peer.IP != nil && len(peer.IP.String()) > 0 && !pm.peerIPSet.Add(peer.IP.String()) {
 		Logger.Infof("addPeer failed, peer in same IP exceed limit size !Max size:%v, ip:%v", pm.peerIPSet.Limit, peer.IP.String())
 		return false
 	}
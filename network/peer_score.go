@@ -0,0 +1,388 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/darren0718/zvchain/common"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// PeerEvent is the misbehavior of a peer reported by the upper layers
+type PeerEvent int
+
+const (
+	PeerEventBadBlock     PeerEvent = iota // Synced block failed to verify
+	PeerEventBadForkBlock                  // Block of the fork chain failed to verify
+	PeerEventBadTxs                        // Too many illegal transactions
+	PeerEventBadMessage                    // Message can't be decoded
+	PeerEventTimeout                       // Request not responded in time
+	PeerEventRateLimited                   // Message exceeds the rate limit
+	PeerEventOversize                      // Message exceeds the size limit
+)
+
+var peerEventNames = map[PeerEvent]string{
+	PeerEventBadBlock:     "bad block",
+	PeerEventBadForkBlock: "bad fork block",
+	PeerEventBadTxs:       "bad transactions",
+	PeerEventBadMessage:   "bad message",
+	PeerEventTimeout:      "timeout",
+	PeerEventRateLimited:  "rate limited",
+	PeerEventOversize:     "oversize message",
+}
+
+// Score deducted for each event
+var peerEventPenalties = map[PeerEvent]int{
+	PeerEventBadBlock:     40,
+	PeerEventBadForkBlock: 40,
+	PeerEventBadTxs:       25,
+	PeerEventBadMessage:   10,
+	PeerEventTimeout:      5,
+	PeerEventRateLimited:  1,
+	PeerEventOversize:     20,
+}
+
+func (e PeerEvent) String() string {
+	if s, ok := peerEventNames[e]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown event %d", int(e))
+}
+
+const (
+	peerScoreMax             = 100 // Initial and the maximum score of a peer
+	peerScoreBanThreshold    = 0   // Peers with score not above it are banned
+	peerScoreRecoverInterval = time.Minute
+	peerScoreCacheSize       = 1000
+
+	// The ban duration doubles each time the peer is banned again, and the ban
+	// count decays by one each banCountDecayInterval after the last ban
+	banBaseDuration       = 10 * time.Minute
+	banMaxDuration        = 7 * 24 * time.Hour
+	banCountDecayInterval = 24 * time.Hour
+)
+
+// BanEntry is a banned peer in the ban list
+type BanEntry struct {
+	ID      string    `json:"id"`
+	Reason  string    `json:"reason"`
+	Count   int       `json:"count"`    // Times the peer banned, decays over time
+	LastBan time.Time `json:"last_ban"` // Time of the last ban
+	Until   time.Time `json:"until"`    // The ban expires at
+
+	netID uint64 // Net id of the peer, computed when the entry added
+}
+
+type peerScore struct {
+	score  int
+	update time.Time
+}
+
+// peerScorer scores the peers by the reported events and bans the ones scored under the threshold.
+// The ban list is persisted in the file and reloaded on restart
+type peerScorer struct {
+	scores     *lru.Cache // Key is the hex id of the peer, value is *peerScore
+	bans       map[string]*BanEntry
+	file       string
+	mutex      sync.Mutex
+	now        func() time.Time
+	disconnect func(id NodeID)
+}
+
+func newPeerScorer(file string, disconnect func(id NodeID)) *peerScorer {
+	ps := &peerScorer{
+		scores:     common.MustNewLRUCache(peerScoreCacheSize),
+		bans:       make(map[string]*BanEntry),
+		file:       file,
+		now:        time.Now,
+		disconnect: disconnect,
+	}
+	if err := ps.load(); err != nil {
+		Logger.Errorf("load ban list from %v error:%v", file, err)
+	}
+	return ps
+}
+
+// recovered returns the score recovered to now, one point each interval
+func (ps *peerScorer) recovered(s *peerScore) int {
+	n := int(ps.now().Sub(s.update) / peerScoreRecoverInterval)
+	if s.score+n > peerScoreMax {
+		return peerScoreMax
+	}
+	return s.score + n
+}
+
+// score returns the current score of the peer
+func (ps *peerScorer) score(id string) int {
+	id = normalizePeerID(id)
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	v, ok := ps.scores.Get(id)
+	if !ok {
+		return peerScoreMax
+	}
+	return ps.recovered(v.(*peerScore))
+}
+
+// report deducts the score of the peer by the event, and bans the peer if it falls to the threshold
+func (ps *peerScorer) report(id string, event PeerEvent) {
+	penalty, ok := peerEventPenalties[event]
+	if !ok || id == "" {
+		return
+	}
+	id = normalizePeerID(id)
+	ps.mutex.Lock()
+	now := ps.now()
+	s := &peerScore{score: peerScoreMax, update: now}
+	if v, ok := ps.scores.Get(id); ok {
+		s = v.(*peerScore)
+		s.score = ps.recovered(s)
+		s.update = now
+	}
+	s.score -= penalty
+	ps.scores.Add(id, s)
+	score := s.score
+	ps.mutex.Unlock()
+
+	Logger.Infof("peer %v reported for %v, score %v", id, event, score)
+	if score <= peerScoreBanThreshold {
+		ps.ban(id, 0, event.String())
+	}
+}
+
+// ban bans the peer and disconnects it. The duration grows with the times the peer banned if d is 0
+func (ps *peerScorer) ban(id string, d time.Duration, reason string) error {
+	nID := NewNodeID(id)
+	if nID == nil {
+		return fmt.Errorf("invalid peer id %v", id)
+	}
+	id = nID.GetHexString()
+	ps.mutex.Lock()
+	now := ps.now()
+	e, ok := ps.bans[id]
+	if !ok {
+		e = &BanEntry{ID: id, netID: genNetID(*nID)}
+		ps.bans[id] = e
+	}
+	e.Count = ps.decayedCount(e) + 1
+	e.LastBan = now
+	e.Reason = reason
+	if d <= 0 {
+		d = banDuration(e.Count)
+	}
+	e.Until = now.Add(d)
+	until := e.Until
+	ps.scores.Remove(id)
+	err := ps.save()
+	ps.mutex.Unlock()
+
+	Logger.Warnf("peer %v banned until %v, reason:%v", id, until, reason)
+	if ps.disconnect != nil {
+		ps.disconnect(*nID)
+	}
+	return err
+}
+
+// unban removes the peer from the ban list and resets its score
+func (ps *peerScorer) unban(id string) error {
+	id = normalizePeerID(id)
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	if _, ok := ps.bans[id]; !ok {
+		return fmt.Errorf("peer %v not banned", id)
+	}
+	delete(ps.bans, id)
+	ps.scores.Remove(id)
+	return ps.save()
+}
+
+func (ps *peerScorer) isBanned(id string) bool {
+	id = normalizePeerID(id)
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	e, ok := ps.bans[id]
+	return ok && ps.now().Before(e.Until)
+}
+
+// isNetIDBanned returns whether the peer of the net id is banned, used before the node id known
+func (ps *peerScorer) isNetIDBanned(netID uint64) bool {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	now := ps.now()
+	for _, e := range ps.bans {
+		if now.Before(e.Until) && e.netID == netID {
+			return true
+		}
+	}
+	return false
+}
+
+// banned returns the peers banned currently, sorted by the expire time
+func (ps *peerScorer) banned() []BanEntry {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	now := ps.now()
+	ret := make([]BanEntry, 0)
+	for _, e := range ps.bans {
+		if now.Before(e.Until) {
+			ret = append(ret, *e)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Until.Before(ret[j].Until)
+	})
+	return ret
+}
+
+func (ps *peerScorer) decayedCount(e *BanEntry) int {
+	n := e.Count - int(ps.now().Sub(e.LastBan)/banCountDecayInterval)
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// normalizePeerID returns the id in the form of NodeID.GetHexString
+func normalizePeerID(id string) string {
+	if nID := NewNodeID(id); nID != nil {
+		return nID.GetHexString()
+	}
+	return id
+}
+
+func banDuration(count int) time.Duration {
+	d := banBaseDuration
+	for i := 1; i < count && d < banMaxDuration; i++ {
+		d *= 2
+	}
+	if d > banMaxDuration {
+		return banMaxDuration
+	}
+	return d
+}
+
+// load reads the ban list from the file. The file not existing is not an error
+func (ps *peerScorer) load() error {
+	if ps.file == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(ps.file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	entries := make([]*BanEntry, 0)
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	for _, e := range entries {
+		if e == nil || !common.ValidateAddress(e.ID) {
+			Logger.Warnf("invalid entry in ban list %v: %+v", ps.file, e)
+			continue
+		}
+		nID := NewNodeID(e.ID)
+		e.ID = nID.GetHexString()
+		e.netID = genNetID(*nID)
+		ps.bans[e.ID] = e
+	}
+	return nil
+}
+
+// save writes the ban list to the file, the entries expired and fully decayed are dropped.
+// Must be called with the lock held
+func (ps *peerScorer) save() error {
+	now := ps.now()
+	entries := make([]*BanEntry, 0, len(ps.bans))
+	for id, e := range ps.bans {
+		if now.After(e.Until) && ps.decayedCount(e) == 0 {
+			delete(ps.bans, id)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if ps.file == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := ps.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ps.file)
+}
+
+// ReportPeer reports the misbehavior of the peer. The peer is disconnected and banned if its score
+// falls under the threshold
+func ReportPeer(id string, event PeerEvent) {
+	if netCore == nil || netCore.scorer == nil {
+		return
+	}
+	netCore.scorer.report(id, event)
+}
+
+// BanPeer bans the peer for the duration, which grows with the times banned if d is 0
+func BanPeer(id string, d time.Duration, reason string) error {
+	if netCore == nil || netCore.scorer == nil {
+		return fmt.Errorf("network not initialized")
+	}
+	return netCore.scorer.ban(id, d, reason)
+}
+
+// UnbanPeer removes the peer from the ban list
+func UnbanPeer(id string) error {
+	if netCore == nil || netCore.scorer == nil {
+		return fmt.Errorf("network not initialized")
+	}
+	return netCore.scorer.unban(id)
+}
+
+// IsPeerBanned returns whether the peer is banned currently
+func IsPeerBanned(id string) bool {
+	if netCore == nil || netCore.scorer == nil {
+		return false
+	}
+	return netCore.scorer.isBanned(id)
+}
+
+// BannedPeers returns the ban list
+func BannedPeers() []BanEntry {
+	if netCore == nil || netCore.scorer == nil {
+		return nil
+	}
+	return netCore.scorer.banned()
+}
+
+// PeerScore returns the current score of the peer
+func PeerScore(id string) int {
+	if netCore == nil || netCore.scorer == nil {
+		return peerScoreMax
+	}
+	return netCore.scorer.score(id)
+}
@@ -0,0 +1,169 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/darren0718/zvchain/log"
+)
+
+const peerID4Test = "zv0000000000000000000000000000000000000000000000000000000000001234"
+
+type peerScorer4Test struct {
+	*peerScorer
+	clock        time.Time
+	disconnected []NodeID
+}
+
+func newPeerScorer4Test(t *testing.T, file string) *peerScorer4Test {
+	if Logger == nil {
+		Logger = log.P2PLogger
+	}
+	ps := &peerScorer4Test{clock: time.Now()}
+	ps.peerScorer = newPeerScorer(file, func(id NodeID) {
+		ps.disconnected = append(ps.disconnected, id)
+	})
+	ps.now = func() time.Time { return ps.clock }
+	return ps
+}
+
+func (ps *peerScorer4Test) elapse(d time.Duration) {
+	ps.clock = ps.clock.Add(d)
+}
+
+func TestPeerScorer_ReportAndBan(t *testing.T) {
+	ps := newPeerScorer4Test(t, "")
+
+	ps.report(peerID4Test, PeerEventBadBlock)
+	ps.report(peerID4Test, PeerEventBadBlock)
+	if s := ps.score(peerID4Test); s != peerScoreMax-80 {
+		t.Fatalf("score error, got %v", s)
+	}
+	if ps.isBanned(peerID4Test) {
+		t.Fatalf("peer shouldn't be banned")
+	}
+	// Recovers one point per minute
+	ps.elapse(10 * time.Minute)
+	if s := ps.score(peerID4Test); s != peerScoreMax-70 {
+		t.Fatalf("score recover error, got %v", s)
+	}
+	ps.report(peerID4Test, PeerEventBadTxs)
+	ps.report(peerID4Test, PeerEventBadMessage)
+	if !ps.isBanned(peerID4Test) {
+		t.Fatalf("peer should be banned")
+	}
+	if len(ps.disconnected) != 1 || ps.disconnected[0].GetHexString() != peerID4Test {
+		t.Fatalf("peer should be disconnected")
+	}
+	if !ps.isNetIDBanned(genNetID(*NewNodeID(peerID4Test))) {
+		t.Fatalf("net id should be banned")
+	}
+	// Score resets after banned
+	if s := ps.score(peerID4Test); s != peerScoreMax {
+		t.Fatalf("score should reset, got %v", s)
+	}
+
+	ps.elapse(banBaseDuration)
+	if ps.isBanned(peerID4Test) {
+		t.Fatalf("ban should expire")
+	}
+}
+
+func TestPeerScorer_BanDuration(t *testing.T) {
+	ps := newPeerScorer4Test(t, "")
+
+	ps.ban(peerID4Test, 0, "test")
+	if e := ps.bans[peerID4Test]; e.Count != 1 || e.Until.Sub(ps.clock) != banBaseDuration {
+		t.Fatalf("first ban error %+v", e)
+	}
+	ps.elapse(time.Hour)
+	ps.ban(peerID4Test, 0, "test")
+	if e := ps.bans[peerID4Test]; e.Count != 2 || e.Until.Sub(ps.clock) != 2*banBaseDuration {
+		t.Fatalf("second ban error %+v", e)
+	}
+	// Two days passed, the count decays to 0
+	ps.elapse(2 * banCountDecayInterval)
+	ps.ban(peerID4Test, 0, "test")
+	if e := ps.bans[peerID4Test]; e.Count != 1 || e.Until.Sub(ps.clock) != banBaseDuration {
+		t.Fatalf("decayed ban error %+v", e)
+	}
+	if d := banDuration(100); d != banMaxDuration {
+		t.Fatalf("ban duration should be capped, got %v", d)
+	}
+}
+
+func TestPeerScorer_Persist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ban_list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "ban_list.json")
+
+	ps := newPeerScorer4Test(t, file)
+	if err := ps.ban(peerID4Test, time.Hour, "test"); err != nil {
+		t.Fatal(err)
+	}
+	other := "zv0000000000000000000000000000000000000000000000000000000000005678"
+	ps.ban(other, time.Hour, "test")
+
+	// Reload from the file
+	ps2 := newPeerScorer4Test(t, file)
+	if !ps2.isBanned(peerID4Test) || !ps2.isBanned(other) {
+		t.Fatalf("ban list should be reloaded")
+	}
+	if len(ps2.banned()) != 2 {
+		t.Fatalf("banned size error")
+	}
+
+	if err := ps2.unban(peerID4Test); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps2.unban(peerID4Test); err == nil {
+		t.Fatalf("unban twice should fail")
+	}
+	ps3 := newPeerScorer4Test(t, file)
+	if ps3.isBanned(peerID4Test) || !ps3.isBanned(other) {
+		t.Fatalf("unban not persisted")
+	}
+}
+
+func TestPeerScorer_LoadInvalidEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ban_list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "ban_list.json")
+
+	until := time.Now().Add(time.Hour).Format(time.RFC3339)
+	data := `[null, {"id":""}, {"id":"zv12"}, {"id":"` + peerID4Test + `","until":"` + until + `"}]`
+	if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ps := newPeerScorer4Test(t, file)
+	if len(ps.bans) != 1 || !ps.isBanned(peerID4Test) {
+		t.Fatalf("only the valid entry should be loaded, got %v", len(ps.bans))
+	}
+	if !ps.isNetIDBanned(genNetID(*NewNodeID(peerID4Test))) {
+		t.Fatalf("net id of the loaded entry should be banned")
+	}
+}
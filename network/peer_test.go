@@ -19,6 +19,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"github.com/darren0718/zvchain/middleware/time"
+	"io/ioutil"
 	"math"
 	"testing"
 
@@ -46,6 +47,8 @@ func InitTestNetwork() bool {
 	PK := SK.GetPubKey()
 	ID := PK.GetAddress()
 	Seeds := make([]string, 0)
+	// Each network initialized in the tests uses its own node database
+	nodeDB, _ := ioutil.TempDir("", "d_nodes")
 	netCfg := NetworkConfig{IsSuper: false,
 		TestMode:        true,
 		NatAddr:         "",
@@ -57,6 +60,7 @@ func InitTestNetwork() bool {
 		SeedIDs:         Seeds,
 		PK:              PK.Hex(),
 		SK:              SK.Hex(),
+		NodeDBPath:      nodeDB,
 	}
 
 	err := Init(nil, nil, netCfg)
@@ -0,0 +1,170 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"sync"
+	"time"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/gogo/protobuf/proto"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// messageLimit limits the inbound messages of a code from each peer
+type messageLimit struct {
+	rate    float64 // Messages allowed per second
+	burst   float64 // Messages allowed at once
+	maxSize int     // Maximum size of the message body
+}
+
+// Default limits by the code range
+var (
+	consensusMessageLimit = messageLimit{rate: 100, burst: 200, maxSize: 4 * 1024 * 1024}
+	chainMessageLimit     = messageLimit{rate: 20, burst: 50, maxSize: 16 * 1024 * 1024}
+	// Shared by all the unknown codes, which can't be handled anyway
+	unknownMessageLimit = messageLimit{rate: 2, burst: 10, maxSize: 64 * 1024}
+)
+
+// Limits of the codes differ from the default of the range
+var messageLimits = map[uint32]messageLimit{
+	BlockInfoNotifyMsg:  {rate: 2, burst: 10, maxSize: 4 * 1024},
+	ReqBlock:            {rate: 5, burst: 20, maxSize: 4 * 1024},
+	ForkFindAncestorReq: {rate: 5, burst: 20, maxSize: 64 * 1024},
+	ForkChainSliceReq:   {rate: 5, burst: 20, maxSize: 4 * 1024},
+	TxSyncNotify:        {rate: 1, burst: 10, maxSize: 256 * 1024},
+	TxSyncReq:           {rate: 2, burst: 10, maxSize: 256 * 1024},
+	ReqBlockHeaders:     {rate: 5, burst: 20, maxSize: 4 * 1024},
+	ReqBlockBodies:      {rate: 20, burst: 50, maxSize: 64 * 1024},
+}
+
+const (
+	// Maximum size of the p2p messages other than data
+	maxP2PMessageSize = 64 * 1024
+	// Size of the fields wrapping the body in MsgData
+	msgDataOverhead = 256
+
+	// Range of the consensus message codes, see interface.go
+	consensusCodeMin = 1
+	consensusCodeMax = 9999
+
+	rateLimiterCacheSize = 10000
+	msgDataCodeField     = 9 // Field number of MessageCode in MsgData
+
+	// Code of the bucket shared by all the unknown codes of a peer, so that a peer can't
+	// dodge the limit or evict the buckets of other peers by rotating the codes
+	unknownMessageCode = 0
+)
+
+// messageLimitOf returns the limit of the message code
+func messageLimitOf(code uint32) messageLimit {
+	if !isKnownMessageCode(code) {
+		return unknownMessageLimit
+	}
+	if l, ok := messageLimits[code]; ok {
+		return l
+	}
+	if code >= consensusCodeMin && code <= consensusCodeMax {
+		return consensusMessageLimit
+	}
+	return chainMessageLimit
+}
+
+// tokenBucket refills rate tokens per second up to burst, each message takes one
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) take(l messageLimit, now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+type rateKey struct {
+	peer NodeID
+	code uint32
+}
+
+// rateLimiter limits the inbound message rate per peer and message code
+type rateLimiter struct {
+	buckets *lru.Cache // Key is rateKey, value is *tokenBucket
+	mutex   sync.Mutex
+	now     func() time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		buckets: common.MustNewLRUCache(rateLimiterCacheSize),
+		now:     time.Now,
+	}
+}
+
+// allow returns whether the message of the code from the peer is allowed currently
+func (rl *rateLimiter) allow(peer NodeID, code uint32) bool {
+	l := messageLimitOf(code)
+	if !isKnownMessageCode(code) {
+		code = unknownMessageCode
+	}
+	key := rateKey{peer: peer, code: code}
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	now := rl.now()
+	v, ok := rl.buckets.Get(key)
+	if !ok {
+		v = &tokenBucket{tokens: l.burst, last: now}
+		rl.buckets.Add(key, v)
+	}
+	return v.(*tokenBucket).take(l, now)
+}
+
+// peekMessageCode reads MessageCode of the encoded MsgData without decoding the whole message
+func peekMessageCode(data []byte) (uint32, bool) {
+	for len(data) > 0 {
+		field, wire, value, n := nextField(data)
+		if n == 0 {
+			return 0, false
+		}
+		if field == msgDataCodeField && wire == proto.WireVarint {
+			v, _ := proto.DecodeVarint(value)
+			return uint32(v), true
+		}
+		data = data[n:]
+	}
+	return 0, false
+}
+
+// checkMessageSize checks the size of the encoded message before decoding, returns the
+// message code and whether the size is allowed
+func checkMessageSize(msgType MessageType, data []byte) (uint32, bool) {
+	if msgType != MessageType_MessageData {
+		return 0, len(data) <= maxP2PMessageSize
+	}
+	code, ok := peekMessageCode(data)
+	if !ok {
+		return 0, len(data) <= chainMessageLimit.maxSize+msgDataOverhead
+	}
+	return code, len(data) <= messageLimitOf(code).maxSize+msgDataOverhead
+}
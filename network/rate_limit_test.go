@@ -0,0 +1,130 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+func TestRateLimiter(t *testing.T) {
+	rl := newRateLimiter()
+	clock := time.Now()
+	rl.now = func() time.Time { return clock }
+
+	a := *NewNodeID("zv1234")
+	b := *NewNodeID("zv5678")
+	l := messageLimitOf(TxSyncNotify)
+	for i := 0; i < int(l.burst); i++ {
+		if !rl.allow(a, TxSyncNotify) {
+			t.Fatalf("message %v should be allowed in burst", i)
+		}
+	}
+	if rl.allow(a, TxSyncNotify) {
+		t.Fatalf("message should be limited after burst")
+	}
+	// Other peers and codes are not affected
+	if !rl.allow(b, TxSyncNotify) || !rl.allow(a, CastVerifyMsg) {
+		t.Fatalf("limit should be per peer and code")
+	}
+	// Refilled over time
+	clock = clock.Add(time.Second)
+	if !rl.allow(a, TxSyncNotify) {
+		t.Fatalf("message should be allowed after refilled")
+	}
+	if rl.allow(a, TxSyncNotify) {
+		t.Fatalf("only one token refilled in a second")
+	}
+}
+
+func TestRateLimiter_UnknownCodes(t *testing.T) {
+	rl := newRateLimiter()
+	clock := time.Now()
+	rl.now = func() time.Time { return clock }
+
+	a := *NewNodeID("zv1234")
+	allowed := 0
+	for code := uint32(20000); code < 21000; code++ {
+		if rl.allow(a, code) {
+			allowed++
+		}
+	}
+	if allowed != int(unknownMessageLimit.burst) {
+		t.Fatalf("unknown codes should share one bucket, allowed %v", allowed)
+	}
+	if rl.buckets.Len() != 1 {
+		t.Fatalf("expect one bucket for unknown codes, got %v", rl.buckets.Len())
+	}
+	if !rl.allow(a, TxSyncNotify) {
+		t.Fatalf("known codes should not be affected")
+	}
+}
+
+func TestMessageLimitOf(t *testing.T) {
+	if messageLimitOf(CastVerifyMsg) != consensusMessageLimit {
+		t.Fatalf("consensus limit error")
+	}
+	if messageLimitOf(BlockResponseMsg) != chainMessageLimit {
+		t.Fatalf("chain limit error")
+	}
+	if messageLimitOf(TxSyncNotify) != messageLimits[TxSyncNotify] {
+		t.Fatalf("code limit error")
+	}
+	if messageLimitOf(9000) != unknownMessageLimit || messageLimitOf(20000) != unknownMessageLimit {
+		t.Fatalf("unknown code limit error")
+	}
+}
+
+func TestCheckMessageSize(t *testing.T) {
+	encode := func(code uint32, size int) []byte {
+		msg := &MsgData{
+			DataType:    DataType_DataGlobal,
+			GroupID:     "group",
+			Expiration:  100,
+			MessageID:   1,
+			SrcNodeID:   bytes.Repeat([]byte{1}, 32),
+			Data:        make([]byte, size),
+			MessageCode: code,
+			MessageInfo: 1,
+		}
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	data := encode(BlockInfoNotifyMsg, 100)
+	if code, ok := peekMessageCode(data); !ok || code != BlockInfoNotifyMsg {
+		t.Fatalf("peek message code error %v %v", code, ok)
+	}
+	if _, ok := checkMessageSize(MessageType_MessageData, data); !ok {
+		t.Fatalf("small message should be allowed")
+	}
+	data = encode(BlockInfoNotifyMsg, messageLimits[BlockInfoNotifyMsg].maxSize+1024)
+	if code, ok := checkMessageSize(MessageType_MessageData, data); ok || code != BlockInfoNotifyMsg {
+		t.Fatalf("oversize message should be refused")
+	}
+	if _, ok := checkMessageSize(MessageType_MessagePing, make([]byte, maxP2PMessageSize+1)); ok {
+		t.Fatalf("oversize ping should be refused")
+	}
+	if _, ok := peekMessageCode([]byte{0xff}); ok {
+		t.Fatalf("peek bad data should fail")
+	}
+}
@@ -49,6 +49,12 @@ func (s *Server) Send(id string, msg Message) error {
 	}
 	nID := NewNodeID(id)
 	if nID != nil {
+		if caps := requiredCapabilities(msg.Code); caps != 0 {
+			p := s.netCore.peerManager.peerByID(*nID)
+			if p == nil || !p.supports(caps) {
+				return errPeerLacksCapability
+			}
+		}
 		go s.netCore.sendToNode(*nID, nil, bytes, msg.Code)
 	}
 
@@ -184,31 +190,7 @@ func (s *Server) handleMessageInner(message *Message, from string) {
 			Logger.Errorf("consensusHandler handle error:%s", err.Error())
 		}
 	} else {
-		topicID := ""
-		switch code {
-		case TxSyncNotify:
-			topicID = notify.TxSyncNotify
-		case TxSyncReq:
-			topicID = notify.TxSyncReq
-		case TxSyncResponse:
-			topicID = notify.TxSyncResponse
-		case BlockInfoNotifyMsg:
-			topicID = notify.BlockInfoNotify
-		case ReqBlock:
-			topicID = notify.BlockReq
-		case BlockResponseMsg:
-			topicID = notify.BlockResponse
-		case NewBlockMsg:
-			topicID = notify.NewBlock
-		case ForkFindAncestorResponse:
-			topicID = notify.ForkFindAncestorResponse
-		case ForkFindAncestorReq:
-			topicID = notify.ForkFindAncestorReq
-		case ForkChainSliceReq:
-			topicID = notify.ForkChainSliceReq
-		case ForkChainSliceResponse:
-			topicID = notify.ForkChainSliceResponse
-		}
+		topicID := chainMessageTopic(code)
 		if topicID != "" {
 			msg := newNotifyMessage(message, from)
 			notify.BUS.PublishWithRecover(topicID, msg)
@@ -220,6 +202,43 @@ func (s *Server) handleMessageInner(message *Message, from string) {
 	}
 }
 
+// chainMessageTopic returns the notify topic of the chain message code, empty if unknown
+func chainMessageTopic(code uint32) string {
+	switch code {
+	case TxSyncNotify:
+		return notify.TxSyncNotify
+	case TxSyncReq:
+		return notify.TxSyncReq
+	case TxSyncResponse:
+		return notify.TxSyncResponse
+	case BlockInfoNotifyMsg:
+		return notify.BlockInfoNotify
+	case ReqBlock:
+		return notify.BlockReq
+	case BlockResponseMsg:
+		return notify.BlockResponse
+	case NewBlockMsg:
+		return notify.NewBlock
+	case ForkFindAncestorResponse:
+		return notify.ForkFindAncestorResponse
+	case ForkFindAncestorReq:
+		return notify.ForkFindAncestorReq
+	case ForkChainSliceReq:
+		return notify.ForkChainSliceReq
+	case ForkChainSliceResponse:
+		return notify.ForkChainSliceResponse
+	case ReqBlockHeaders:
+		return notify.BlockHeadersReq
+	case BlockHeadersResponse:
+		return notify.BlockHeadersResponse
+	case ReqBlockBodies:
+		return notify.BlockBodiesReq
+	case BlockBodiesResponse:
+		return notify.BlockBodiesResponse
+	}
+	return ""
+}
+
 func marshalMessage(m Message) ([]byte, error) {
 	message := tas_middleware_pb.Message{Code: &m.Code, Body: m.Body}
 	return proto.Marshal(&message)
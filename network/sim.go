@@ -0,0 +1,378 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/darren0718/zvchain/log"
+	"github.com/darren0718/zvchain/middleware/notify"
+)
+
+// LinkConfig is the condition of the link between two simulated nodes
+type LinkConfig struct {
+	Latency   time.Duration // One-way delay of each message
+	Loss      float64       // Probability of dropping a message, in [0, 1]
+	Bandwidth int           // Bytes per second, unlimited if 0
+}
+
+type simLinkKey struct {
+	from, to string
+}
+
+type simLink struct {
+	LinkConfig
+	busyUntil time.Time // The link is sending the previous messages until then
+}
+
+// SimNetwork is an in-memory router connecting the simulated nodes, used to test the
+// behaviors under latency, loss and partitions. All the nodes are connected to each other
+// unless partitioned, and the link conditions can be changed at runtime
+type SimNetwork struct {
+	mutex       sync.Mutex
+	nodes       map[string]*SimNode
+	links       map[simLinkKey]*simLink
+	defaultLink LinkConfig
+	partitions  map[string]int // Partition index of the nodes, nil if not partitioned
+	rand        *rand.Rand
+
+	inflight  sync.WaitGroup
+	delivered uint64
+	dropped   uint64
+}
+
+// NewSimNetwork creates the simulated network without any node
+func NewSimNetwork() *SimNetwork {
+	if Logger == nil {
+		Logger = log.P2PLogger
+	}
+	return &SimNetwork{
+		nodes: make(map[string]*SimNode),
+		links: make(map[simLinkKey]*simLink),
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// AddNode joins the node to the network. The consensus messages are delivered to the handler
+// and the chain messages are published to the bus, either of which may be nil
+func (sn *SimNetwork) AddNode(id string, handler MsgHandler, bus *notify.Bus) *SimNode {
+	sn.mutex.Lock()
+	defer sn.mutex.Unlock()
+	n := &SimNode{
+		ID:           id,
+		net:          sn,
+		handler:      handler,
+		bus:          bus,
+		capabilities: ^uint32(0),
+		groups:       make(map[string][]string),
+	}
+	sn.nodes[id] = n
+	return n
+}
+
+// RemoveNode disconnects the node from the network, the messages in flight to it are dropped
+func (sn *SimNetwork) RemoveNode(id string) {
+	sn.mutex.Lock()
+	defer sn.mutex.Unlock()
+	delete(sn.nodes, id)
+}
+
+// Node returns the node of the id, nil if not found
+func (sn *SimNetwork) Node(id string) *SimNode {
+	sn.mutex.Lock()
+	defer sn.mutex.Unlock()
+	return sn.nodes[id]
+}
+
+// SetDefaultLink sets the condition of the links not configured by SetLink
+func (sn *SimNetwork) SetDefaultLink(cfg LinkConfig) {
+	sn.mutex.Lock()
+	defer sn.mutex.Unlock()
+	sn.defaultLink = cfg
+}
+
+// SetLink sets the condition of the links between the two nodes in both directions
+func (sn *SimNetwork) SetLink(a, b string, cfg LinkConfig) {
+	sn.mutex.Lock()
+	defer sn.mutex.Unlock()
+	for _, key := range []simLinkKey{{a, b}, {b, a}} {
+		if l, ok := sn.links[key]; ok {
+			l.LinkConfig = cfg
+		} else {
+			sn.links[key] = &simLink{LinkConfig: cfg}
+		}
+	}
+}
+
+// Partition splits the nodes into the groups, nodes in different groups can't reach each other.
+// Nodes not in any group are isolated from all the others
+func (sn *SimNetwork) Partition(groups ...[]string) {
+	sn.mutex.Lock()
+	defer sn.mutex.Unlock()
+	sn.partitions = make(map[string]int)
+	for i, g := range groups {
+		for _, id := range g {
+			sn.partitions[id] = i + 1
+		}
+	}
+}
+
+// Heal removes the partitions
+func (sn *SimNetwork) Heal() {
+	sn.mutex.Lock()
+	defer sn.mutex.Unlock()
+	sn.partitions = nil
+}
+
+// Reachable returns whether the message from a can be delivered to b currently
+func (sn *SimNetwork) Reachable(a, b string) bool {
+	sn.mutex.Lock()
+	defer sn.mutex.Unlock()
+	return sn.reachableLocked(a, b)
+}
+
+func (sn *SimNetwork) reachableLocked(a, b string) bool {
+	if sn.nodes[a] == nil || sn.nodes[b] == nil {
+		return false
+	}
+	if sn.partitions == nil {
+		return true
+	}
+	pa, pb := sn.partitions[a], sn.partitions[b]
+	return pa != 0 && pa == pb
+}
+
+// Wait blocks until all the messages in flight are delivered or dropped, including the
+// messages sent by the MsgHandlers meanwhile. The bus runs the subscribers asynchronously,
+// which aren't waited
+func (sn *SimNetwork) Wait() {
+	sn.inflight.Wait()
+}
+
+// Delivered returns the number of the messages delivered
+func (sn *SimNetwork) Delivered() uint64 {
+	return atomic.LoadUint64(&sn.delivered)
+}
+
+// Dropped returns the number of the messages dropped for loss, partitions or missing nodes
+func (sn *SimNetwork) Dropped() uint64 {
+	return atomic.LoadUint64(&sn.dropped)
+}
+
+func (sn *SimNetwork) link(from, to string) *simLink {
+	key := simLinkKey{from, to}
+	l, ok := sn.links[key]
+	if !ok {
+		l = &simLink{LinkConfig: sn.defaultLink}
+		sn.links[key] = l
+	}
+	return l
+}
+
+// send schedules the delivery of the message by the condition of the link. The message
+// is dropped if lost or the nodes are unreachable at either the sending or the delivery time
+func (sn *SimNetwork) send(from, to string, msg Message) error {
+	sn.mutex.Lock()
+	target := sn.nodes[to]
+	if target == nil {
+		sn.mutex.Unlock()
+		return fmt.Errorf("node %v not found", to)
+	}
+	if caps := requiredCapabilities(msg.Code); caps != 0 && target.capabilities&caps != caps {
+		sn.mutex.Unlock()
+		return errPeerLacksCapability
+	}
+	l := sn.link(from, to)
+	if !sn.reachableLocked(from, to) || (l.Loss > 0 && sn.rand.Float64() < l.Loss) {
+		sn.mutex.Unlock()
+		atomic.AddUint64(&sn.dropped, 1)
+		return nil
+	}
+	now := time.Now()
+	sent := now
+	if l.Bandwidth > 0 {
+		if l.busyUntil.After(sent) {
+			sent = l.busyUntil
+		}
+		sent = sent.Add(time.Duration(len(msg.Body)) * time.Second / time.Duration(l.Bandwidth))
+		l.busyUntil = sent
+	}
+	delay := sent.Sub(now) + l.Latency
+	sn.inflight.Add(1)
+	sn.mutex.Unlock()
+
+	body := make([]byte, len(msg.Body))
+	copy(body, msg.Body)
+	msg.Body = body
+	time.AfterFunc(delay, func() {
+		defer sn.inflight.Done()
+		sn.mutex.Lock()
+		ok := sn.reachableLocked(from, to)
+		target := sn.nodes[to]
+		sn.mutex.Unlock()
+		if !ok {
+			atomic.AddUint64(&sn.dropped, 1)
+			return
+		}
+		atomic.AddUint64(&sn.delivered, 1)
+		target.handle(from, msg)
+	})
+	return nil
+}
+
+// SimNode is a node in the simulated network, which implements Network
+type SimNode struct {
+	ID string
+
+	net          *SimNetwork
+	handler      MsgHandler
+	bus          *notify.Bus
+	capabilities uint32
+
+	mutex     sync.Mutex
+	groups    map[string][]string
+	proposers []*Proposer
+}
+
+// SetCapabilities sets the capabilities the node advertises, all by default
+func (n *SimNode) SetCapabilities(caps uint32) {
+	n.net.mutex.Lock()
+	defer n.net.mutex.Unlock()
+	n.capabilities = caps
+}
+
+func (n *SimNode) handle(from string, msg Message) {
+	if msg.Code < P2PMessageCodeBase {
+		if n.handler == nil {
+			return
+		}
+		if err := n.handler.Handle(from, msg); err != nil {
+			Logger.Errorf("sim node %v handle message %v from %v error:%v", n.ID, msg.Code, from, err)
+		}
+		return
+	}
+	if topicID := chainMessageTopic(msg.Code); topicID != "" && n.bus != nil {
+		n.bus.PublishWithRecover(topicID, newNotifyMessage(&msg, from))
+	}
+}
+
+// peers returns the other nodes currently in the network
+func (n *SimNode) peers() []string {
+	n.net.mutex.Lock()
+	defer n.net.mutex.Unlock()
+	ids := make([]string, 0, len(n.net.nodes))
+	for id := range n.net.nodes {
+		if id != n.ID {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (n *SimNode) sendAll(ids []string, msg Message) error {
+	for _, id := range ids {
+		if id == n.ID {
+			continue
+		}
+		if err := n.net.send(n.ID, id, msg); err != nil && err != errPeerLacksCapability {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *SimNode) Send(id string, msg Message) error {
+	if id == n.ID {
+		n.net.inflight.Add(1)
+		go func() {
+			defer n.net.inflight.Done()
+			n.handle(n.ID, msg)
+		}()
+		return nil
+	}
+	return n.net.send(n.ID, id, msg)
+}
+
+func (n *SimNode) SpreadAmongGroup(groupID string, msg Message) error {
+	n.mutex.Lock()
+	members, ok := n.groups[groupID]
+	n.mutex.Unlock()
+	if !ok {
+		return fmt.Errorf("group %v not found", groupID)
+	}
+	return n.sendAll(members, msg)
+}
+
+func (n *SimNode) SpreadToGroup(groupID string, groupMembers []string, msg Message, digest MsgDigest) error {
+	return n.sendAll(groupMembers, msg)
+}
+
+func (n *SimNode) TransmitToNeighbor(msg Message, blacklist []string) error {
+	excluded := make(map[string]bool, len(blacklist))
+	for _, id := range blacklist {
+		excluded[id] = true
+	}
+	ids := make([]string, 0)
+	for _, id := range n.peers() {
+		if !excluded[id] {
+			ids = append(ids, id)
+		}
+	}
+	return n.sendAll(ids, msg)
+}
+
+func (n *SimNode) Broadcast(msg Message) error {
+	return n.sendAll(n.peers(), msg)
+}
+
+// ConnInfo returns the nodes reachable currently
+func (n *SimNode) ConnInfo() []Conn {
+	conns := make([]Conn, 0)
+	for _, id := range n.peers() {
+		if n.net.Reachable(n.ID, id) {
+			conns = append(conns, Conn{ID: id})
+		}
+	}
+	return conns
+}
+
+func (n *SimNode) BuildGroupNet(groupID string, members []string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.groups[groupID] = members
+}
+
+func (n *SimNode) DissolveGroupNet(groupID string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	delete(n.groups, groupID)
+}
+
+func (n *SimNode) BuildProposerGroupNet(proposers []*Proposer) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.proposers = proposers
+}
+
+func (n *SimNode) AddProposers(proposers []*Proposer) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.proposers = append(n.proposers, proposers...)
+}
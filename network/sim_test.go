@@ -0,0 +1,170 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darren0718/zvchain/middleware/notify"
+)
+
+type simHandler4Test struct {
+	mutex sync.Mutex
+	msgs  []Message
+	from  []string
+}
+
+func (h *simHandler4Test) Handle(sourceID string, msg Message) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.msgs = append(h.msgs, msg)
+	h.from = append(h.from, sourceID)
+	return nil
+}
+
+func (h *simHandler4Test) count() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return len(h.msgs)
+}
+
+func newSimNetwork4Test(ids ...string) (*SimNetwork, map[string]*simHandler4Test) {
+	sn := NewSimNetwork()
+	handlers := make(map[string]*simHandler4Test)
+	for _, id := range ids {
+		handlers[id] = &simHandler4Test{}
+		sn.AddNode(id, handlers[id], notify.NewBus())
+	}
+	return sn, handlers
+}
+
+func TestSimNetwork_Deliver(t *testing.T) {
+	sn, handlers := newSimNetwork4Test("a", "b", "c")
+
+	var received []string
+	var mutex sync.Mutex
+	sn.Node("b").bus.Subscribe(notify.NewBlock, func(msg notify.Message) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		received = append(received, notify.AsDefault(msg).Source())
+		return nil
+	})
+
+	if err := sn.Node("a").Send("b", Message{Code: CastVerifyMsg, Body: []byte{1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sn.Node("a").Broadcast(Message{Code: NewBlockMsg, Body: []byte{2}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sn.Node("a").Send("d", Message{Code: CastVerifyMsg}); err == nil {
+		t.Fatalf("should be error sending to the unknown node")
+	}
+	sn.Wait()
+
+	if handlers["b"].count() != 1 || handlers["b"].from[0] != "a" || handlers["c"].count() != 0 {
+		t.Fatalf("consensus message should be delivered to the handler")
+	}
+	// The bus handles the messages asynchronously
+	for i := 0; i < 100; i++ {
+		mutex.Lock()
+		n := len(received)
+		mutex.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(received) != 1 || received[0] != "a" {
+		t.Fatalf("chain message should be published to the bus, got %v", received)
+	}
+	if sn.Delivered() != 3 {
+		t.Fatalf("delivered count error %v", sn.Delivered())
+	}
+
+	sn.Node("a").BuildGroupNet("g", []string{"a", "b", "c"})
+	if err := sn.Node("a").SpreadAmongGroup("g", Message{Code: CastVerifyMsg}); err != nil {
+		t.Fatal(err)
+	}
+	sn.Wait()
+	if handlers["a"].count() != 0 || handlers["b"].count() != 2 || handlers["c"].count() != 1 {
+		t.Fatalf("group message should be sent to the other members")
+	}
+}
+
+func TestSimNetwork_PartitionAndHeal(t *testing.T) {
+	sn, handlers := newSimNetwork4Test("a", "b", "c", "d")
+	sn.SetDefaultLink(LinkConfig{Latency: 20 * time.Millisecond})
+	sn.Partition([]string{"a", "b"}, []string{"c"})
+
+	msg := Message{Code: CastVerifyMsg, Body: []byte{1}}
+	sn.Node("a").Broadcast(msg)
+	sn.Wait()
+	if handlers["b"].count() != 1 || handlers["c"].count() != 0 || handlers["d"].count() != 0 {
+		t.Fatalf("message shouldn't cross the partition")
+	}
+	if len(sn.Node("c").ConnInfo()) != 0 || len(sn.Node("a").ConnInfo()) != 1 {
+		t.Fatalf("conn info should be the reachable nodes")
+	}
+
+	// Messages in flight are dropped if partitioned before delivered
+	sn.Heal()
+	sn.Node("a").Send("c", msg)
+	sn.Partition([]string{"a"}, []string{"c"})
+	sn.Wait()
+	if handlers["c"].count() != 0 {
+		t.Fatalf("message in flight should be dropped")
+	}
+
+	sn.Heal()
+	sn.Node("a").Broadcast(msg)
+	sn.Wait()
+	if handlers["b"].count() != 2 || handlers["c"].count() != 1 || handlers["d"].count() != 1 {
+		t.Fatalf("message should be delivered after healed")
+	}
+	if sn.Dropped() != 3 {
+		t.Fatalf("dropped count error %v", sn.Dropped())
+	}
+}
+
+func TestSimNetwork_LinkCondition(t *testing.T) {
+	sn, handlers := newSimNetwork4Test("a", "b", "c")
+	sn.SetLink("a", "b", LinkConfig{Latency: 50 * time.Millisecond, Bandwidth: 10000})
+	sn.SetLink("a", "c", LinkConfig{Loss: 1})
+
+	begin := time.Now()
+	body := make([]byte, 1000)
+	for i := 0; i < 3; i++ {
+		sn.Node("a").Send("b", Message{Code: CastVerifyMsg, Body: body})
+	}
+	sn.Node("a").Send("c", Message{Code: CastVerifyMsg, Body: body})
+	sn.Wait()
+	// 3 messages of 1000 bytes take 300ms on the link of 10000 bytes per second
+	if cost := time.Since(begin); cost < 350*time.Millisecond {
+		t.Fatalf("latency and bandwidth not applied, cost %v", cost)
+	}
+	if handlers["b"].count() != 3 || handlers["c"].count() != 0 {
+		t.Fatalf("lossy link should drop the message")
+	}
+
+	sn.Node("b").SetCapabilities(CapCompression)
+	if err := sn.Node("a").Send("b", Message{Code: ReqBlockHeaders}); err != errPeerLacksCapability {
+		t.Fatalf("node lacking capability shouldn't be sent, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+// Transports selectable by the config
+const (
+	TransportCore = "core" // The prebuilt libp2pcore bound through cgo
+	TransportTCP  = "tcp"  // The pure-Go TCP transport
+)
+
+// transportHandler receives the session events from the transport
+type transportHandler interface {
+	onRecved(netID uint64, session uint32, data []byte)
+	onAccepted(netID uint64, session uint32, p2pType uint32, ip string, port uint16)
+	onConnected(netID uint64, session uint32, p2pType uint32)
+	onDisconnected(netID uint64, session uint32, p2pCode uint32)
+	onSendWaited(netID uint64, session uint32)
+}
+
+// transport is the session-oriented connection layer under the peers.
+// Sessions are identified by the non-zero session id and the remote node by its net id.
+// All the operations are asynchronous, the results are reported to the handler
+type transport interface {
+	config(netID uint64, handler transportHandler)
+	proxy(ip string, port uint16)
+	listen(ip string, port uint16)
+	close()
+	connect(netID uint64, ip string, port uint16)
+	shutdown(session uint32)
+	send(session uint32, data []byte)
+}
+
+var p2pTransport transport
+
+// newTransport returns the transport of the given name.
+// The TCP transport is always used if the core is not built in
+func newTransport(name string) transport {
+	if name == TransportTCP || !coreTransportAvailable {
+		return newTCPTransport()
+	}
+	return newCoreTransport()
+}
+
+func P2PConfig(id uint64) {
+	if p2pTransport != nil {
+		p2pTransport.config(id, netCore)
+	}
+}
+
+func P2PProxy(ip string, port uint16) {
+	if p2pTransport != nil {
+		p2pTransport.proxy(ip, port)
+	}
+}
+
+func P2PListen(ip string, port uint16) {
+	if p2pTransport != nil {
+		p2pTransport.listen(ip, port)
+	}
+}
+
+func P2PClose() {
+	if p2pTransport != nil {
+		p2pTransport.close()
+	}
+}
+
+func P2PConnect(id uint64, ip string, port uint16) {
+	if p2pTransport != nil {
+		p2pTransport.connect(id, ip, port)
+	}
+}
+
+func P2PShutdown(session uint32) {
+	if p2pTransport != nil {
+		p2pTransport.shutdown(session)
+	}
+}
+
+func P2PSend(session uint32, data []byte) {
+	if p2pTransport != nil {
+		p2pTransport.send(session, data)
+	}
+}
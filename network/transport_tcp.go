@@ -0,0 +1,441 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/darren0718/zvchain/common"
+)
+
+const (
+	tcpFrameHeadSize = 4
+	tcpMaxFrameSize  = 16*1024*1024 + PacketHeadSize
+
+	// The handshake is the hello of the magic, the public key and a random nonce of the sender,
+	// followed by the signature of the nonce received, which proves the sender holds the key
+	tcpHandshakeMagic uint32 = 0x5a565033
+	tcpNonceSize             = 32
+	tcpHelloSize             = 4 + common.PubKeyLength + tcpNonceSize
+
+	// Max bytes queued to send in a session, the session is disconnected if exceeded
+	tcpMaxQueueBytes = 4 * tcpMaxFrameSize
+
+	// TCP connections are always direct
+	tcpP2PType uint32 = 0
+)
+
+// Disconnect codes reported by the TCP transport
+const (
+	tcpCodeClosed uint32 = iota
+	tcpCodeConnectFail
+	tcpCodeHandshakeFail
+	tcpCodeIOError
+	tcpCodeQueueOverflow
+)
+
+// tcpSession is a TCP connection with a remote node, whose frames are sent by a dedicated routine
+type tcpSession struct {
+	id       uint32
+	netID    uint64
+	conn     net.Conn
+	mutex    sync.Mutex
+	queue    [][]byte
+	queued   int  // Bytes in the queue
+	overflow bool // Set if disconnected for the queue overflow
+	signal   chan struct{}
+	closed   chan struct{}
+	once     sync.Once
+}
+
+func newTCPSession(id uint32, netID uint64, conn net.Conn) *tcpSession {
+	return &tcpSession{
+		id:     id,
+		netID:  netID,
+		conn:   conn,
+		queue:  make([][]byte, 0),
+		signal: make(chan struct{}, 1),
+		closed: make(chan struct{}),
+	}
+}
+
+// push adds the data to the send queue without blocking. It returns false if the queue is full,
+// in which case the remote doesn't read fast enough and the session should be closed
+func (s *tcpSession) push(data []byte) bool {
+	s.mutex.Lock()
+	if s.queued+len(data) > tcpMaxQueueBytes {
+		s.overflow = true
+		s.mutex.Unlock()
+		return false
+	}
+	s.queue = append(s.queue, data)
+	s.queued += len(data)
+	s.mutex.Unlock()
+
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+func (s *tcpSession) popAll() [][]byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	q := s.queue
+	s.queue = make([][]byte, 0)
+	s.queued = 0
+	return q
+}
+
+func (s *tcpSession) isOverflow() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.overflow
+}
+
+func (s *tcpSession) close() {
+	s.once.Do(func() {
+		close(s.closed)
+		s.conn.Close()
+	})
+}
+
+// tcpTransport implements the transport over TCP with length-prefixed frames.
+// Each send call is delivered to the remote as a whole frame
+type tcpTransport struct {
+	netID       uint64
+	key         *common.PrivateKey // Authenticates the node in the handshake
+	handler     transportHandler
+	listener    net.Listener
+	sessions    map[uint32]*tcpSession
+	nextSession uint32
+	mutex       sync.RWMutex
+}
+
+func newTCPTransport() *tcpTransport {
+	return &tcpTransport{
+		sessions: make(map[uint32]*tcpSession),
+	}
+}
+
+func (t *tcpTransport) config(netID uint64, handler transportHandler) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.netID = netID
+	t.handler = handler
+}
+
+// setKey sets the key of the node, whose address is the node id
+func (t *tcpTransport) setKey(key *common.PrivateKey) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.key = key
+}
+
+func (t *tcpTransport) proxy(ip string, port uint16) {
+	Logger.Errorf("tcp transport doesn't support nat traversal, proxy %v:%v ignored", ip, port)
+}
+
+func (t *tcpTransport) listen(ip string, port uint16) {
+	l, err := net.Listen("tcp", net.JoinHostPort(ip, strconv.Itoa(int(port))))
+	if err != nil {
+		Logger.Errorf("tcp listen %v:%v error:%v", ip, port, err)
+		return
+	}
+	t.mutex.Lock()
+	if t.listener != nil {
+		t.listener.Close()
+	}
+	t.listener = l
+	t.mutex.Unlock()
+
+	go t.acceptLoop(l)
+}
+
+// listenAddr returns the address listening on, or nil if not listening
+func (t *tcpTransport) listenAddr() *net.TCPAddr {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	if t.listener == nil {
+		return nil
+	}
+	return t.listener.Addr().(*net.TCPAddr)
+}
+
+func (t *tcpTransport) acceptLoop(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			Logger.Infof("tcp listener %v closed:%v", l.Addr(), err)
+			return
+		}
+		go t.serve(conn, 0, true)
+	}
+}
+
+func (t *tcpTransport) close() {
+	t.mutex.Lock()
+	if t.listener != nil {
+		t.listener.Close()
+		t.listener = nil
+	}
+	sessions := t.sessions
+	t.sessions = make(map[uint32]*tcpSession)
+	t.mutex.Unlock()
+
+	for _, s := range sessions {
+		s.close()
+	}
+}
+
+func (t *tcpTransport) connect(netID uint64, ip string, port uint16) {
+	go func() {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip, strconv.Itoa(int(port))), connectTimeout)
+		if err != nil {
+			Logger.Infof("tcp connect %v:%v error:%v", ip, port, err)
+			t.getHandler().onDisconnected(netID, 0, tcpCodeConnectFail)
+			return
+		}
+		t.serve(conn, netID, false)
+	}()
+}
+
+func (t *tcpTransport) shutdown(session uint32) {
+	if s := t.session(session); s != nil {
+		s.close()
+	}
+}
+
+func (t *tcpTransport) send(session uint32, data []byte) {
+	s := t.session(session)
+	if s == nil {
+		return
+	}
+	// The caller reuses the buffer after sending
+	buf := make([]byte, len(data))
+	copy(buf, data)
+	if !s.push(buf) {
+		Logger.Infof("tcp session %v send queue overflow, disconnect", s.id)
+		s.close()
+	}
+}
+
+func (t *tcpTransport) session(id uint32) *tcpSession {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.sessions[id]
+}
+
+func (t *tcpTransport) getHandler() transportHandler {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.handler
+}
+
+func (t *tcpTransport) addSession(netID uint64, conn net.Conn) *tcpSession {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.nextSession++
+	if t.nextSession == 0 {
+		t.nextSession++
+	}
+	s := newTCPSession(t.nextSession, netID, conn)
+	t.sessions[s.id] = s
+	return s
+}
+
+func (t *tcpTransport) removeSession(id uint32) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.sessions, id)
+}
+
+// handshake authenticates both sides by their keys, and returns the net id of the remote derived
+// from its public key
+func (t *tcpTransport) handshake(conn net.Conn) (uint64, error) {
+	conn.SetDeadline(time.Now().Add(connectTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	t.mutex.RLock()
+	key := t.key
+	t.mutex.RUnlock()
+	if key == nil {
+		return 0, fmt.Errorf("no key to authenticate")
+	}
+
+	hello := make([]byte, tcpHelloSize)
+	binary.BigEndian.PutUint32(hello, tcpHandshakeMagic)
+	pub := key.GetPubKey()
+	copy(hello[4:], pub.Bytes())
+	nonce := hello[4+common.PubKeyLength:]
+	if _, err := rand.Read(nonce); err != nil {
+		return 0, err
+	}
+	if _, err := conn.Write(hello); err != nil {
+		return 0, err
+	}
+	remote := make([]byte, tcpHelloSize)
+	if _, err := io.ReadFull(conn, remote); err != nil {
+		return 0, err
+	}
+	if binary.BigEndian.Uint32(remote) != tcpHandshakeMagic {
+		return 0, fmt.Errorf("bad handshake magic")
+	}
+	remotePub, err := tcpPublicKey(remote[4 : 4+common.PubKeyLength])
+	if err != nil {
+		return 0, err
+	}
+	remoteNonce := remote[4+common.PubKeyLength:]
+	if bytes.Equal(remoteNonce, nonce) {
+		return 0, fmt.Errorf("nonce replayed")
+	}
+
+	sign, err := key.Sign(common.Sha256(remoteNonce))
+	if err != nil {
+		return 0, err
+	}
+	if _, err := conn.Write(sign.Bytes()); err != nil {
+		return 0, err
+	}
+	buf := make([]byte, common.SignLength)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return 0, err
+	}
+	remoteSign := common.BytesToSign(buf)
+	if remoteSign == nil || !remotePub.Verify(common.Sha256(nonce), remoteSign) {
+		return 0, fmt.Errorf("bad handshake signature")
+	}
+	return tcpNetID(remotePub), nil
+}
+
+// tcpPublicKey decodes the public key, which panics on the bad data
+func tcpPublicKey(b []byte) (pk *common.PublicKey, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pk, err = nil, fmt.Errorf("bad public key")
+		}
+	}()
+	return common.BytesToPublicKey(b), nil
+}
+
+// tcpNetID returns the net id of the node with the public key
+func tcpNetID(pub *common.PublicKey) uint64 {
+	var id NodeID
+	id.SetBytes(pub.GetAddress().Bytes())
+	return genNetID(id)
+}
+
+// serve does the handshake and then reads the frames until the connection closed.
+// The expected net id is checked for the connections initiated by us
+func (t *tcpTransport) serve(conn net.Conn, expectID uint64, accepted bool) {
+	handler := t.getHandler()
+	remoteID, err := t.handshake(conn)
+	if err == nil && !accepted && remoteID != expectID {
+		err = fmt.Errorf("net id not match, expect %v, got %v", expectID, remoteID)
+	}
+	if err != nil {
+		Logger.Infof("tcp handshake with %v error:%v", conn.RemoteAddr(), err)
+		conn.Close()
+		if !accepted {
+			handler.onDisconnected(expectID, 0, tcpCodeHandshakeFail)
+		}
+		return
+	}
+
+	s := t.addSession(remoteID, conn)
+	go t.writeLoop(s)
+
+	if accepted {
+		addr := conn.RemoteAddr().(*net.TCPAddr)
+		handler.onAccepted(remoteID, s.id, tcpP2PType, addr.IP.String(), uint16(addr.Port))
+	} else {
+		handler.onConnected(remoteID, s.id, tcpP2PType)
+	}
+
+	code := t.readLoop(s, handler)
+	s.close()
+	t.removeSession(s.id)
+	handler.onDisconnected(remoteID, s.id, code)
+}
+
+func (t *tcpTransport) readLoop(s *tcpSession, handler transportHandler) uint32 {
+	head := make([]byte, tcpFrameHeadSize)
+	for {
+		if _, err := io.ReadFull(s.conn, head); err != nil {
+			return t.closeCode(s, err)
+		}
+		size := binary.BigEndian.Uint32(head)
+		if size == 0 || size > tcpMaxFrameSize {
+			Logger.Infof("tcp session %v bad frame size %v", s.id, size)
+			return tcpCodeIOError
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(s.conn, data); err != nil {
+			return t.closeCode(s, err)
+		}
+		handler.onRecved(s.netID, s.id, data)
+	}
+}
+
+func (t *tcpTransport) closeCode(s *tcpSession, err error) uint32 {
+	if s.isOverflow() {
+		return tcpCodeQueueOverflow
+	}
+	select {
+	case <-s.closed:
+		return tcpCodeClosed
+	default:
+	}
+	if err == io.EOF {
+		return tcpCodeClosed
+	}
+	return tcpCodeIOError
+}
+
+// writeLoop sends the queued frames, and notifies the handler each time the queue drained
+func (t *tcpTransport) writeLoop(s *tcpSession) {
+	head := make([]byte, tcpFrameHeadSize)
+	for {
+		select {
+		case <-s.closed:
+			return
+		case <-s.signal:
+		}
+		frames := s.popAll()
+		if len(frames) == 0 {
+			continue
+		}
+		for _, data := range frames {
+			binary.BigEndian.PutUint32(head, uint32(len(data)))
+			buffers := net.Buffers{head, data}
+			if _, err := buffers.WriteTo(s.conn); err != nil {
+				Logger.Infof("tcp session %v write error:%v", s.id, err)
+				s.close()
+				return
+			}
+		}
+		t.getHandler().onSendWaited(s.netID, s.id)
+	}
+}
@@ -0,0 +1,258 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/log"
+)
+
+type transportEvent struct {
+	kind    string
+	netID   uint64
+	session uint32
+	code    uint32
+	data    []byte
+}
+
+type transportHandler4Test struct {
+	events  chan *transportEvent
+	skipped []*transportEvent // Events of other kinds received while waiting
+}
+
+func (h *transportHandler4Test) onRecved(netID uint64, session uint32, data []byte) {
+	h.events <- &transportEvent{kind: "recv", netID: netID, session: session, data: data}
+}
+
+func (h *transportHandler4Test) onAccepted(netID uint64, session uint32, p2pType uint32, ip string, port uint16) {
+	h.events <- &transportEvent{kind: "accept", netID: netID, session: session}
+}
+
+func (h *transportHandler4Test) onConnected(netID uint64, session uint32, p2pType uint32) {
+	h.events <- &transportEvent{kind: "connect", netID: netID, session: session}
+}
+
+func (h *transportHandler4Test) onDisconnected(netID uint64, session uint32, p2pCode uint32) {
+	h.events <- &transportEvent{kind: "disconnect", netID: netID, session: session, code: p2pCode}
+}
+
+// Send waited events are too many to check, just ignore them
+func (h *transportHandler4Test) onSendWaited(netID uint64, session uint32) {
+}
+
+// wait returns the next event of the given kind
+func (h *transportHandler4Test) wait(t *testing.T, kind string) *transportEvent {
+	for i, e := range h.skipped {
+		if e.kind == kind {
+			h.skipped = append(h.skipped[:i], h.skipped[i+1:]...)
+			return e
+		}
+	}
+	timer := time.NewTimer(5 * time.Second)
+	defer timer.Stop()
+	for {
+		select {
+		case e := <-h.events:
+			if e.kind == kind {
+				return e
+			}
+			h.skipped = append(h.skipped, e)
+		case <-timer.C:
+			t.Fatalf("wait %v event timeout", kind)
+			return nil
+		}
+	}
+}
+
+type tcpNode4Test struct {
+	netID     uint64
+	transport *tcpTransport
+	handler   *transportHandler4Test
+}
+
+func newTCPNode4Test() *tcpNode4Test {
+	if Logger == nil {
+		Logger = log.P2PLogger
+	}
+	key, err := common.GenerateKey("")
+	if err != nil {
+		panic(err)
+	}
+	pub := key.GetPubKey()
+	n := &tcpNode4Test{
+		netID:     tcpNetID(&pub),
+		transport: newTCPTransport(),
+		handler:   &transportHandler4Test{events: make(chan *transportEvent, 1024)},
+	}
+	n.transport.setKey(&key)
+	n.transport.config(n.netID, n.handler)
+	n.transport.listen("127.0.0.1", 0)
+	return n
+}
+
+func (n *tcpNode4Test) port() uint16 {
+	return uint16(n.transport.listenAddr().Port)
+}
+
+// connect connects a to b and returns the sessions of both sides
+func connectTCPNode4Test(t *testing.T, a, b *tcpNode4Test) (uint32, uint32) {
+	a.transport.connect(b.netID, "127.0.0.1", b.port())
+	ce := a.handler.wait(t, "connect")
+	if ce.netID != b.netID {
+		t.Fatalf("connected net id error, expect %v, got %v", b.netID, ce.netID)
+	}
+	ae := b.handler.wait(t, "accept")
+	if ae.netID != a.netID {
+		t.Fatalf("accepted net id error, expect %v, got %v", a.netID, ae.netID)
+	}
+	return ce.session, ae.session
+}
+
+func TestTCPTransport_SendRecv(t *testing.T) {
+	a := newTCPNode4Test()
+	b := newTCPNode4Test()
+	defer a.transport.close()
+	defer b.transport.close()
+
+	sa, sb := connectTCPNode4Test(t, a, b)
+
+	big := bytes.Repeat([]byte{7}, 2*1024*1024)
+	a.transport.send(sa, []byte("hello"))
+	a.transport.send(sa, big)
+	b.transport.send(sb, []byte("world"))
+
+	if e := b.handler.wait(t, "recv"); !bytes.Equal(e.data, []byte("hello")) || e.netID != a.netID {
+		t.Fatalf("recv data error")
+	}
+	if e := b.handler.wait(t, "recv"); !bytes.Equal(e.data, big) {
+		t.Fatalf("recv big data error, size %v", len(e.data))
+	}
+	if e := a.handler.wait(t, "recv"); !bytes.Equal(e.data, []byte("world")) || e.netID != b.netID {
+		t.Fatalf("recv data error")
+	}
+}
+
+func TestTCPTransport_Shutdown(t *testing.T) {
+	a := newTCPNode4Test()
+	b := newTCPNode4Test()
+	defer a.transport.close()
+	defer b.transport.close()
+
+	sa, sb := connectTCPNode4Test(t, a, b)
+	a.transport.shutdown(sa)
+
+	if e := a.handler.wait(t, "disconnect"); e.session != sa || e.code != tcpCodeClosed {
+		t.Fatalf("disconnect event error %+v", e)
+	}
+	if e := b.handler.wait(t, "disconnect"); e.session != sb || e.netID != a.netID {
+		t.Fatalf("disconnect event error %+v", e)
+	}
+	if a.transport.session(sa) != nil || b.transport.session(sb) != nil {
+		t.Fatalf("session should be removed")
+	}
+}
+
+func TestTCPTransport_ConnectFail(t *testing.T) {
+	a := newTCPNode4Test()
+	b := newTCPNode4Test()
+	defer a.transport.close()
+
+	// Net id not match
+	a.transport.connect(b.netID+1, "127.0.0.1", b.port())
+	if e := a.handler.wait(t, "disconnect"); e.netID != b.netID+1 || e.code != tcpCodeHandshakeFail {
+		t.Fatalf("disconnect event error %+v", e)
+	}
+
+	// Nobody listening
+	port := b.port()
+	b.transport.close()
+	a.transport.connect(b.netID, "127.0.0.1", port)
+	if e := a.handler.wait(t, "disconnect"); e.netID != b.netID || e.code != tcpCodeConnectFail {
+		t.Fatalf("disconnect event error %+v", e)
+	}
+}
+
+func TestTCPTransport_MultiNodes(t *testing.T) {
+	nodes := make([]*tcpNode4Test, 4)
+	for i := range nodes {
+		nodes[i] = newTCPNode4Test()
+		defer nodes[i].transport.close()
+	}
+	// Every node connects to the nodes after it and sends its index
+	for i, a := range nodes {
+		for _, b := range nodes[i+1:] {
+			sa, _ := connectTCPNode4Test(t, a, b)
+			a.transport.send(sa, []byte{byte(i)})
+		}
+	}
+	for i, b := range nodes {
+		for j := 0; j < i; j++ {
+			e := b.handler.wait(t, "recv")
+			if nodes[e.data[0]].netID != e.netID {
+				t.Fatalf("recv data from wrong node")
+			}
+		}
+	}
+}
+
+func TestTCPTransport_NetIDFromKey(t *testing.T) {
+	a := newTCPNode4Test()
+	b := newTCPNode4Test()
+	defer a.transport.close()
+	defer b.transport.close()
+
+	// The net id is derived from the key, not taken from the config
+	a.transport.config(12345, a.handler)
+	b.transport.connect(a.netID, "127.0.0.1", a.port())
+	if e := b.handler.wait(t, "connect"); e.netID != a.netID {
+		t.Fatalf("connected net id error, expect %v, got %v", a.netID, e.netID)
+	}
+	if e := a.handler.wait(t, "accept"); e.netID != b.netID {
+		t.Fatalf("accepted net id error, expect %v, got %v", b.netID, e.netID)
+	}
+
+	// No key to authenticate
+	c := newTCPNode4Test()
+	defer c.transport.close()
+	c.transport.setKey(nil)
+	c.transport.connect(a.netID, "127.0.0.1", a.port())
+	if e := c.handler.wait(t, "disconnect"); e.code != tcpCodeHandshakeFail {
+		t.Fatalf("disconnect event error %+v", e)
+	}
+}
+
+func TestTCPTransport_QueueOverflow(t *testing.T) {
+	a := newTCPNode4Test()
+	b := newTCPNode4Test()
+	defer a.transport.close()
+	defer b.transport.close()
+
+	sa, _ := connectTCPNode4Test(t, a, b)
+	s := a.transport.session(sa)
+	// Fill the queue as if the remote doesn't read
+	s.mutex.Lock()
+	s.queued = tcpMaxQueueBytes
+	s.mutex.Unlock()
+	a.transport.send(sa, []byte("overflow"))
+
+	if e := a.handler.wait(t, "disconnect"); e.session != sa || e.code != tcpCodeQueueOverflow {
+		t.Fatalf("disconnect event error %+v", e)
+	}
+}
@@ -29,11 +29,51 @@ type ChainConfig struct {
 
 	// zip002 implements the gas price calculation when multiplying
 	ZIP002 uint64
+
+	// zip003 caps the weight of any single address when selecting group candidates
+	ZIP003 uint64
+
+	// zip004 weights the group candidates by their stake and historical drop rate
+	ZIP004 uint64
+
+	// zip005 stores the contract abi at deploy time and checks the contract calls against it
+	ZIP005 uint64
+
+	// zip006 encodes the contract events canonically with the indexed topics and fills the receipt bloom
+	ZIP006 uint64
+
+	// zip007 enables the precompiled contracts callable from the contracts
+	ZIP007 uint64
+
+	// zip008 deploys the contracts to the addresses derived from the salt and the code if asked
+	ZIP008 uint64
+
+	// zip009 records the gas used, the effective gas price and the fee of each transaction in the receipt
+	ZIP009 uint64
+
+	// zip010 limits the storage written and the events emitted by each contract call
+	ZIP010 uint64
+
+	// Limits of the bytes of the storage written and the number of the events emitted by each
+	// contract call since zip010, zero means no limit
+	CallMaxStorageBytes uint64
+	CallMaxEvents       uint64
 }
 
 var config = &ChainConfig{
-	ZIP001: 931588, // effect at : 2019-10-30 14:00:00
-	ZIP002: 960388, // effect at : 2019-10-31 14:00:00
+	ZIP001: 931588,           // effect at : 2019-10-30 14:00:00
+	ZIP002: 960388,           // effect at : 2019-10-31 14:00:00
+	ZIP003: common.MaxUint64, // not scheduled yet
+	ZIP004: common.MaxUint64, // not scheduled yet
+	ZIP005: common.MaxUint64, // not scheduled yet
+	ZIP006: common.MaxUint64, // not scheduled yet
+	ZIP007: common.MaxUint64, // not scheduled yet
+	ZIP008: common.MaxUint64, // not scheduled yet
+	ZIP009: common.MaxUint64, // not scheduled yet
+	ZIP010: common.MaxUint64, // not scheduled yet
+
+	CallMaxStorageBytes: 64 * 1024,
+	CallMaxEvents:       256,
 }
 
 func InitChainConfig(chainId uint16) {
@@ -59,3 +99,35 @@ func (cfg *ChainConfig) IsZIP001(h uint64) bool {
 func (cfg *ChainConfig) IsZIP002(h uint64) bool {
 	return isFork(cfg.ZIP002, h)
 }
+
+func (cfg *ChainConfig) IsZIP003(h uint64) bool {
+	return isFork(cfg.ZIP003, h)
+}
+
+func (cfg *ChainConfig) IsZIP004(h uint64) bool {
+	return isFork(cfg.ZIP004, h)
+}
+
+func (cfg *ChainConfig) IsZIP005(h uint64) bool {
+	return isFork(cfg.ZIP005, h)
+}
+
+func (cfg *ChainConfig) IsZIP006(h uint64) bool {
+	return isFork(cfg.ZIP006, h)
+}
+
+func (cfg *ChainConfig) IsZIP007(h uint64) bool {
+	return isFork(cfg.ZIP007, h)
+}
+
+func (cfg *ChainConfig) IsZIP008(h uint64) bool {
+	return isFork(cfg.ZIP008, h)
+}
+
+func (cfg *ChainConfig) IsZIP009(h uint64) bool {
+	return isFork(cfg.ZIP009, h)
+}
+
+func (cfg *ChainConfig) IsZIP010(h uint64) bool {
+	return isFork(cfg.ZIP010, h)
+}
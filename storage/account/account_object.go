@@ -33,7 +33,8 @@ import (
 	"golang.org/x/crypto/sha3"
 )
 
-var emptyCodeHash = sha3.Sum256(nil)
+// EmptyCodeHash is the code hash of the accounts without code
+var EmptyCodeHash = sha3.Sum256(nil)
 
 type Code []byte
 
@@ -101,7 +102,7 @@ type accountObject struct {
 
 // empty returns whether the account is considered empty.
 func (ao *accountObject) empty() bool {
-	return ao.data.Nonce == 0 && ao.data.Balance.Sign() == 0 && bytes.Equal(ao.data.CodeHash, emptyCodeHash[:]) && len(ao.cachedStorage) == 0
+	return ao.data.Nonce == 0 && ao.data.Balance.Sign() == 0 && bytes.Equal(ao.data.CodeHash, EmptyCodeHash[:]) && len(ao.cachedStorage) == 0
 }
 
 // Account is the consensus representation of accounts.
@@ -119,7 +120,7 @@ func newAccountObject(db *AccountDB, address common.Address, data Account, onDir
 		data.Balance = new(big.Int)
 	}
 	if data.CodeHash == nil {
-		data.CodeHash = emptyCodeHash[:]
+		data.CodeHash = EmptyCodeHash[:]
 	}
 	return &accountObject{
 		db:            db,
@@ -337,7 +338,7 @@ func (ao *accountObject) Code(db AccountDatabase) []byte {
 	if ao.code != nil {
 		return ao.code
 	}
-	if bytes.Equal(ao.CodeHash(), emptyCodeHash[:]) {
+	if bytes.Equal(ao.CodeHash(), EmptyCodeHash[:]) {
 		return nil
 	}
 	code, err := db.ContractCode(ao.addrHash, common.BytesToHash(ao.CodeHash()))
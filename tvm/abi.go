@@ -0,0 +1,231 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/darren0718/zvchain/params"
+)
+
+// abiStorageKey is the reserved key of the abi in the contract account. The keys of the
+// contract data never start with 0, and the reserved keys are refused by the bridge
+// anyway, so it can't be touched by the contract
+var abiStorageKey = []byte("\x00abi")
+
+var (
+	eventRegexp  = regexp.MustCompile(`Event\(([^)]*)\)`)
+	stringRegexp = regexp.MustCompile(`["']([^"']*)["']`)
+)
+
+// ContractABI is the interface of the contract, extracted from the source at deploy time
+type ContractABI struct {
+	Functions []ABIVerify `json:"functions"`
+	Events    []ABIEvent  `json:"events"`
+}
+
+// IsReservedKey returns whether the key of the contract data is reserved by the chain
+func IsReservedKey(key []byte) bool {
+	return len(key) > 0 && key[0] == 0
+}
+
+// isReservedKey returns whether the key is reserved and hidden from the contract running,
+// which applies since ZIP005
+func (con *Controller) isReservedKey(key []byte) bool {
+	var blockHeight uint64
+	if con.BlockHeader != nil {
+		blockHeight = con.BlockHeader.Height
+	}
+	return params.GetChainConfig().IsZIP005(blockHeight) && IsReservedKey(key)
+}
+
+// ExtractABI extracts the public functions with the argument types and the events from the
+// contract source. The fields of the events are declared after the name, suffixed with
+// ":indexed" if indexed
+func ExtractABI(code string) *ContractABI {
+	abi := &ContractABI{Functions: make([]ABIVerify, 0), Events: make([]ABIEvent, 0)}
+
+	lines := strings.Split(code, "\n")
+	for k, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, m := range eventRegexp.FindAllStringSubmatch(line, -1) {
+			if e := parseEvent(m[1]); e != nil {
+				abi.Events = append(abi.Events, *e)
+			}
+		}
+		if !strings.HasPrefix(line, "@register.public") || k+1 >= len(lines) {
+			continue
+		}
+		params := strings.TrimSpace(strings.TrimPrefix(line, "@register.public"))
+		if len(params) < 2 || params[0] != '(' || params[len(params)-1] != ')' {
+			continue
+		}
+		args := make([]string, 0)
+		for _, arg := range strings.Split(params[1:len(params)-1], ",") {
+			if arg = strings.TrimSpace(arg); len(arg) > 0 {
+				args = append(args, arg)
+			}
+		}
+
+		funcLine := strings.TrimSpace(lines[k+1])
+		if !strings.HasPrefix(funcLine, "def") {
+			continue
+		}
+		funcLine = strings.TrimSpace(strings.TrimPrefix(funcLine, "def"))
+		if i := strings.Index(funcLine, "("); i > 0 {
+			abi.Functions = append(abi.Functions, ABIVerify{
+				FuncName: strings.TrimSpace(funcLine[:i]),
+				Args:     args,
+			})
+		}
+	}
+	return abi
+}
+
+// parseEvent parses the arguments of the event declaration, nil if the name isn't a literal
+func parseEvent(args string) *ABIEvent {
+	literals := stringRegexp.FindAllStringSubmatch(args, -1)
+	if len(literals) == 0 || !strings.HasPrefix(strings.TrimSpace(args), literals[0][0]) {
+		return nil
+	}
+	e := &ABIEvent{Name: literals[0][1], Fields: make([]ABIEventField, 0)}
+	for _, l := range literals[1:] {
+		name := strings.TrimSpace(l[1])
+		f := ABIEventField{Name: name}
+		if strings.HasSuffix(name, ":indexed") {
+			f.Name = strings.TrimSpace(strings.TrimSuffix(name, ":indexed"))
+			f.Indexed = true
+		}
+		e.Fields = append(e.Fields, f)
+	}
+	return e
+}
+
+// StoreABI stores the abi in the contract account under the reserved key
+func StoreABI(db types.AccountDB, addr common.Address, abi *ContractABI) error {
+	data, err := json.Marshal(abi)
+	if err != nil {
+		return err
+	}
+	db.SetData(addr, abiStorageKey, data)
+	return nil
+}
+
+// LoadABI returns the abi stored in the contract account, nil if the contract deployed
+// before the abi stored
+func LoadABI(db types.AccountDB, addr common.Address) *ContractABI {
+	data := db.GetData(addr, abiStorageKey)
+	if len(data) == 0 {
+		return nil
+	}
+	abi := &ContractABI{}
+	if err := json.Unmarshal(data, abi); err != nil {
+		return nil
+	}
+	return abi
+}
+
+func (abi *ContractABI) function(name string) *ABIVerify {
+	for i := range abi.Functions {
+		if abi.Functions[i].FuncName == name {
+			return &abi.Functions[i]
+		}
+	}
+	return nil
+}
+
+// VerifyCall checks the function called is public and the arguments match the types declared
+func (abi *ContractABI) VerifyCall(call *ABI) error {
+	f := abi.function(call.FuncName)
+	if f == nil {
+		return fmt.Errorf("function %v not found in the abi", call.FuncName)
+	}
+	if len(call.Args) != len(f.Args) {
+		return fmt.Errorf("function %v expects %v arguments, got %v", f.FuncName, len(f.Args), len(call.Args))
+	}
+	for i, arg := range call.Args {
+		if !abiTypeMatch(f.Args[i], arg) {
+			return fmt.Errorf("argument %v of function %v should be %v", i, f.FuncName, f.Args[i])
+		}
+	}
+	return nil
+}
+
+// abiTypeMatch returns whether the json value matches the python type. The types not
+// known are left to the vm
+func abiTypeMatch(typ string, value interface{}) bool {
+	switch typ {
+	case "str":
+		_, ok := value.(string)
+		return ok
+	case "int":
+		switch v := value.(type) {
+		case json.Number:
+			_, ok := new(big.Int).SetString(v.String(), 10)
+			return ok
+		case float64:
+			return v == math.Trunc(v)
+		}
+		return false
+	case "bool":
+		_, ok := value.(bool)
+		return ok
+	case "list":
+		_, ok := value.([]interface{})
+		return ok
+	case "dict":
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return true
+}
+
+// decodeABICall decodes the call of the contract function
+func decodeABICall(abiJSON string) (*ABI, error) {
+	abi := &ABI{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(abiJSON)))
+	decoder.DisallowUnknownFields()
+	decoder.UseNumber()
+	if err := decoder.Decode(abi); err != nil {
+		return nil, err
+	}
+	return abi, nil
+}
+
+// checkABICall checks the call against the abi stored, only the format checked if the
+// contract has no abi stored
+func checkABICall(db types.AccountDB, contract *Contract, abiJSON string) error {
+	call, err := decodeABICall(abiJSON)
+	if err != nil {
+		return err
+	}
+	abi := LoadABI(db, *contract.ContractAddress)
+	if abi == nil {
+		return nil
+	}
+	return abi.VerifyCall(call)
+}
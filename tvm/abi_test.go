@@ -0,0 +1,122 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/darren0718/zvchain/params"
+)
+
+const abiCode4Test = `
+import account
+event = Event("send")
+
+class Router(object):
+    def __init__(self):
+        self.transfer_event = Event('transfer', 'from:indexed', "value")
+
+    @register.public(str, int)
+    def call_contract(self, addr, times):
+        event.emit(times)
+
+    # @register.public(str)
+    # def disabled(self, addr):
+    #     Event("disabled")
+
+    @register.public()
+    def get_name(self):
+        return self.name
+
+    @register.public(bool, list, dict)
+    def set_all(self, b, l, d):
+        pass
+
+    def private_func(self):
+        pass
+`
+
+func TestExtractABI(t *testing.T) {
+	abi := ExtractABI(abiCode4Test)
+	expect := []ABIVerify{
+		{FuncName: "call_contract", Args: []string{"str", "int"}},
+		{FuncName: "get_name", Args: []string{}},
+		{FuncName: "set_all", Args: []string{"bool", "list", "dict"}},
+	}
+	if !reflect.DeepEqual(abi.Functions, expect) {
+		t.Fatalf("functions error %+v", abi.Functions)
+	}
+	expectEvents := []ABIEvent{
+		{Name: "send", Fields: []ABIEventField{}},
+		{Name: "transfer", Fields: []ABIEventField{{Name: "from", Indexed: true}, {Name: "value"}}},
+	}
+	if !reflect.DeepEqual(abi.Events, expectEvents) {
+		t.Fatalf("events error %v", abi.Events)
+	}
+}
+
+func TestContractABI_VerifyCall(t *testing.T) {
+	abi := ExtractABI(abiCode4Test)
+	cases := []struct {
+		call string
+		ok   bool
+	}{
+		{`{"func_name":"call_contract","args":["zv01",10]}`, true},
+		{`{"func_name":"call_contract","args":["zv01",100000000000000000000000000000001]}`, true},
+		{`{"func_name":"call_contract","args":["zv01",1.5]}`, false},
+		{`{"func_name":"call_contract","args":[10,"zv01"]}`, false},
+		{`{"func_name":"call_contract","args":["zv01"]}`, false},
+		{`{"func_name":"get_name","args":[]}`, true},
+		{`{"func_name":"private_func","args":[]}`, false},
+		{`{"func_name":"set_all","args":[true,[1,"a"],{"k":1}]}`, true},
+		{`{"func_name":"set_all","args":[1,[1],{"k":1}]}`, false},
+	}
+	for _, c := range cases {
+		call, err := decodeABICall(c.call)
+		if err != nil {
+			t.Fatalf("decode %v error %v", c.call, err)
+		}
+		if err := abi.VerifyCall(call); (err == nil) != c.ok {
+			t.Fatalf("verify %v expect %v, got %v", c.call, c.ok, err)
+		}
+	}
+	if _, err := decodeABICall(`{"func_name":"get_name","args":[],"unknown":1}`); err == nil {
+		t.Fatalf("unknown field should be rejected")
+	}
+}
+
+func TestIsReservedKey(t *testing.T) {
+	if !IsReservedKey(abiStorageKey) || IsReservedKey([]byte("name")) || IsReservedKey(nil) {
+		t.Fatalf("reserved key error")
+	}
+}
+
+func TestController_IsReservedKey(t *testing.T) {
+	cfg := params.GetChainConfig()
+	defer func(h uint64) { cfg.ZIP005 = h }(cfg.ZIP005)
+	cfg.ZIP005 = 100
+
+	con := &Controller{BlockHeader: &types.BlockHeader{Height: 99}}
+	if con.isReservedKey(abiStorageKey) {
+		t.Fatalf("no reserved key before zip005")
+	}
+	con.BlockHeader.Height = 100
+	if !con.isReservedKey(abiStorageKey) || con.isReservedKey([]byte("name")) {
+		t.Fatalf("reserved key error after zip005")
+	}
+}
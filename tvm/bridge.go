@@ -25,10 +25,17 @@ import (
 
 	"github.com/darren0718/zvchain/common"
 	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/darren0718/zvchain/params"
 )
 
+// controllerOf returns the controller of the handle passed by the vm
+func controllerOf(handle C.ulonglong) *Controller {
+	return lookupController(uint64(handle))
+}
+
 //export Transfer
-func Transfer(toAddress *C.char, value *C.char) bool {
+func Transfer(handle C.ulonglong, toAddress *C.char, value *C.char) bool {
+	controller := controllerOf(handle)
 	toAddressStr := C.GoString(toAddress)
 	if !common.ValidateAddress(toAddressStr) {
 		return false
@@ -39,6 +46,8 @@ func Transfer(toAddress *C.char, value *C.char) bool {
 	}
 	contractAddr := controller.VM.ContractAddress
 	to := common.StringToAddress(toAddressStr)
+	done := controller.profileOp(OpTransfer)
+	defer done(0)
 
 	if !controller.AccountDB.CanTransfer(*contractAddr, transValue) {
 		return false
@@ -49,7 +58,8 @@ func Transfer(toAddress *C.char, value *C.char) bool {
 }
 
 //export GetBalance
-func GetBalance(addressC *C.char) *C.char {
+func GetBalance(handle C.ulonglong, addressC *C.char) *C.char {
+	controller := controllerOf(handle)
 	toAddressStr := C.GoString(addressC)
 	if !common.ValidateAddress(toAddressStr) {
 		return C.CString("0")
@@ -60,10 +70,18 @@ func GetBalance(addressC *C.char) *C.char {
 }
 
 //export GetData
-func GetData(key *C.char, keyLen C.int, value **C.char, valueLen *C.int) {
+func GetData(handle C.ulonglong, key *C.char, keyLen C.int, value **C.char, valueLen *C.int) {
+	controller := controllerOf(handle)
 	//hash := common.StringToHash(C.GoString(hashC))
 	address := *controller.VM.ContractAddress
-	state := controller.AccountDB.GetData(address, C.GoBytes(unsafe.Pointer(key), keyLen))
+	done := controller.profileOp(OpStorageRead)
+	k := C.GoBytes(unsafe.Pointer(key), keyLen)
+	var state []byte
+	// The reserved keys are hidden from the contract
+	if !controller.isReservedKey(k) {
+		state = controller.AccountDB.GetData(address, k)
+	}
+	done(len(k) + len(state))
 	if state == nil {
 		*value = nil
 		*valueLen = -1
@@ -74,15 +92,22 @@ func GetData(key *C.char, keyLen C.int, value **C.char, valueLen *C.int) {
 }
 
 //export SetData
-func SetData(key *C.char, kenLen C.int, value *C.char, valueLen C.int) {
+func SetData(handle C.ulonglong, key *C.char, kenLen C.int, value *C.char, valueLen C.int) {
+	controller := controllerOf(handle)
 	address := *controller.VM.ContractAddress
 	k := C.GoBytes(unsafe.Pointer(key), kenLen)
 	v := C.GoBytes(unsafe.Pointer(value), valueLen)
+	done := controller.profileOp(OpStorageWrite)
+	defer done(len(k) + len(v))
+	if controller.isReservedKey(k) || !controller.useStorage(len(k)+len(v)) {
+		return
+	}
 	controller.AccountDB.SetData(address, k, v)
 }
 
 //export BlockHash
-func BlockHash(height C.ulonglong) *C.char {
+func BlockHash(handle C.ulonglong, height C.ulonglong) *C.char {
+	controller := controllerOf(handle)
 	block := controller.Reader.QueryBlockHeaderByHeight(uint64(height))
 	if block == nil {
 		return nil
@@ -91,23 +116,29 @@ func BlockHash(height C.ulonglong) *C.char {
 }
 
 //export Number
-func Number() C.ulonglong {
+func Number(handle C.ulonglong) C.ulonglong {
+	controller := controllerOf(handle)
 	return C.ulonglong(controller.BlockHeader.Height)
 }
 
 //export Timestamp
-func Timestamp() C.ulonglong {
+func Timestamp(handle C.ulonglong) C.ulonglong {
+	controller := controllerOf(handle)
 	return C.ulonglong(uint64(controller.BlockHeader.CurTime.UnixMilli()))
 }
 
 //export TxGasLimit
-func TxGasLimit() C.ulonglong {
+func TxGasLimit(handle C.ulonglong) C.ulonglong {
+	controller := controllerOf(handle)
 	return C.ulonglong(controller.Transaction.GetGasLimit())
 }
 
 //export ContractCall
-func ContractCall(addressC *C.char, funName *C.char, jsonParms *C.char, cResult unsafe.Pointer) {
-	goResult := CallContract(C.GoString(addressC), C.GoString(funName), C.GoString(jsonParms))
+func ContractCall(handle C.ulonglong, addressC *C.char, funName *C.char, jsonParms *C.char, cResult unsafe.Pointer) {
+	controller := controllerOf(handle)
+	done := controller.profileOp(OpContractCall)
+	goResult := controller.callContract(C.GoString(addressC), C.GoString(funName), C.GoString(jsonParms))
+	done(0)
 	ccResult := (*C.struct__tvm_execute_result_t)(cResult)
 	ccResult.result_type = C.int(goResult.ResultType)
 	ccResult.error_code = C.int(goResult.ErrorCode)
@@ -116,11 +147,45 @@ func ContractCall(addressC *C.char, funName *C.char, jsonParms *C.char, cResult
 	}
 }
 
-//export EventCall
-func EventCall(eventName *C.char, data *C.char, dataLen C.int) {
+//export CreateContract
+func CreateContract(handle C.ulonglong, name *C.char, code *C.char, salt *C.char, cResult unsafe.Pointer) {
+	controller := controllerOf(handle)
+	done := controller.profileOp(OpContractCreate)
+	goResult := controller.createContract(C.GoString(name), C.GoString(code), C.GoString(salt))
+	done(0)
+	ccResult := (*C.struct__tvm_execute_result_t)(cResult)
+	ccResult.result_type = C.int(goResult.ResultType)
+	ccResult.error_code = C.int(goResult.ErrorCode)
+	if goResult.Content != "" {
+		ccResult.content = C.CString(goResult.Content)
+	}
+}
 
+//export PrecompileCall
+func PrecompileCall(handle C.ulonglong, name *C.char, jsonParms *C.char, cResult unsafe.Pointer) {
+	controller := controllerOf(handle)
+	done := controller.profileOp(OpPrecompile)
+	goResult := controller.callPrecompile(C.GoString(name), C.GoString(jsonParms))
+	done(0)
+	ccResult := (*C.struct__tvm_execute_result_t)(cResult)
+	ccResult.result_type = C.int(goResult.ResultType)
+	ccResult.error_code = C.int(goResult.ErrorCode)
+	if goResult.Content != "" {
+		ccResult.content = C.CString(goResult.Content)
+	}
+}
+
+//export EventCall
+func EventCall(handle C.ulonglong, eventName *C.char, data *C.char, dataLen C.int) {
+	controller := controllerOf(handle)
+	done := controller.profileOp(OpEvent)
+	defer done(int(dataLen))
+	if !controller.useEvent() {
+		return
+	}
 	var log types.Log
-	log.Topic = common.BytesToHash(common.Sha256([]byte(C.GoString(eventName))))
+	name := C.GoString(eventName)
+	log.Topic = EventTopic(name)
 	log.Index = uint(len(controller.VM.Logs))
 	log.Data = C.GoBytes(unsafe.Pointer(data), dataLen)
 	log.TxHash = controller.Transaction.GetHash()
@@ -128,13 +193,22 @@ func EventCall(eventName *C.char, data *C.char, dataLen C.int) {
 	log.BlockNumber = controller.BlockHeader.Height
 	//block is running ,no blockhash this time
 	// log.BlockHash = controller.BlockHeader.Hash
+	if params.GetChainConfig().IsZIP006(log.BlockNumber) {
+		encodeEventLog(controller.AccountDB, &log, name)
+	}
 
 	controller.VM.Logs = append(controller.VM.Logs, &log)
 }
 
 //export RemoveData
-func RemoveData(key *C.char, kenLen C.int) {
+func RemoveData(handle C.ulonglong, key *C.char, kenLen C.int) {
+	controller := controllerOf(handle)
 	address := *controller.VM.ContractAddress
 	k := C.GoBytes(unsafe.Pointer(key), kenLen)
+	done := controller.profileOp(OpStorageRemove)
+	defer done(len(k))
+	if controller.isReservedKey(k) {
+		return
+	}
 	controller.AccountDB.RemoveData(address, k)
 }
@@ -24,70 +24,128 @@ package tvm
 #include <unistd.h>
 #include <string.h>
 
+// The handle of the controller running the vm on the thread, passed to the callbacks. It's
+// thread local so that each execution finds its own controller
+static __thread unsigned long long tvm_handle = 0;
+
+void set_tvm_handle(unsigned long long handle)
+{
+	tvm_handle = handle;
+}
+
 void wrap_transfer(const char* p2, const char* value)
 {
-    void Transfer(const char*, const char* value);
-    Transfer(p2, value);
+    void Transfer(unsigned long long, const char*, const char* value);
+    Transfer(tvm_handle, p2, value);
 }
 
 char* wrap_get_balance(const char* address)
 {
-	char* GetBalance(const char*);
-	return GetBalance(address);
+	char* GetBalance(unsigned long long, const char*);
+	return GetBalance(tvm_handle, address);
 }
 
 void wrap_remove_data(const char* key, int key_len)
 {
-	void RemoveData(const char* , int);
-	RemoveData(key, key_len);
+	void RemoveData(unsigned long long, const char* , int);
+	RemoveData(tvm_handle, key, key_len);
 }
 
 void wrap_get_data(const char* key, int key_len, char** value, int* value_len)
 {
-	void GetData(const char*, int, char**, int*);
-	return GetData(key, key_len, value, value_len);
+	void GetData(unsigned long long, const char*, int, char**, int*);
+	return GetData(tvm_handle, key, key_len, value, value_len);
 }
 
 void wrap_set_data(const char* key, int key_len, const char* value, int value_len)
 {
-	void SetData(const char*, int, const char*, int);
-	SetData(key, key_len, value, value_len);
+	void SetData(unsigned long long, const char*, int, const char*, int);
+	SetData(tvm_handle, key, key_len, value, value_len);
 }
 
 char* wrap_block_hash(unsigned long long height)
 {
-	char* BlockHash(unsigned long long);
-	return BlockHash(height);
+	char* BlockHash(unsigned long long, unsigned long long);
+	return BlockHash(tvm_handle, height);
 }
 
 unsigned long long wrap_number()
 {
-	unsigned long long Number();
-	return Number();
+	unsigned long long Number(unsigned long long);
+	return Number(tvm_handle);
 }
 
 unsigned long long wrap_timestamp()
 {
-	unsigned long long Timestamp();
-	return Timestamp();
+	unsigned long long Timestamp(unsigned long long);
+	return Timestamp(tvm_handle);
 }
 
 unsigned long long wrap_tx_gas_limit()
 {
-	unsigned long long TxGasLimit();
-	return TxGasLimit();
+	unsigned long long TxGasLimit(unsigned long long);
+	return TxGasLimit(tvm_handle);
 }
 
 void wrap_contract_call(const char* address, const char* func_name, const char* json_parms, tvm_execute_result_t *result)
 {
-    char* ContractCall();
-    ContractCall(address, func_name, json_parms, result);
+    void ContractCall(unsigned long long, const char*, const char*, const char*, void*);
+    ContractCall(tvm_handle, address, func_name, json_parms, result);
+}
+
+void wrap_create_contract(const char* name, const char* code, const char* salt, tvm_execute_result_t *result)
+{
+    void CreateContract(unsigned long long, const char*, const char*, const char*, void*);
+    CreateContract(tvm_handle, name, code, salt, result);
+}
+
+void wrap_precompile_call(const char* name, const char* json_parms, tvm_execute_result_t *result)
+{
+    void PrecompileCall(unsigned long long, const char*, const char*, void*);
+    PrecompileCall(tvm_handle, name, json_parms, result);
 }
 
 void wrap_event_call(const char* event, const char* json_parms)
 {
-    void EventCall(const char*, const char*);
-    EventCall(event, json_parms);
+    void EventCall(unsigned long long, const char*, const char*, int);
+    EventCall(tvm_handle, event, json_parms, (int)strlen(json_parms));
+}
+
+// The hooks of the vm library the contracts call the precompiled contracts and create the
+// contracts by. They are declared weak so that the bridge still links with the library not
+// having them, where they are left unbound
+typedef void (*tvm_precompile_call_hook_t)(const char*, const char*, tvm_execute_result_t*);
+typedef void (*tvm_create_contract_hook_t)(const char*, const char*, const char*, tvm_execute_result_t*);
+extern tvm_precompile_call_hook_t precompile_call_fn __attribute__((weak));
+extern tvm_create_contract_hook_t create_contract_fn __attribute__((weak));
+
+_Bool bind_precompile_call_hook()
+{
+	if (&precompile_call_fn == NULL) {
+		return 0;
+	}
+	precompile_call_fn = wrap_precompile_call;
+	return 1;
+}
+
+_Bool bind_create_contract_hook()
+{
+	if (&create_contract_fn == NULL) {
+		return 0;
+	}
+	create_contract_fn = wrap_create_contract;
+	return 1;
+}
+
+// call_precompile_hook and call_create_contract_hook call the hooks bound as the library does
+void call_precompile_hook(const char* name, const char* json_parms, tvm_execute_result_t *result)
+{
+	precompile_call_fn(name, json_parms, result);
+}
+
+void call_create_contract_hook(const char* name, const char* code, const char* salt, tvm_execute_result_t *result)
+{
+	create_contract_fn(name, code, salt, result);
 }
 */
 import "C"
@@ -110,8 +168,9 @@ type ExecuteResult struct {
 	Content    string
 }
 
-// CallContract Execute the function of a contract which python code store in contractAddr
-func CallContract(contractAddr string, funcName string, params string) *ExecuteResult {
+// callContract Execute the function of a contract which python code store in contractAddr,
+// called by the contract running
+func (con *Controller) callContract(contractAddr string, funcName string, params string) *ExecuteResult {
 	result := &ExecuteResult{}
 	if !common.ValidateAddress(contractAddr) {
 		result.ResultType = C.RETURN_TYPE_EXCEPTION
@@ -120,7 +179,7 @@ func CallContract(contractAddr string, funcName string, params string) *ExecuteR
 		return result
 	}
 	conAddr := common.StringToAddress(contractAddr)
-	contract := LoadContract(conAddr)
+	contract := con.LoadContract(conAddr)
 	if contract.Code == "" {
 		result.ResultType = C.RETURN_TYPE_EXCEPTION
 		result.ErrorCode = types.TVMNoCodeError
@@ -129,20 +188,20 @@ func CallContract(contractAddr string, funcName string, params string) *ExecuteR
 	}
 
 	// prepare vm environment
-	remainGas := controller.VM.Gas()
+	remainGas := con.VM.Gas()
 	var blockHeight uint64 = 0
-	if controller.BlockHeader != nil {
-		blockHeight = controller.BlockHeader.Height
+	if con.BlockHeader != nil {
+		blockHeight = con.BlockHeader.Height
 	}
-	oneVM := NewTVMForRetainContext(controller.VM.ContractAddress, contract, controller.VM.Logs, blockHeight)
+	oneVM := NewTVMForRetainContext(con.VM.ContractAddress, contract, con.VM.Logs, blockHeight)
 	oneVM.SetGas(remainGas)
-	finished := controller.StoreVMContext(oneVM)
+	finished := con.StoreVMContext(oneVM)
 	defer func() {
 		// recover vm environment
 		if finished {
 			remainGas := oneVM.Gas()
-			controller.RecoverVMContext()
-			controller.VM.SetGas(remainGas)
+			con.RecoverVMContext()
+			con.VM.SetGas(remainGas)
 		}
 	}()
 	if !finished {
@@ -153,7 +212,7 @@ func CallContract(contractAddr string, funcName string, params string) *ExecuteR
 	}
 
 	msg := Msg{Data: []byte{}, Value: 0}
-	result, err := controller.VM.CreateContractInstance(msg)
+	result, err := con.VM.CreateContractInstance(msg)
 	if err != nil {
 		return result
 	}
@@ -171,10 +230,123 @@ func CallContract(contractAddr string, funcName string, params string) *ExecuteR
 		return result
 	}
 
-	finalResult := controller.VM.executeABIKindEval(abi)
+	finalResult := con.VM.executeABIKindEval(abi)
 	return finalResult
 }
 
+// createContract deploys the contract created by the contract running to the address derived
+// from the creator, the salt and the code. The address is returned on success
+func (con *Controller) createContract(name string, code string, saltHex string) *ExecuteResult {
+	result := &ExecuteResult{ResultType: C.RETURN_TYPE_EXCEPTION, ErrorCode: types.TVMExecutedError}
+	var blockHeight uint64
+	if con.BlockHeader != nil {
+		blockHeight = con.BlockHeader.Height
+	}
+	if !params.GetChainConfig().IsZIP008(blockHeight) {
+		result.Content = "create contract not supported"
+		return result
+	}
+	gas := con.VM.Gas()
+	cost := createContractGas + createContractByteGas*len(code)
+	if gas < cost {
+		result.ErrorCode = types.TVMGasNotEnoughError
+		result.Content = "does not have enough gas to run!"
+		return result
+	}
+	con.VM.SetGas(gas - cost)
+
+	salt, err := hexArg(saltHex)
+	if err != nil {
+		result.Content = err.Error()
+		return result
+	}
+	creator := con.VM.ContractAddress
+	// The new contract is removed if the deployment failed
+	snapshot := con.AccountDB.Snapshot()
+	contract, err := newSaltedContract(con.AccountDB, *creator, name, code, salt)
+	if err != nil {
+		result.Content = err.Error()
+		return result
+	}
+
+	// prepare vm environment, the creator is the sender of the new contract
+	oneVM := NewTVMForRetainContext(creator, contract, con.VM.Logs, blockHeight)
+	oneVM.SetGas(con.VM.Gas())
+	finished := con.StoreVMContext(oneVM)
+	if !finished {
+		con.AccountDB.RevertToSnapshot(snapshot)
+		result.ErrorCode = types.TVMCallMaxDeepError
+		result.Content = fmt.Sprintf("call max deep cannot more than %d", MaxDepth)
+		return result
+	}
+	deployResult := oneVM.Deploy(Msg{Data: []byte{}, Value: 0})
+	remainGas := oneVM.Gas()
+	con.RecoverVMContext()
+	con.VM.SetGas(remainGas)
+	if deployResult.ResultType == C.RETURN_TYPE_EXCEPTION {
+		con.AccountDB.RevertToSnapshot(snapshot)
+		return deployResult
+	}
+	if params.GetChainConfig().IsZIP005(blockHeight) {
+		if err := StoreABI(con.AccountDB, *contract.ContractAddress, ExtractABI(contract.Code)); err != nil {
+			con.AccountDB.RevertToSnapshot(snapshot)
+			result.Content = err.Error()
+			return result
+		}
+	}
+	return &ExecuteResult{ResultType: C.RETURN_TYPE_STRING, Content: contract.ContractAddress.AddrPrefixString()}
+}
+
+// callPrecompile runs the precompiled contract called by the contract running, charging the
+// fixed gas cost of the precompile before running
+func (con *Controller) callPrecompile(name string, jsonParms string) *ExecuteResult {
+	result := &ExecuteResult{ResultType: C.RETURN_TYPE_EXCEPTION, ErrorCode: types.TVMExecutedError}
+	var blockHeight uint64
+	if con.BlockHeader != nil {
+		blockHeight = con.BlockHeader.Height
+	}
+	p := Precompiled(name)
+	if p == nil || !params.GetChainConfig().IsZIP007(blockHeight) {
+		result.Content = fmt.Sprintf("precompiled contract %s not found", name)
+		return result
+	}
+	gas := con.VM.Gas()
+	if gas < 0 || uint64(gas) < p.Gas {
+		result.ErrorCode = types.TVMGasNotEnoughError
+		result.Content = "does not have enough gas to run!"
+		return result
+	}
+	con.VM.SetGas(gas - int(p.Gas))
+
+	args, err := p.decodeArgs(jsonParms)
+	if err != nil {
+		result.ErrorCode = types.TVMCheckABIError
+		result.Content = fmt.Sprintf("precompiled contract %s: %v", name, err)
+		return result
+	}
+	ret, err := p.Run(args)
+	if err != nil {
+		result.Content = fmt.Sprintf("precompiled contract %s: %v", name, err)
+		return result
+	}
+	result.ErrorCode = 0
+	switch v := ret.(type) {
+	case bool:
+		result.ResultType = C.RETURN_TYPE_BOOL
+		result.Content = strconv.FormatBool(v)
+	default:
+		result.ResultType = C.RETURN_TYPE_STRING
+		result.Content = fmt.Sprint(v)
+	}
+	return result
+}
+
+// setBridgeHandle sets the handle passed to the callbacks of the vm running on the thread,
+// the caller must be locked to the thread
+func setBridgeHandle(handle uint64) {
+	C.set_tvm_handle(C.ulonglong(handle))
+}
+
 func bridgeInit() {
 	C.transfer_fn = (C.transfer_fn_t)(unsafe.Pointer(C.wrap_transfer))
 	C.get_balance = (C.get_balance_fn_t)(unsafe.Pointer(C.wrap_get_balance))
@@ -188,6 +360,46 @@ func bridgeInit() {
 	C.gas_limit_fn = (C.gas_limit_fn_t)(unsafe.Pointer(C.wrap_tx_gas_limit))
 	C.contract_call_fn = (C.contract_call_fn_t)(unsafe.Pointer(C.wrap_contract_call))
 	C.event_call_fn = (C.event_call_fn_t)(unsafe.Pointer(C.wrap_event_call))
+	precompileHookBound = bool(C.bind_precompile_call_hook())
+	createContractHookBound = bool(C.bind_create_contract_hook())
+}
+
+// callPrecompileHook calls the precompiled contract through the hook bound as the contract
+// running does, false returned if the hook isn't bound
+func callPrecompileHook(name string, jsonParms string) (*ExecuteResult, bool) {
+	if !precompileHookBound {
+		return nil, false
+	}
+	cName, cParms := C.CString(name), C.CString(jsonParms)
+	defer C.free(unsafe.Pointer(cName))
+	defer C.free(unsafe.Pointer(cParms))
+	cResult := &C.tvm_execute_result_t{}
+	C.call_precompile_hook(cName, cParms, cResult)
+	return hookResult(cResult), true
+}
+
+// callCreateContractHook creates the contract through the hook bound as the contract running
+// does, false returned if the hook isn't bound
+func callCreateContractHook(name string, code string, salt string) (*ExecuteResult, bool) {
+	if !createContractHookBound {
+		return nil, false
+	}
+	cName, cCode, cSalt := C.CString(name), C.CString(code), C.CString(salt)
+	defer C.free(unsafe.Pointer(cName))
+	defer C.free(unsafe.Pointer(cCode))
+	defer C.free(unsafe.Pointer(cSalt))
+	cResult := &C.tvm_execute_result_t{}
+	C.call_create_contract_hook(cName, cCode, cSalt, cResult)
+	return hookResult(cResult), true
+}
+
+func hookResult(cResult *C.tvm_execute_result_t) *ExecuteResult {
+	result := &ExecuteResult{ResultType: int(cResult.result_type), ErrorCode: int(cResult.error_code)}
+	if cResult.content != nil {
+		result.Content = C.GoString(cResult.content)
+		C.free(unsafe.Pointer(cResult.content))
+	}
+	return result
 }
 
 // Contract Contract contains the base message of a contract
@@ -198,12 +410,12 @@ type Contract struct {
 }
 
 // LoadContract Load a contract-instance from a contract address
-func LoadContract(address common.Address) *Contract {
-	jsonString := controller.AccountDB.GetCode(address)
-	con := &Contract{}
-	_ = json.Unmarshal([]byte(jsonString), con)
-	con.ContractAddress = &address
-	return con
+func (con *Controller) LoadContract(address common.Address) *Contract {
+	jsonString := con.AccountDB.GetCode(address)
+	contract := &Contract{}
+	_ = json.Unmarshal([]byte(jsonString), contract)
+	contract.ContractAddress = &address
+	return contract
 }
 
 // TVM TVM is the role who execute contract code
@@ -18,15 +18,22 @@ package tvm
 import (
 	"bytes"
 	"encoding/binary"
-	"encoding/json"
 	"fmt"
 	"github.com/darren0718/zvchain/common"
 	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/darren0718/zvchain/params"
 	"math/big"
 )
 
 var bridgeInited = false
 
+// Whether the vm library has the hooks of the precompiled contracts and the contracts created by
+// contracts, which are bound to the bridge then
+var (
+	precompileHookBound     bool
+	createContractHookBound bool
+)
+
 // Controller VM Controller
 type Controller struct {
 	BlockHeader *types.BlockHeader
@@ -37,6 +44,10 @@ type Controller struct {
 	VMStack     []*TVM
 	GasLeft     uint64
 	mm          MinerManager
+
+	Profile *GasProfile // Attributes the gas to the bridge operations if set
+	Limits  *CallLimits // Limits of the storage written and the events emitted, nil if unlimited
+	usage   callUsage
 }
 
 // MinerManager MinerManager is the interface of the miner manager
@@ -51,21 +62,23 @@ func NewController(accountDB types.AccountDB,
 	transaction types.TxMessage,
 	gasUsed uint64,
 	manager MinerManager) *Controller {
-	if controller == nil {
-		controller = &Controller{}
-	}
 	if transaction.GetGasLimit() < gasUsed {
 		panic(fmt.Sprintf("gasLimit less than gasUsed:%v %v", transaction.GetGasLimit(), gasUsed))
 	}
-	controller.BlockHeader = header
-	controller.Transaction = transaction
-	controller.AccountDB = accountDB
-	controller.Reader = chainReader
-	controller.VM = nil
-	controller.VMStack = make([]*TVM, 0)
-	controller.GasLeft = transaction.GetGasLimit() - gasUsed
-	controller.mm = manager
-	return controller
+	var height uint64
+	if header != nil {
+		height = header.Height
+	}
+	return &Controller{
+		BlockHeader: header,
+		Transaction: transaction,
+		AccountDB:   accountDB,
+		Reader:      chainReader,
+		VMStack:     make([]*TVM, 0),
+		GasLeft:     transaction.GetGasLimit() - gasUsed,
+		mm:          manager,
+		Limits:      ChainCallLimits(height),
+	}
 }
 
 func transactionErrorWith(result *ExecuteResult) *types.TransactionError {
@@ -88,19 +101,23 @@ func (con *Controller) Deploy(contract *Contract) (*ExecuteResult, []*types.Log,
 	if con.BlockHeader != nil {
 		blockHeight = con.BlockHeader.Height
 	}
+	release := con.acquireVM()
+	defer release()
 	con.VM = NewTVM(con.Transaction.Operator(), contract, blockHeight)
-	defer func() {
-		con.VM.DelTVM()
-		con.GasLeft = uint64(con.VM.Gas())
-	}()
+	defer con.finishVM(con.GasLeft)
 	con.VM.SetGas(int(con.GasLeft))
 	msg := Msg{Data: []byte{}, Value: con.Transaction.GetValue()}
 
 	result := con.VM.Deploy(msg)
-	transactionError := transactionErrorWith(result)
+	transactionError := con.transactionError(result)
 	if transactionError != nil {
 		return result, nil, transactionError
 	}
+	if params.GetChainConfig().IsZIP005(blockHeight) {
+		if err := StoreABI(con.AccountDB, *contract.ContractAddress, ExtractABI(contract.Code)); err != nil {
+			return result, nil, types.NewTransactionError(types.TVMExecutedError, err.Error())
+		}
+	}
 
 	return result, con.VM.Logs, nil
 }
@@ -111,31 +128,31 @@ func (con *Controller) ExecuteAbiEval(sender *common.Address, contract *Contract
 	if con.BlockHeader != nil {
 		blockHeight = con.BlockHeader.Height
 	}
+	// Malformed calls fail before running the vm
+	if params.GetChainConfig().IsZIP005(blockHeight) {
+		if err := checkABICall(con.AccountDB, contract, abiJSON); err != nil {
+			return nil, nil, types.NewTransactionError(types.TVMCheckABIError, err.Error())
+		}
+	}
+	release := con.acquireVM()
+	defer release()
 	con.VM = NewTVM(sender, contract, blockHeight)
 	con.VM.SetGas(int(con.GasLeft))
-	defer func() {
-		con.VM.DelTVM()
-		con.GasLeft = uint64(con.VM.Gas())
-	}()
+	defer con.finishVM(con.GasLeft)
 	msg := Msg{Data: con.Transaction.Payload(), Value: con.Transaction.GetValue()}
 	result, err := con.VM.CreateContractInstance(msg)
 	if err != nil {
-		return result, nil, transactionErrorWith(result)
+		return result, nil, con.transactionError(result)
 	}
-	abi := ABI{}
-
-	decoder := json.NewDecoder(bytes.NewReader([]byte(abiJSON)))
-	decoder.DisallowUnknownFields()
-	decoder.UseNumber()
-	abiJSONError := decoder.Decode(&abi)
+	abi, abiJSONError := decodeABICall(abiJSON)
 	if abiJSONError != nil {
 		return nil, nil, types.NewTransactionError(types.TVMCheckABIError, abiJSONError.Error())
 	}
 
 	//con.VM.SetLibLine(libLen)
 
-	result = con.VM.executeABIKindEval(abi) //execute
-	transactionError := transactionErrorWith(result)
+	result = con.VM.executeABIKindEval(*abi) //execute
+	transactionError := con.transactionError(result)
 	if transactionError != nil {
 		return result, nil, transactionError
 	}
@@ -143,6 +160,19 @@ func (con *Controller) ExecuteAbiEval(sender *common.Address, contract *Contract
 	return result, con.VM.Logs, nil
 }
 
+// finishVM releases the vm and takes the gas left, the gas used since the gas left before is
+// added to the profile. The gas left is the one kept by exceed if the call exceeded a limit
+func (con *Controller) finishVM(gasBefore uint64) {
+	con.VM.DelTVM()
+	con.GasLeft = uint64(con.VM.Gas())
+	if con.usage.err != nil {
+		con.GasLeft = con.usage.gasLeft
+	}
+	if con.Profile != nil && gasBefore > con.GasLeft {
+		con.Profile.finish(gasBefore - con.GasLeft)
+	}
+}
+
 // GetGasLeft get gas left
 func (con *Controller) GetGasLeft() uint64 {
 	return con.GasLeft
@@ -15,7 +15,51 @@
 
 package tvm
 
-var controller *Controller // vm controller
+import (
+	"runtime"
+	"sync"
+)
+
+// Each execution binds its controller to a handle passed through the bridge. The handle is kept
+// per thread in C and the goroutine running the vm is locked to its thread, so the callbacks of
+// an execution always find its own controller, whichever controller runs on the other threads.
+// The interpreter of the vm library is one per process though, so vmLock still makes the
+// executions, the read-only calls on the snapshots included, take turns on it
+var (
+	vmLock      sync.Mutex
+	handleMutex sync.RWMutex
+	handles     = make(map[uint64]*Controller)
+	lastHandle  uint64
+)
+
+// lookupController returns the controller of the handle, nil if not running
+func lookupController(handle uint64) *Controller {
+	handleMutex.RLock()
+	defer handleMutex.RUnlock()
+	return handles[handle]
+}
+
+// acquireVM waits for the vm and binds it to the controller on the current thread until the
+// returned function called
+func (con *Controller) acquireVM() func() {
+	vmLock.Lock()
+	runtime.LockOSThread()
+	handleMutex.Lock()
+	lastHandle++
+	handle := lastHandle
+	handles[handle] = con
+	handleMutex.Unlock()
+	setBridgeHandle(handle)
+
+	return func() {
+		setBridgeHandle(0)
+		handleMutex.Lock()
+		delete(handles, handle)
+		handleMutex.Unlock()
+		runtime.UnlockOSThread()
+		vmLock.Unlock()
+	}
+}
 
 // MaxDepth max depth of running stack
 const MaxDepth int = 5
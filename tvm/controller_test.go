@@ -0,0 +1,148 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/darren0718/zvchain/storage/account"
+	"github.com/darren0718/zvchain/storage/tasdb"
+)
+
+const counterContract4Test = `
+class Counter(object):
+    def __init__(self):
+        self.count = 0
+
+    @register.public()
+    def get(self):
+        return self.count
+
+    @register.public(int)
+    def add(self, n):
+        self.count += n
+`
+
+var sender4Test = common.StringToAddress("zv0000000000000000000000000000000000000000000000000000000000000001")
+
+func tx4Test(target *common.Address) *types.Transaction {
+	raw := &types.RawTransaction{
+		Source:   &sender4Test,
+		Target:   target,
+		Value:    types.NewBigInt(0),
+		GasLimit: types.NewBigInt(500000),
+		GasPrice: types.NewBigInt(500),
+	}
+	return types.NewTransaction(raw, raw.GenHash())
+}
+
+// deployCounter4Test deploys the counter contract and returns the state root committed
+func deployCounter4Test(t *testing.T, db account.AccountDatabase, addr common.Address) common.Hash {
+	state, _ := account.NewAccountDB(common.Hash{}, db)
+	contract := &Contract{Code: counterContract4Test, ContractName: "Counter"}
+	code, _ := json.Marshal(contract)
+	state.CreateAccount(addr)
+	state.SetCode(addr, code)
+	contract.ContractAddress = &addr
+
+	if _, _, err := NewController(state, nil, &types.BlockHeader{}, tx4Test(nil), 0, nil).Deploy(contract); err != nil {
+		t.Fatalf("deploy error %v", err.Message)
+	}
+	root, err := state.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.TrieDB().Commit(root, false)
+	return root
+}
+
+func call4Test(state types.AccountDB, addr common.Address, abiJSON string) (*ExecuteResult, *types.TransactionError) {
+	con := NewController(state, nil, &types.BlockHeader{Height: 1}, tx4Test(&addr), 0, nil)
+	result, _, err := con.ExecuteAbiEval(&sender4Test, con.LoadContract(addr), abiJSON)
+	return result, err
+}
+
+// Run with -race: the reads on the snapshots and the writes of the block processing share
+// no controller state, and the callbacks of each execution reach its own controller by the
+// handle of its thread. See TestEngine_ConcurrentCalls of the mock engine for the test running
+// without the vm
+func TestController_ConcurrentCalls(t *testing.T) {
+	diskdb, _ := tasdb.NewMemDatabase()
+	db := account.NewDatabase(diskdb)
+	addr := common.BytesToAddress(common.Sha256([]byte("counter")))
+	root := deployCounter4Test(t, db, addr)
+
+	snapshot, _ := account.NewAccountDB(root, db)
+	expect, err := call4Test(snapshot, addr, `{"func_name":"get","args":[]}`)
+	if err != nil {
+		t.Fatalf("call error %v", err.Message)
+	}
+
+	const readers, rounds = 8, 20
+	var wg sync.WaitGroup
+	errs := make(chan string, readers*rounds+rounds)
+
+	// Block processing keeps adding to the counter on its own state
+	state, _ := account.NewAccountDB(root, db)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			if _, err := call4Test(state, addr, `{"func_name":"add","args":[1]}`); err != nil {
+				errs <- err.Message
+			}
+		}
+	}()
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				state, _ := account.NewAccountDB(root, db)
+				result, err := call4Test(state, addr, `{"func_name":"get","args":[]}`)
+				if err != nil {
+					errs <- err.Message
+				} else if result.Content != expect.Content {
+					errs <- "snapshot read " + result.Content + ", expect " + expect.Content
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Error(e)
+	}
+	// All the writes reached the state of the block processing, none the snapshots
+	result, err := call4Test(state, addr, `{"func_name":"get","args":[]}`)
+	if err != nil {
+		t.Fatalf("call error %v", err.Message)
+	}
+	if count, _ := strconv.Atoi(expect.Content); result.Content != strconv.Itoa(count+rounds) {
+		t.Fatalf("block state read %v, expect %v added by %v", result.Content, expect.Content, rounds)
+	}
+	handleMutex.RLock()
+	running := len(handles)
+	handleMutex.RUnlock()
+	if running != 0 {
+		t.Fatalf("controller handles not released: %v", running)
+	}
+}
@@ -0,0 +1,61 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/middleware/types"
+)
+
+// Gas cost of the contracts created by the contracts, besides the gas of the deployment
+const (
+	createContractGas     = 20000
+	createContractByteGas = 2
+)
+
+// setupContract creates the contract account with the code, failed if the address is
+// taken by another contract
+func setupContract(db types.AccountDB, addr common.Address, code []byte) error {
+	if !types.IsContractAddressFree(db, addr) {
+		return fmt.Errorf("contract address conflict")
+	}
+	// The balance sent to the address before is kept
+	db.CreateAccount(addr)
+	db.SetCode(addr, code)
+	db.SetNonce(addr, 1)
+	return nil
+}
+
+// newSaltedContract prepares the contract created by the contract running at the salted address
+func newSaltedContract(db types.AccountDB, creator common.Address, name string, code string, salt []byte) (*Contract, error) {
+	if len(salt) != types.ContractSaltLength {
+		return nil, fmt.Errorf("salt should be %v bytes", types.ContractSaltLength)
+	}
+	contract := &Contract{Code: code, ContractName: name}
+	data, err := json.Marshal(contract)
+	if err != nil {
+		return nil, err
+	}
+	addr := types.SaltedContractAddress(creator, salt, data)
+	if err := setupContract(db, addr, data); err != nil {
+		return nil, err
+	}
+	contract.ContractAddress = &addr
+	return contract, nil
+}
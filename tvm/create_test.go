@@ -0,0 +1,99 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/darren0718/zvchain/params"
+	"github.com/darren0718/zvchain/storage/account"
+	"github.com/darren0718/zvchain/storage/tasdb"
+)
+
+func TestNewSaltedContract(t *testing.T) {
+	diskdb, _ := tasdb.NewMemDatabase()
+	db, _ := account.NewAccountDB(common.Hash{}, account.NewDatabase(diskdb))
+	creator := common.StringToAddress("zv0000000000000000000000000000000000000000000000000000000000000001")
+	salt := common.Sha256([]byte("salt"))
+
+	contract, err := newSaltedContract(db, creator, "A", "class A(object):\n    pass", salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.GetCodeHash(*contract.ContractAddress) == (common.Hash{}) || db.GetNonce(*contract.ContractAddress) != 1 {
+		t.Fatalf("contract account not created")
+	}
+	if _, err := newSaltedContract(db, creator, "A", "class A(object):\n    pass", salt); err == nil {
+		t.Fatalf("address conflict expected")
+	}
+	if _, err := newSaltedContract(db, creator, "A", "class A(object):\n    pass", salt[:8]); err == nil {
+		t.Fatalf("short salt should fail")
+	}
+}
+
+func TestNewSaltedContract_PreFunded(t *testing.T) {
+	diskdb, _ := tasdb.NewMemDatabase()
+	db, _ := account.NewAccountDB(common.Hash{}, account.NewDatabase(diskdb))
+	creator := common.StringToAddress("zv0000000000000000000000000000000000000000000000000000000000000001")
+	salt := common.Sha256([]byte("salt"))
+	code, _ := json.Marshal(&Contract{Code: "class A(object):\n    pass", ContractName: "A"})
+	addr := types.SaltedContractAddress(creator, salt, code)
+	db.AddBalance(addr, big.NewInt(100))
+
+	contract, err := newSaltedContract(db, creator, "A", "class A(object):\n    pass", salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *contract.ContractAddress != addr || db.GetBalance(addr).Int64() != 100 {
+		t.Fatalf("balance of the pre-funded address should be kept")
+	}
+}
+
+func TestCreateContract_ThroughBridge(t *testing.T) {
+	cfg := params.GetChainConfig()
+	defer func(h uint64) { cfg.ZIP008 = h }(cfg.ZIP008)
+	cfg.ZIP008 = 0
+
+	diskdb, _ := tasdb.NewMemDatabase()
+	db, _ := account.NewAccountDB(common.Hash{}, account.NewDatabase(diskdb))
+	creator := common.BytesToAddress(common.Sha256([]byte("factory")))
+	con := NewController(db, nil, &types.BlockHeader{Height: 1}, tx4Test(&creator), 0, nil)
+	con.VM = NewTVM(&sender4Test, &Contract{ContractAddress: &creator}, 1)
+	con.VM.SetGas(1000000)
+	release := con.acquireVM()
+	defer release()
+
+	salt := common.ToHex(common.Sha256([]byte("salt")))
+	result, ok := callCreateContractHook("Counter", counterContract4Test, salt)
+	if !ok {
+		t.Skip("vm library without the create contract hook")
+	}
+	code, _ := json.Marshal(&Contract{Code: counterContract4Test, ContractName: "Counter"})
+	addr := types.SaltedContractAddress(creator, common.Sha256([]byte("salt")), code)
+	if result.ErrorCode != 0 || result.Content != addr.AddrPrefixString() {
+		t.Fatalf("create contract through the bridge error %+v", result)
+	}
+	if db.GetCodeHash(addr) == (common.Hash{}) {
+		t.Fatalf("contract not created at the salted address")
+	}
+	if result, _ := callCreateContractHook("Counter", counterContract4Test, salt); result.ErrorCode == 0 {
+		t.Fatalf("address conflict expected")
+	}
+}
@@ -0,0 +1,61 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/middleware/types"
+)
+
+// Engine runs the contracts on the TVM, which is the types.ContractEngine of the chain
+type Engine struct {
+	controller *Controller
+	logs       []*types.Log
+}
+
+// NewEngine creates the TVM engine for the transaction, which is a types.ContractEngineFactory
+func NewEngine(db types.AccountDB, reader types.ChainReader, bh *types.BlockHeader, msg types.TxMessage, gasUsed uint64, mm types.MinerManager) types.ContractEngine {
+	return &Engine{controller: NewController(db, reader, bh, msg, gasUsed, mm)}
+}
+
+// HasCode returns whether the contract at the address has the code to run
+func (e *Engine) HasCode(contract common.Address) bool {
+	return e.controller.LoadContract(contract).Code != ""
+}
+
+// Deploy runs the deployment of the contract whose code is already set at the address
+func (e *Engine) Deploy(contract common.Address) *types.TransactionError {
+	_, logs, err := e.controller.Deploy(e.controller.LoadContract(contract))
+	e.logs = logs
+	return err
+}
+
+// Call calls the contract by the sender with the abi
+func (e *Engine) Call(sender common.Address, contract common.Address, abiJSON string) *types.TransactionError {
+	_, logs, err := e.controller.ExecuteAbiEval(&sender, e.controller.LoadContract(contract), abiJSON)
+	e.logs = logs
+	return err
+}
+
+// GasLeft returns the gas left of the transaction
+func (e *Engine) GasLeft() uint64 {
+	return e.controller.GetGasLeft()
+}
+
+// Logs returns the logs generated by the last successful deployment or call
+func (e *Engine) Logs() []*types.Log {
+	return e.logs
+}
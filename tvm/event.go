@@ -0,0 +1,211 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/middleware/types"
+)
+
+// The canonical encoding of the events:
+//  - The first topic is the hash of the event name
+//  - Each indexed field adds a topic, which is the hash of the json encoding of the value
+//  - The data is the json array of all the field values in the declared order
+// The events not declared with fields keep the data emitted by the contract
+
+// ABIEventField is a field declared by the event
+type ABIEventField struct {
+	Name    string `json:"name"`
+	Indexed bool   `json:"indexed"`
+}
+
+// ABIEvent is the event declared by the contract, e.g. Event("transfer", "from:indexed", "to:indexed", "value")
+type ABIEvent struct {
+	Name   string          `json:"name"`
+	Fields []ABIEventField `json:"fields"`
+}
+
+// DecodedLog is the log decoded by the event declared
+type DecodedLog struct {
+	Address common.Address         `json:"address"`
+	Index   uint                   `json:"log_index"`
+	Event   string                 `json:"event"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+	Data    string                 `json:"data,omitempty"` // Raw data if the log can't be decoded
+}
+
+// EventTopic returns the first topic of the event
+func EventTopic(name string) common.Hash {
+	return common.BytesToHash(common.Sha256([]byte(name)))
+}
+
+func valueTopic(value interface{}) (common.Hash, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(common.Sha256(b)), nil
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Encode encodes the arguments emitted, either positional as a json array or keyword as
+// a json object, and returns the topics and the data
+func (e *ABIEvent) Encode(data []byte) ([]common.Hash, []byte, error) {
+	topics := []common.Hash{EventTopic(e.Name)}
+	if len(e.Fields) == 0 {
+		return topics, data, nil
+	}
+	args, err := decodeJSON(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	values := make([]interface{}, len(e.Fields))
+	switch v := args.(type) {
+	case []interface{}:
+		if len(v) != len(e.Fields) {
+			return nil, nil, fmt.Errorf("event %v expects %v fields, got %v", e.Name, len(e.Fields), len(v))
+		}
+		copy(values, v)
+	case map[string]interface{}:
+		if len(v) != len(e.Fields) {
+			return nil, nil, fmt.Errorf("event %v expects %v fields, got %v", e.Name, len(e.Fields), len(v))
+		}
+		for i, f := range e.Fields {
+			value, ok := v[f.Name]
+			if !ok {
+				return nil, nil, fmt.Errorf("field %v of event %v missing", f.Name, e.Name)
+			}
+			values[i] = value
+		}
+	default:
+		return nil, nil, fmt.Errorf("event %v arguments should be an array or an object", e.Name)
+	}
+
+	for i, f := range e.Fields {
+		if !f.Indexed {
+			continue
+		}
+		topic, err := valueTopic(values[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		topics = append(topics, topic)
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return nil, nil, err
+	}
+	return topics, encoded, nil
+}
+
+// Decode decodes the log encoded by the event into the named fields
+func (e *ABIEvent) Decode(log *types.Log) (map[string]interface{}, error) {
+	indexed := 0
+	for _, f := range e.Fields {
+		if f.Indexed {
+			indexed++
+		}
+	}
+	if len(log.Topics) != indexed+1 || log.Topics[0] != EventTopic(e.Name) {
+		return nil, fmt.Errorf("topics mismatch the event %v", e.Name)
+	}
+	args, err := decodeJSON(log.Data)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := args.([]interface{})
+	if !ok || len(values) != len(e.Fields) {
+		return nil, fmt.Errorf("data mismatch the event %v", e.Name)
+	}
+
+	fields := make(map[string]interface{}, len(e.Fields))
+	topic := 1
+	for i, f := range e.Fields {
+		if f.Indexed {
+			if h, err := valueTopic(values[i]); err != nil || h != log.Topics[topic] {
+				return nil, fmt.Errorf("indexed field %v mismatch the topic", f.Name)
+			}
+			topic++
+		}
+		fields[f.Name] = values[i]
+	}
+	return fields, nil
+}
+
+// event returns the event declared of the name, nil if not found
+func (abi *ContractABI) event(name string) *ABIEvent {
+	for i := range abi.Events {
+		if abi.Events[i].Name == name {
+			return &abi.Events[i]
+		}
+	}
+	return nil
+}
+
+// DecodeLog decodes the log emitted by the contract. The raw data is returned if the log
+// isn't encoded canonically
+func (abi *ContractABI) DecodeLog(log *types.Log) *DecodedLog {
+	decoded := &DecodedLog{Address: log.Address, Index: log.Index}
+	for i := range abi.Events {
+		if EventTopic(abi.Events[i].Name) != log.Topic {
+			continue
+		}
+		e := &abi.Events[i]
+		decoded.Event = e.Name
+		if len(e.Fields) > 0 && len(log.Topics) > 0 {
+			if fields, err := e.Decode(log); err == nil {
+				decoded.Fields = fields
+				return decoded
+			}
+		}
+		break
+	}
+	decoded.Data = string(log.Data)
+	return decoded
+}
+
+// encodeEventLog encodes the log emitted by the contract canonically if the event is
+// declared in the abi stored. Otherwise only the event name topic is set
+func encodeEventLog(db types.AccountDB, log *types.Log, name string) {
+	log.Topics = []common.Hash{log.Topic}
+	abi := LoadABI(db, log.Address)
+	if abi == nil {
+		return
+	}
+	e := abi.event(name)
+	if e == nil {
+		return
+	}
+	topics, data, err := e.Encode(log.Data)
+	if err != nil {
+		return
+	}
+	log.Topics = topics
+	log.Data = data
+}
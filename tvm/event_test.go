@@ -0,0 +1,95 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/darren0718/zvchain/middleware/types"
+)
+
+var transferEvent4Test = ABIEvent{
+	Name:   "transfer",
+	Fields: []ABIEventField{{Name: "from", Indexed: true}, {Name: "to", Indexed: true}, {Name: "value"}},
+}
+
+func TestABIEvent_EncodeDecode(t *testing.T) {
+	positional, err := json.Marshal([]interface{}{"zv01", "zv02", json.Number("100000000000000000000000000000001")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyword := []byte(`{"value":100000000000000000000000000000001,"to":"zv02","from":"zv01"}`)
+
+	var expectData []byte
+	for _, data := range [][]byte{positional, keyword} {
+		topics, encoded, err := transferEvent4Test.Encode(data)
+		if err != nil {
+			t.Fatalf("encode error %v", err)
+		}
+		if len(topics) != 3 || topics[0] != EventTopic("transfer") {
+			t.Fatalf("topics error %v", topics)
+		}
+		if expectData == nil {
+			expectData = encoded
+		} else if string(encoded) != string(expectData) {
+			t.Fatalf("positional and keyword arguments should encode the same, %s %s", expectData, encoded)
+		}
+
+		fields, err := transferEvent4Test.Decode(&types.Log{Topic: topics[0], Topics: topics, Data: encoded})
+		if err != nil {
+			t.Fatalf("decode error %v", err)
+		}
+		if fields["from"] != "zv01" || fields["to"] != "zv02" || fields["value"].(json.Number).String() != "100000000000000000000000000000001" {
+			t.Fatalf("fields error %v", fields)
+		}
+	}
+}
+
+func TestABIEvent_EncodeError(t *testing.T) {
+	for _, data := range []string{`["zv01","zv02"]`, `{"from":"zv01","to":"zv02","amount":1}`, `1`, `[`} {
+		if _, _, err := transferEvent4Test.Encode([]byte(data)); err == nil {
+			t.Fatalf("encode %v should fail", data)
+		}
+	}
+}
+
+func TestContractABI_DecodeLog(t *testing.T) {
+	abi := &ContractABI{Events: []ABIEvent{transferEvent4Test, {Name: "send", Fields: []ABIEventField{}}}}
+	topics, data, _ := transferEvent4Test.Encode([]byte(`["zv01","zv02",1]`))
+
+	decoded := abi.DecodeLog(&types.Log{Topic: topics[0], Topics: topics, Data: data})
+	if decoded.Event != "transfer" || decoded.Fields["to"] != "zv02" || decoded.Data != "" {
+		t.Fatalf("decode error %+v", decoded)
+	}
+
+	// The indexed topic tampered
+	topics[1] = EventTopic("zv03")
+	decoded = abi.DecodeLog(&types.Log{Topic: topics[0], Topics: topics, Data: data})
+	if decoded.Event != "transfer" || decoded.Fields != nil || decoded.Data != string(data) {
+		t.Fatalf("tampered log shouldn't be decoded %+v", decoded)
+	}
+
+	// Logs before zip006 and the events without fields keep the raw data
+	decoded = abi.DecodeLog(&types.Log{Topic: EventTopic("send"), Data: []byte(`[1]`)})
+	if decoded.Event != "send" || decoded.Fields != nil || decoded.Data != "[1]" {
+		t.Fatalf("decode error %+v", decoded)
+	}
+	decoded = abi.DecodeLog(&types.Log{Topic: EventTopic("unknown"), Data: []byte(`[1]`)})
+	if decoded.Event != "" || decoded.Data != "[1]" {
+		t.Fatalf("decode error %+v", decoded)
+	}
+}
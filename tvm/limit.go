@@ -0,0 +1,97 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"fmt"
+
+	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/darren0718/zvchain/params"
+)
+
+// limitExceededGas is charged for the call stopped by exceeding a limit, besides the gas used
+// before. The gas left after is refunded
+const limitExceededGas = 20000
+
+// CallLimits caps the bytes of the storage written and the number of the events emitted by a
+// contract call, the nested calls included. Zero means no limit
+type CallLimits struct {
+	MaxStorageBytes uint64 `json:"maxStorageBytes"`
+	MaxEvents       uint64 `json:"maxEvents"`
+}
+
+// ChainCallLimits returns the limits of the calls on the chain at the height, nil before zip010
+func ChainCallLimits(height uint64) *CallLimits {
+	cfg := params.GetChainConfig()
+	if !cfg.IsZIP010(height) {
+		return nil
+	}
+	return &CallLimits{MaxStorageBytes: cfg.CallMaxStorageBytes, MaxEvents: cfg.CallMaxEvents}
+}
+
+// callUsage is what the call used against the limits
+type callUsage struct {
+	storageBytes uint64
+	events       uint64
+	err          *types.TransactionError // Set once a limit exceeded
+	gasLeft      uint64                  // Gas left of the transaction once a limit exceeded
+}
+
+// transactionError returns the error of the vm result, the limit error if any limit exceeded
+func (con *Controller) transactionError(result *ExecuteResult) *types.TransactionError {
+	if con.usage.err != nil {
+		return con.usage.err
+	}
+	return transactionErrorWith(result)
+}
+
+// exceed stops the vm by running out of the gas left, the call fails with the limit error.
+// Only limitExceededGas is charged from the gas left, the rest is given back by finishVM
+func (con *Controller) exceed(format string, args ...interface{}) {
+	if con.usage.err == nil {
+		con.usage.err = types.NewTransactionError(types.TVMCallLimitError, fmt.Sprintf(format, args...))
+		if gas := con.VM.Gas(); gas > limitExceededGas {
+			con.usage.gasLeft = uint64(gas - limitExceededGas)
+		}
+	}
+	con.VM.SetGas(0)
+}
+
+// useStorage counts the bytes written, false if the limit exceeded
+func (con *Controller) useStorage(bytes int) bool {
+	if con.usage.err != nil {
+		return false
+	}
+	con.usage.storageBytes += uint64(bytes)
+	if con.Limits != nil && con.Limits.MaxStorageBytes > 0 && con.usage.storageBytes > con.Limits.MaxStorageBytes {
+		con.exceed("storage written exceeds the limit of %v bytes", con.Limits.MaxStorageBytes)
+		return false
+	}
+	return true
+}
+
+// useEvent counts the event emitted, false if the limit exceeded
+func (con *Controller) useEvent() bool {
+	if con.usage.err != nil {
+		return false
+	}
+	con.usage.events++
+	if con.Limits != nil && con.Limits.MaxEvents > 0 && con.usage.events > con.Limits.MaxEvents {
+		con.exceed("events emitted exceed the limit of %v", con.Limits.MaxEvents)
+		return false
+	}
+	return true
+}
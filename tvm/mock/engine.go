@@ -0,0 +1,205 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package mock provides the contract engine scripted in Go, which runs the contract
+// transactions without the TVM for the tests
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/middleware/types"
+)
+
+// Context is the context of the deployment or the call run by the script
+type Context struct {
+	DB       types.AccountDB
+	Header   *types.BlockHeader
+	Sender   common.Address
+	Contract common.Address
+	Value    uint64
+	Method   string        // Empty for the deployment
+	Args     []interface{} // Arguments of the call, numbers decoded as json.Number
+
+	engine *Engine
+}
+
+// UseGas charges the gas, failed with the gas error if not enough
+func (c *Context) UseGas(gas uint64) *types.TransactionError {
+	if c.engine.gasLeft < gas {
+		c.engine.gasLeft = 0
+		return types.NewTransactionError(types.TVMGasNotEnoughError, "does not have enough gas to run!")
+	}
+	c.engine.gasLeft -= gas
+	return nil
+}
+
+// Emit generates the log of the contract with the topic and the data
+func (c *Context) Emit(topic common.Hash, data []byte) {
+	log := &types.Log{
+		Address: c.Contract,
+		Topic:   topic,
+		Data:    data,
+		TxHash:  c.engine.msg.GetHash(),
+		Index:   uint(len(c.engine.pending)),
+	}
+	if c.Header != nil {
+		log.BlockNumber = c.Header.Height
+	}
+	c.engine.pending = append(c.engine.pending, log)
+}
+
+// Handler runs the deployment or the method of the contract
+type Handler func(ctx *Context) *types.TransactionError
+
+// Script is the behaviour of the contracts with the same name
+type Script struct {
+	Deploy  Handler            // Nil if nothing to do on the deployment
+	Methods map[string]Handler // Calling the method not in the map fails with the abi error
+}
+
+// Mock runs the contracts by the scripts registered under the contract names. The contracts
+// without the script are deployed with nothing done and fail to be called
+type Mock struct {
+	lock    sync.RWMutex
+	scripts map[string]*Script
+
+	DeployGas uint64 // Gas charged before running each deployment
+	CallGas   uint64 // Gas charged before running each call
+}
+
+// New creates the mock without any script
+func New() *Mock {
+	return &Mock{scripts: make(map[string]*Script)}
+}
+
+// Register registers the script of the contracts with the name
+func (m *Mock) Register(name string, script *Script) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.scripts[name] = script
+}
+
+func (m *Mock) script(name string) *Script {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.scripts[name]
+}
+
+// NewEngine creates the engine running the transaction by the scripts, which is a
+// types.ContractEngineFactory
+func (m *Mock) NewEngine(db types.AccountDB, reader types.ChainReader, bh *types.BlockHeader, msg types.TxMessage, gasUsed uint64, mm types.MinerManager) types.ContractEngine {
+	if msg.GetGasLimit() < gasUsed {
+		panic(fmt.Sprintf("gasLimit less than gasUsed:%v %v", msg.GetGasLimit(), gasUsed))
+	}
+	return &Engine{mock: m, db: db, bh: bh, msg: msg, gasLeft: msg.GetGasLimit() - gasUsed}
+}
+
+// contract is the code set at the contract address, in the format of the tvm contract
+type contract struct {
+	Code         string `json:"code"`
+	ContractName string `json:"contract_name"`
+}
+
+type abiCall struct {
+	FuncName string        `json:"func_name"`
+	Args     []interface{} `json:"args"`
+}
+
+// Engine runs the contracts of a transaction by the scripts of the mock
+type Engine struct {
+	mock    *Mock
+	db      types.AccountDB
+	bh      *types.BlockHeader
+	msg     types.TxMessage
+	gasLeft uint64
+	logs    []*types.Log
+	pending []*types.Log // Logs of the running deployment or call
+}
+
+func (e *Engine) load(addr common.Address) *contract {
+	c := &contract{}
+	_ = json.Unmarshal(e.db.GetCode(addr), c)
+	return c
+}
+
+// run runs the handler with the charge first, the logs are kept only if succeeded
+func (e *Engine) run(ctx *Context, charge uint64, handler Handler) *types.TransactionError {
+	e.logs, e.pending = nil, nil
+	ctx.engine = e
+	if err := ctx.UseGas(charge); err != nil {
+		return err
+	}
+	if handler != nil {
+		if err := handler(ctx); err != nil {
+			return err
+		}
+	}
+	e.logs = e.pending
+	return nil
+}
+
+func (e *Engine) newContext(sender common.Address, addr common.Address) *Context {
+	return &Context{DB: e.db, Header: e.bh, Sender: sender, Contract: addr, Value: e.msg.GetValue()}
+}
+
+// HasCode returns whether the contract at the address has the code to run
+func (e *Engine) HasCode(addr common.Address) bool {
+	return e.load(addr).Code != ""
+}
+
+// Deploy runs the deploy handler of the script of the contract
+func (e *Engine) Deploy(addr common.Address) *types.TransactionError {
+	var handler Handler
+	if script := e.mock.script(e.load(addr).ContractName); script != nil {
+		handler = script.Deploy
+	}
+	return e.run(e.newContext(*e.msg.Operator(), addr), e.mock.DeployGas, handler)
+}
+
+// Call runs the handler of the method called in the script of the contract
+func (e *Engine) Call(sender common.Address, addr common.Address, abiJSON string) *types.TransactionError {
+	e.logs = nil
+	call := &abiCall{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(abiJSON)))
+	decoder.UseNumber()
+	if err := decoder.Decode(call); err != nil {
+		return types.NewTransactionError(types.TVMCheckABIError, err.Error())
+	}
+	var handler Handler
+	if script := e.mock.script(e.load(addr).ContractName); script != nil {
+		handler = script.Methods[call.FuncName]
+	}
+	if handler == nil {
+		return types.NewTransactionError(types.TVMCheckABIError, fmt.Sprintf("function %v not found", call.FuncName))
+	}
+	ctx := e.newContext(sender, addr)
+	ctx.Method, ctx.Args = call.FuncName, call.Args
+	return e.run(ctx, e.mock.CallGas, handler)
+}
+
+// GasLeft returns the gas left of the transaction
+func (e *Engine) GasLeft() uint64 {
+	return e.gasLeft
+}
+
+// Logs returns the logs generated by the last successful deployment or call
+func (e *Engine) Logs() []*types.Log {
+	return e.logs
+}
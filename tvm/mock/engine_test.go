@@ -0,0 +1,162 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/darren0718/zvchain/storage/account"
+	"github.com/darren0718/zvchain/storage/tasdb"
+)
+
+type tx4Test struct {
+	types.TxMessage
+	source common.Address
+}
+
+func (tx *tx4Test) GetGasLimit() uint64       { return 1000 }
+func (tx *tx4Test) GetValue() uint64          { return 0 }
+func (tx *tx4Test) Operator() *common.Address { return &tx.source }
+func (tx *tx4Test) GetHash() common.Hash      { return common.BytesToHash([]byte("tx")) }
+
+func TestEngine_Script(t *testing.T) {
+	diskdb, _ := tasdb.NewMemDatabase()
+	db, _ := account.NewAccountDB(common.Hash{}, account.NewDatabase(diskdb))
+	sender := common.BytesToAddress([]byte("sender"))
+	addr := common.BytesToAddress([]byte("contract"))
+	db.SetCode(addr, []byte(`{"code":"class A(object):\n    pass","contract_name":"A"}`))
+
+	m := New()
+	m.DeployGas, m.CallGas = 100, 10
+	m.Register("A", &Script{
+		Deploy: func(ctx *Context) *types.TransactionError {
+			ctx.DB.SetData(ctx.Contract, []byte("owner"), ctx.Sender.Bytes())
+			return nil
+		},
+		Methods: map[string]Handler{
+			"echo": func(ctx *Context) *types.TransactionError {
+				ctx.Emit(common.BytesToHash([]byte("echo")), []byte(ctx.Args[0].(json.Number).String()))
+				return nil
+			},
+			"burn": func(ctx *Context) *types.TransactionError {
+				ctx.Emit(common.BytesToHash([]byte("burn")), nil)
+				return ctx.UseGas(10000)
+			},
+		},
+	})
+
+	engine := m.NewEngine(db, nil, &types.BlockHeader{Height: 5}, &tx4Test{source: sender}, 200, nil)
+	if !engine.HasCode(addr) || engine.HasCode(sender) {
+		t.Fatalf("has code error")
+	}
+	if err := engine.Deploy(addr); err != nil || string(db.GetData(addr, []byte("owner"))) != string(sender.Bytes()) {
+		t.Fatalf("deploy error %v", err)
+	}
+	if err := engine.Call(sender, addr, `{"func_name":"echo","args":[7]}`); err != nil {
+		t.Fatalf("call error %v", err)
+	}
+	logs := engine.Logs()
+	if len(logs) != 1 || string(logs[0].Data) != "7" || logs[0].Address != addr || logs[0].BlockNumber != 5 || engine.GasLeft() != 690 {
+		t.Fatalf("logs %+v, gas left %v", logs, engine.GasLeft())
+	}
+
+	if err := engine.Call(sender, addr, `{"func_name":"unknown","args":[]}`); err == nil || err.Code != types.TVMCheckABIError {
+		t.Fatalf("abi error expected, got %v", err)
+	}
+	if err := engine.Call(sender, addr, `{"func_name":"burn","args":[]}`); err == nil || err.Code != types.TVMGasNotEnoughError {
+		t.Fatalf("gas error expected, got %v", err)
+	}
+	if engine.Logs() != nil || engine.GasLeft() != 0 {
+		t.Fatalf("logs of the failed call should be dropped")
+	}
+}
+
+// Run with -race: the calls on the snapshots run alongside the block processing, each
+// engine with its own state
+func TestEngine_ConcurrentCalls(t *testing.T) {
+	diskdb, _ := tasdb.NewMemDatabase()
+	database := account.NewDatabase(diskdb)
+	sender := common.BytesToAddress([]byte("sender"))
+	addr := common.BytesToAddress([]byte("counter"))
+
+	m := New()
+	m.Register("Counter", &Script{
+		Methods: map[string]Handler{
+			"get": func(ctx *Context) *types.TransactionError {
+				ctx.Emit(common.BytesToHash([]byte("get")), ctx.DB.GetData(ctx.Contract, []byte("count")))
+				return nil
+			},
+			"add": func(ctx *Context) *types.TransactionError {
+				count := ctx.DB.GetData(ctx.Contract, []byte("count"))
+				ctx.DB.SetData(ctx.Contract, []byte("count"), []byte{count[0] + 1})
+				return nil
+			},
+		},
+	})
+	state, _ := account.NewAccountDB(common.Hash{}, database)
+	state.SetCode(addr, []byte(`{"code":"class Counter(object):\n    pass","contract_name":"Counter"}`))
+	state.SetData(addr, []byte("count"), []byte{0})
+	root, err := state.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.TrieDB().Commit(root, false)
+
+	const readers, rounds = 8, 20
+	var wg sync.WaitGroup
+	errs := make(chan string, readers*rounds+rounds)
+
+	// Block processing keeps adding to the counter on its own state
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		db, _ := account.NewAccountDB(root, database)
+		for i := 0; i < rounds; i++ {
+			engine := m.NewEngine(db, nil, &types.BlockHeader{Height: 1}, &tx4Test{source: sender}, 0, nil)
+			if err := engine.Call(sender, addr, `{"func_name":"add","args":[]}`); err != nil {
+				errs <- err.Message
+			}
+		}
+		if c := db.GetData(addr, []byte("count")); c[0] != rounds {
+			errs <- fmt.Sprintf("count %v after added, expect %v", c[0], rounds)
+		}
+	}()
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				db, _ := account.NewAccountDB(root, database)
+				engine := m.NewEngine(db, nil, &types.BlockHeader{Height: 1}, &tx4Test{source: sender}, 0, nil)
+				if err := engine.Call(sender, addr, `{"func_name":"get","args":[]}`); err != nil {
+					errs <- err.Message
+				} else if logs := engine.Logs(); len(logs) != 1 || logs[0].Data[0] != 0 {
+					errs <- fmt.Sprintf("snapshot read %v, expect 0", logs)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Error(e)
+	}
+}
@@ -0,0 +1,163 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/consensus/base"
+	"github.com/darren0718/zvchain/consensus/groupsig"
+	"github.com/darren0718/zvchain/storage/sha3"
+)
+
+// PrecompiledContract is a contract implemented natively, which the contracts call by name
+// with the string arguments. The byte arguments are hex encoded
+type PrecompiledContract struct {
+	Gas  uint64 // Fixed gas cost of each call
+	Args int    // Number of the arguments
+	Run  func(args []string) (interface{}, error)
+}
+
+var precompiles = map[string]*PrecompiledContract{
+	"sha256":          {Gas: 60, Args: 1, Run: runSha256},
+	"keccak256":       {Gas: 60, Args: 1, Run: runKeccak256},
+	"ecrecover":       {Gas: 3000, Args: 2, Run: runEcrecover},
+	"groupsig_verify": {Gas: 45000, Args: 3, Run: runGroupsigVerify},
+	"vrf_verify":      {Gas: 3000, Args: 3, Run: runVRFVerify},
+}
+
+// Precompiled returns the precompiled contract of the name, nil if not found
+func Precompiled(name string) *PrecompiledContract {
+	return precompiles[name]
+}
+
+// decodeArgs decodes the json array of the string arguments
+func (p *PrecompiledContract) decodeArgs(jsonArgs string) ([]string, error) {
+	args := make([]string, 0)
+	if err := json.Unmarshal([]byte(jsonArgs), &args); err != nil {
+		return nil, fmt.Errorf("arguments should be an array of strings: %v", err)
+	}
+	if len(args) != p.Args {
+		return nil, fmt.Errorf("expects %v arguments, got %v", p.Args, len(args))
+	}
+	return args, nil
+}
+
+func hexArg(s string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex argument %v", s)
+	}
+	return b, nil
+}
+
+func hexArgs(args []string) ([][]byte, error) {
+	bs := make([][]byte, len(args))
+	for i, arg := range args {
+		b, err := hexArg(arg)
+		if err != nil {
+			return nil, err
+		}
+		bs[i] = b
+	}
+	return bs, nil
+}
+
+func runSha256(args []string) (interface{}, error) {
+	data, err := hexArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return common.ToHex(common.Sha256(data)), nil
+}
+
+func runKeccak256(args []string) (interface{}, error) {
+	data, err := hexArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	h := sha3.NewKeccak256()
+	h.Write(data)
+	return common.ToHex(h.Sum(nil)), nil
+}
+
+// runEcrecover returns the address of the signer of the hash
+func runEcrecover(args []string) (interface{}, error) {
+	bs, err := hexArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	hash, sig := bs[0], common.BytesToSign(bs[1])
+	if len(hash) != common.HashLength || sig == nil || !sig.Valid() {
+		return nil, fmt.Errorf("invalid hash or signature")
+	}
+	pk, err := sig.RecoverPubkey(hash)
+	if err != nil {
+		return nil, err
+	}
+	return pk.GetAddress().AddrPrefixString(), nil
+}
+
+// runGroupsigVerify verifies the bls signature of the message, with the arguments of the
+// public key, the message and the signature. The keys and signatures at infinity are refused,
+// as are the ones not in the canonical encoding
+func runGroupsigVerify(args []string) (interface{}, error) {
+	bs, err := hexArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if isZeroBytes(bs[0]) || isZeroBytes(bs[2]) {
+		return false, nil
+	}
+	var pk groupsig.Pubkey
+	if err := pk.Deserialize(bs[0]); err != nil || !bytes.Equal(pk.Serialize(), bs[0]) {
+		return false, nil
+	}
+	var sig groupsig.Signature
+	if err := sig.Deserialize(bs[2]); err != nil || !sig.IsValid() || !bytes.Equal(sig.Serialize(), bs[2]) {
+		return false, nil
+	}
+	return groupsig.VerifySig(pk, bs[1], sig), nil
+}
+
+// isZeroBytes returns whether all the bytes are zero, which encodes the point at infinity
+func isZeroBytes(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// runVRFVerify verifies the vrf prove of the message, with the arguments of the public key,
+// the prove and the message
+func runVRFVerify(args []string) (interface{}, error) {
+	bs, err := hexArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := base.VRFVerify(base.VRFPublicKey(bs[0]), base.VRFProve(bs[1]), bs[2])
+	if err != nil {
+		return false, nil
+	}
+	return ok, nil
+}
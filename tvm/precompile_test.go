@@ -0,0 +1,158 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"github.com/darren0718/zvchain/common"
+	"github.com/darren0718/zvchain/consensus/base"
+	"github.com/darren0718/zvchain/consensus/groupsig"
+	"github.com/darren0718/zvchain/middleware/types"
+	"github.com/darren0718/zvchain/params"
+)
+
+func runPrecompile4Test(t *testing.T, name string, args ...string) (interface{}, error) {
+	p := Precompiled(name)
+	if p == nil {
+		t.Fatalf("precompile %v not found", name)
+	}
+	b, _ := json.Marshal(args)
+	decoded, err := p.decodeArgs(string(b))
+	if err != nil {
+		return nil, err
+	}
+	return p.Run(decoded)
+}
+
+func TestPrecompile_Hashes(t *testing.T) {
+	ret, err := runPrecompile4Test(t, "sha256", common.ToHex([]byte("abc")))
+	if err != nil || ret != "0xba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Fatalf("sha256 error %v %v", ret, err)
+	}
+	ret, err = runPrecompile4Test(t, "keccak256", "")
+	if err != nil || ret != "0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470" {
+		t.Fatalf("keccak256 error %v %v", ret, err)
+	}
+	if _, err := runPrecompile4Test(t, "sha256", "0xzz"); err == nil {
+		t.Fatalf("invalid hex should fail")
+	}
+	if _, err := runPrecompile4Test(t, "sha256", "0x00", "0x01"); err == nil {
+		t.Fatalf("wrong argument count should fail")
+	}
+}
+
+func TestPrecompile_Ecrecover(t *testing.T) {
+	sk, _ := common.GenerateKey("")
+	hash := common.Sha256([]byte("message"))
+	sig, err := sk.Sign(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk := sk.GetPubKey()
+	ret, err := runPrecompile4Test(t, "ecrecover", common.ToHex(hash), sig.Hex())
+	if err != nil || ret != pk.GetAddress().AddrPrefixString() {
+		t.Fatalf("ecrecover error %v %v", ret, err)
+	}
+	if _, err := runPrecompile4Test(t, "ecrecover", common.ToHex(hash), "0x01"); err == nil {
+		t.Fatalf("invalid signature should fail")
+	}
+}
+
+func TestPrecompile_GroupsigVerify(t *testing.T) {
+	sk := groupsig.NewSeckeyFromRand(base.RandFromString("precompile"))
+	pk := groupsig.NewPubkeyFromSeckey(*sk)
+	msg := []byte("message")
+	sig := groupsig.Sign(*sk, msg)
+
+	ret, err := runPrecompile4Test(t, "groupsig_verify", common.ToHex(pk.Serialize()), common.ToHex(msg), common.ToHex(sig.Serialize()))
+	if err != nil || ret != true {
+		t.Fatalf("groupsig verify error %v %v", ret, err)
+	}
+	ret, err = runPrecompile4Test(t, "groupsig_verify", common.ToHex(pk.Serialize()), common.ToHex([]byte("other")), common.ToHex(sig.Serialize()))
+	if err != nil || ret != false {
+		t.Fatalf("groupsig verify other message error %v %v", ret, err)
+	}
+}
+
+func TestPrecompile_GroupsigVerify_Infinity(t *testing.T) {
+	sk := groupsig.NewSeckeyFromRand(base.RandFromString("precompile"))
+	pk := common.ToHex(groupsig.NewPubkeyFromSeckey(*sk).Serialize())
+	msg := common.ToHex([]byte("message"))
+	sig := common.ToHex(groupsig.Sign(*sk, []byte("message")).Serialize())
+	zeroPk, badPk := common.ToHex(make([]byte, 128)), common.ToHex(bytes.Repeat([]byte{0xff}, 128))
+	zeroSig, badSig := common.ToHex(make([]byte, 33)), common.ToHex(bytes.Repeat([]byte{0xff}, 33))
+
+	for _, args := range [][]string{
+		{zeroPk, msg, sig},
+		{badPk, msg, sig},
+		{pk, msg, zeroSig},
+		{pk, msg, badSig},
+		{zeroPk, msg, zeroSig},
+		{zeroPk, common.ToHex([]byte("other")), zeroSig},
+	} {
+		ret, err := runPrecompile4Test(t, "groupsig_verify", args...)
+		if err != nil || ret != false {
+			t.Fatalf("groupsig verify should refuse %v: %v %v", args, ret, err)
+		}
+	}
+}
+
+func TestPrecompile_VRFVerify(t *testing.T) {
+	pk, sk, _ := base.VRFGenerateKey(rand.Reader)
+	msg := []byte("message")
+	prove, err := base.VRFGenerateProve(pk, sk, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, err := runPrecompile4Test(t, "vrf_verify", common.ToHex(pk), common.ToHex(prove), common.ToHex(msg))
+	if err != nil || ret != true {
+		t.Fatalf("vrf verify error %v %v", ret, err)
+	}
+	ret, err = runPrecompile4Test(t, "vrf_verify", common.ToHex(pk[:10]), common.ToHex(prove), common.ToHex(msg))
+	if err != nil || ret != false {
+		t.Fatalf("vrf verify invalid key error %v %v", ret, err)
+	}
+}
+
+func TestPrecompile_ThroughBridge(t *testing.T) {
+	cfg := params.GetChainConfig()
+	defer func(h uint64) { cfg.ZIP007 = h }(cfg.ZIP007)
+	cfg.ZIP007 = 0
+
+	con := NewController(nil, nil, &types.BlockHeader{Height: 1}, tx4Test(nil), 0, nil)
+	con.VM = NewTVM(&sender4Test, &Contract{ContractAddress: &sender4Test}, 1)
+	con.VM.SetGas(100000)
+	release := con.acquireVM()
+	defer release()
+
+	result, ok := callPrecompileHook("sha256", `["`+common.ToHex([]byte("abc"))+`"]`)
+	if !ok {
+		t.Skip("vm library without the precompile hook")
+	}
+	if result.ErrorCode != 0 || result.Content != "0xba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Fatalf("sha256 through the bridge error %+v", result)
+	}
+	if con.VM.Gas() != 100000-int(Precompiled("sha256").Gas) {
+		t.Fatalf("gas of the precompile not charged, left %v", con.VM.Gas())
+	}
+	if result, _ := callPrecompileHook("unknown", "[]"); result.ErrorCode == 0 {
+		t.Fatalf("unknown precompile should fail")
+	}
+}
@@ -0,0 +1,135 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+// Bridge operations the gas is attributed to by the profile
+const (
+	OpStorageRead    = "storageRead"
+	OpStorageWrite   = "storageWrite"
+	OpStorageRemove  = "storageRemove"
+	OpContractCall   = "contractCall"
+	OpContractCreate = "contractCreate"
+	OpPrecompile     = "precompile"
+	OpTransfer       = "transfer"
+	OpEvent          = "event"
+)
+
+// opCharge is the gas the vm charges for a bridge operation, the base plus the bytes accessed
+type opCharge struct {
+	base    uint64
+	perByte uint64
+}
+
+// opCharges is the schedule of the gas the vm charges for the bridge operations before calling
+// back. The profile can't measure it from the gas left in the callback, so it's attributed to
+// the operations by the schedule and excluded from the execution gas
+var opCharges = map[string]opCharge{
+	OpStorageRead:   {base: 200, perByte: 1},
+	OpStorageWrite:  {base: 500, perByte: 10},
+	OpStorageRemove: {base: 200},
+	OpTransfer:      {base: 1000},
+	OpEvent:         {base: 375, perByte: 8},
+}
+
+// OpProfile is the gas profile of the bridge operations of a kind
+type OpProfile struct {
+	Count uint64 `json:"count"`
+	Bytes uint64 `json:"bytes"` // Bytes of the keys and the values read or written, the data of the events
+	Gas   uint64 `json:"gas"`
+}
+
+type opFrame struct {
+	gas    int    // Gas left when the operation began
+	nested uint64 // Gas used by the operations nested
+}
+
+// GasProfile attributes the gas used by the contract execution to the bridge operations. The gas
+// of an operation is what the vm charged for it by opCharges plus the gas used while the vm is
+// in it, excluding the operations nested which is attributed to them. The rest used by the vm
+// running the code is the execution gas
+type GasProfile struct {
+	Total     uint64                `json:"total"`
+	Execution uint64                `json:"execution"`
+	Ops       map[string]*OpProfile `json:"ops"`
+
+	stack []opFrame
+}
+
+// NewGasProfile creates the empty profile
+func NewGasProfile() *GasProfile {
+	return &GasProfile{Ops: make(map[string]*OpProfile)}
+}
+
+func (p *GasProfile) begin(gas int) {
+	p.stack = append(p.stack, opFrame{gas: gas})
+}
+
+func (p *GasProfile) end(op string, bytes int, gas int) {
+	frame := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	var used uint64
+	if frame.gas > gas {
+		used = uint64(frame.gas - gas)
+	}
+	self := uint64(0)
+	if used > frame.nested {
+		self = used - frame.nested
+	}
+	if c, ok := opCharges[op]; ok {
+		charged := c.base + c.perByte*uint64(bytes)
+		self += charged
+		used += charged
+	}
+	if len(p.stack) > 0 {
+		p.stack[len(p.stack)-1].nested += used
+	}
+
+	prof, ok := p.Ops[op]
+	if !ok {
+		prof = &OpProfile{}
+		p.Ops[op] = prof
+	}
+	prof.Count++
+	prof.Bytes += uint64(bytes)
+	prof.Gas += self
+}
+
+// finish adds the gas used by the execution finished, the part not attributed to the operations
+// is the execution gas
+func (p *GasProfile) finish(used uint64) {
+	p.stack = nil
+	p.Total += used
+	var ops uint64
+	for _, prof := range p.Ops {
+		ops += prof.Gas
+	}
+	p.Execution = 0
+	if p.Total > ops {
+		p.Execution = p.Total - ops
+	}
+}
+
+// profileOp begins profiling the bridge operation, the returned function ends it with the bytes
+// accessed. Nothing done if the controller isn't profiling
+func (con *Controller) profileOp(op string) func(bytes int) {
+	if con.Profile == nil {
+		return func(int) {}
+	}
+	con.Profile.begin(con.VM.Gas())
+	return func(bytes int) {
+		con.Profile.end(op, bytes, con.VM.Gas())
+	}
+}
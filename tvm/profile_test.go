@@ -0,0 +1,63 @@
+//   Copyright (C) 2019 ZVChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"testing"
+
+	"github.com/darren0718/zvchain/params"
+)
+
+func TestGasProfile_Nested(t *testing.T) {
+	p := NewGasProfile()
+	// A storage write then a call reading the storage of the contract called, the vm charged
+	// the storage operations before calling back
+	p.begin(3000)
+	p.end(OpStorageWrite, 40, 3000)
+	p.begin(2000)
+	p.begin(1700)
+	p.end(OpStorageRead, 10, 1650)
+	p.end(OpContractCall, 0, 1500)
+	p.finish(2000)
+
+	if w := p.Ops[OpStorageWrite]; w.Count != 1 || w.Bytes != 40 || w.Gas != 900 {
+		t.Fatalf("storage write profile error %+v", w)
+	}
+	if r := p.Ops[OpStorageRead]; r.Count != 1 || r.Bytes != 10 || r.Gas != 260 {
+		t.Fatalf("storage read profile error %+v", r)
+	}
+	// The gas of the read nested, the charge of the vm included, is excluded from the call
+	if c := p.Ops[OpContractCall]; c.Count != 1 || c.Gas != 240 {
+		t.Fatalf("contract call profile error %+v", c)
+	}
+	if p.Total != 2000 || p.Execution != 600 {
+		t.Fatalf("total %v, execution %v", p.Total, p.Execution)
+	}
+}
+
+func TestChainCallLimits(t *testing.T) {
+	cfg := params.GetChainConfig()
+	defer func(h uint64) { cfg.ZIP010 = h }(cfg.ZIP010)
+	cfg.ZIP010 = 100
+
+	if ChainCallLimits(99) != nil {
+		t.Fatalf("no limit before zip010")
+	}
+	limits := ChainCallLimits(100)
+	if limits == nil || limits.MaxStorageBytes != cfg.CallMaxStorageBytes || limits.MaxEvents != cfg.CallMaxEvents {
+		t.Fatalf("limits error %+v", limits)
+	}
+}